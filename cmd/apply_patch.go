@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/apply_patch"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+var applyPatchCmd = &cobra.Command{
+	Use:   "apply_patch",
+	Short: "Apply a unified diff to one or more files.",
+	Long: `Apply a unified diff to one or more files.
+
+Usage:
+	apply_patch [patch] [--patch-file FILE] [--fuzz N] [--max-offset N] [--expected-sha256 PATH=HASH]... [--expected-mtime PATH=TIME]... [--show-diff] [--show-result]
+
+Parameters:
+	patch: The unified diff to apply, as written by diff -u or git diff. Hunks
+	       from /dev/null create files and hunks to /dev/null delete them.
+
+Flags:
+	--patch-file: Read the patch from FILE instead, or from stdin if FILE is -.
+	--fuzz: Ignore up to N lines of context at each end of a hunk that does not fit (default: 2).
+	--max-offset: Move a hunk at most N lines from where its header places it (default: 0, no limit).
+	--expected-sha256: Fail with a conflict unless the file at PATH has this SHA-256, as printed by view. Repeat for each file.
+	--expected-mtime: Fail with a conflict unless the file at PATH has this modification time, as printed by view. Repeat for each file.
+	--show-diff: Show the changes made to each file.
+	--show-result: Show the new content of each file after the patch.
+
+Every hunk is reported as succeeded or FAILED. Nothing is written unless all
+hunks apply and every file matches its expected SHA-256 and modification
+time. The whole patch is recorded as one edit: undo_edit or redo_edit
+on any patched file moves all of them.
+
+Example:
+	eddie apply_patch "$(git diff)"
+	eddie apply_patch --patch-file fix.patch --show-diff
+	eddie apply_patch --patch-file fix.patch --expected-sha256 main.go=9f86d08...
+	git diff | eddie apply_patch --patch-file - --fuzz 0`,
+	Run: func(cmd *cobra.Command, args []string) {
+		patchFile, _ := cmd.Flags().GetString("patch-file")
+		fuzz, _ := cmd.Flags().GetInt("fuzz")
+		maxOffset, _ := cmd.Flags().GetInt("max-offset")
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+		pres, err := patchPreconditionFlags(cmd)
+		checkErr(err)
+
+		var patch string
+		switch {
+		case patchFile != "" && len(args) > 0:
+			fmt.Println("Error: pass either patch or --patch-file, not both")
+			return
+		case patchFile == "-":
+			data, err := io.ReadAll(os.Stdin)
+			checkErr(err)
+			patch = string(data)
+		case patchFile != "":
+			data, err := os.ReadFile(patchFile)
+			checkErr(err)
+			patch = string(data)
+		case len(args) > 0:
+			patch = args[0]
+		default:
+			fmt.Println("Error: patch or --patch-file is required")
+			return
+		}
+
+		checkErr(apply_patch.ApplyPatch(patch, fuzz, maxOffset, showChanges, showResult, pres))
+	},
+}
+
+// patchPreconditionFlags reads the repeated PATH=VALUE precondition flags of
+// apply_patch into a precondition for each path.
+func patchPreconditionFlags(c *cobra.Command) (map[string]fileops.Precondition, error) {
+	pres := make(map[string]fileops.Precondition)
+	for _, flag := range []string{"expected-sha256", "expected-mtime"} {
+		values, _ := c.Flags().GetStringArray(flag)
+		for _, value := range values {
+			i := strings.LastIndex(value, "=")
+			if i <= 0 {
+				return nil, fmt.Errorf("--%s must be PATH=VALUE, got %q", flag, value)
+			}
+			path := value[:i]
+			pre := pres[path]
+			if flag == "expected-sha256" {
+				pre.SHA256 = value[i+1:]
+			} else {
+				pre.ModTime = value[i+1:]
+			}
+			pres[path] = pre
+		}
+	}
+	return pres, nil
+}
+
+func init() {
+	applyPatchCmd.Flags().String("patch-file", "", "Read the patch from this file, or from stdin if -")
+	applyPatchCmd.Flags().Int("fuzz", apply_patch.DefaultFuzz, "Ignore up to N lines of context at each end of a hunk that does not fit")
+	applyPatchCmd.Flags().Int("max-offset", 0, "Move a hunk at most N lines from where its header places it (0 for no limit)")
+	applyPatchCmd.Flags().StringArray("expected-sha256", nil, "Fail with a conflict unless the file at PATH has this SHA-256, as PATH=HASH")
+	applyPatchCmd.Flags().StringArray("expected-mtime", nil, "Fail with a conflict unless the file at PATH has this modification time, as PATH=TIME")
+	applyPatchCmd.Flags().Bool("show-diff", false, "Show the changes made to each file")
+	applyPatchCmd.Flags().Bool("show-result", false, "Show the new content of each file after the patch")
+	rootCmd.AddCommand(applyPatchCmd)
+}
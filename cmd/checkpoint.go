@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/checkpoint"
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Create, list and restore named checkpoints across all edited files.",
+	Long: `Create, list and restore named checkpoints across all edited files.
+
+A checkpoint records the state of every file eddie has edited under a root
+directory. Restoring it reverts every file changed since then, including files
+created after the checkpoint, which are deleted. Each revert is recorded as an
+edit, so undo_edit can take it back file by file.
+
+Usage:
+	checkpoint create name [--root DIR]
+	checkpoint list
+	checkpoint restore name [--show-diff]
+
+Example:
+	eddie checkpoint create before-refactor
+	eddie checkpoint list
+	eddie checkpoint restore before-refactor --show-diff`,
+}
+
+var checkpointCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a named checkpoint of the files edited under a directory.",
+	Long: `Create a named checkpoint of the files edited under a directory.
+
+Usage:
+	checkpoint create name [--root DIR]
+
+Parameters:
+	name: The checkpoint name, made of letters, digits, '.', '_' and '-'.
+
+Flags:
+	--root: Directory whose files the checkpoint covers (default: current directory).
+
+Example:
+	eddie checkpoint create before-refactor
+	eddie checkpoint create api-change --root ./services/api`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: name is required")
+			return
+		}
+		root, _ := cmd.Flags().GetString("root")
+
+		checkErr(checkpoint.Create(args[0], root))
+	},
+}
+
+var checkpointListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List checkpoints with their creation time and root directory.",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkErr(checkpoint.List())
+	},
+}
+
+var checkpointRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Revert every file changed since a checkpoint to its state at the checkpoint.",
+	Long: `Revert every file changed since a checkpoint to its state at the checkpoint.
+
+Nothing is restored if any of the files was changed outside of eddie since its
+last tracked edit.
+
+Usage:
+	checkpoint restore name [--show-diff]
+
+Parameters:
+	name: The checkpoint to restore.
+
+Flags:
+	--show-diff: Show the changes made to each file.
+
+Example:
+	eddie checkpoint restore before-refactor`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: name is required")
+			return
+		}
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+
+		checkErr(checkpoint.Restore(args[0], showChanges))
+	},
+}
+
+func init() {
+	checkpointCreateCmd.Flags().String("root", ".", "Directory whose files the checkpoint covers")
+	checkpointRestoreCmd.Flags().Bool("show-diff", false, "Show the changes made to each file")
+	checkpointCmd.AddCommand(checkpointCreateCmd, checkpointListCmd, checkpointRestoreCmd)
+	rootCmd.AddCommand(checkpointCmd)
+}
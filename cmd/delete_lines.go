@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/delete_lines"
+)
+
+var deleteLinesCmd = &cobra.Command{
+	Use:   "delete_lines",
+	Short: "Delete one or more ranges of lines from a file.",
+	Long: `Delete one or more ranges of lines from a file.
+
+Usage:
+	delete_lines path range [range...] [--show-diff] [--show-result]
+
+Parameters:
+	path: The path to the file to modify.
+	range: A line number "N" or an inclusive range "start,end" (1-based). Use -1 as
+	       end for the end of the file. Ranges may be given in any order and may overlap.
+
+Flags:
+	--show-diff: Show the changes made to the file.
+	--show-result: Show the new content after the edit operation.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
+
+Example:
+	eddie delete_lines main.go 40,55
+	eddie delete_lines main.go 3 10,12 80,-1 --show-diff`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Println("Error: path and at least one range are required")
+			return
+		}
+		path := args[0]
+		ranges := args[1:]
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+
+		checkErr(delete_lines.DeleteLines(path, ranges, showChanges, showResult, preconditionFlags(cmd)))
+	},
+}
+
+func init() {
+	deleteLinesCmd.Flags().Bool("show-diff", false, "Show the changes made to the file")
+	deleteLinesCmd.Flags().Bool("show-result", false, "Show the new content after the edit operation")
+	addPreconditionFlags(deleteLinesCmd)
+	rootCmd.AddCommand(deleteLinesCmd)
+}
@@ -20,6 +20,10 @@ Parameters:
 	pattern: The glob pattern to match files against
 	[path]: (Optional) The directory to search in. Defaults to current directory.
 
+Flags:
+	--no-ignore: Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes
+	--hidden: Include files and directories whose name starts with a dot
+
 Example:
 	eddie glob "*.go"
 	eddie glob "**/*.js" src/
@@ -35,10 +39,11 @@ Example:
 			path = args[1]
 		}
 
-		checkErr(glob.Glob(pattern, path))
+		checkErr(glob.Glob(pattern, path, ignoreFlags(cmd)))
 	},
 }
 
 func init() {
+	addIgnoreFlags(globCmd)
 	rootCmd.AddCommand(globCmd)
 }
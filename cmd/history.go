@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the recorded edits of a file or move it to any point in them.",
+	Long: `List the recorded edits of a file or move it to any point in them.
+
+Without --goto, this command prints the edit timeline of a file. Each entry shows
+its index, timestamp, edit type and a short diff. Index 0 is the file before its
+first recorded edit, the current state is marked with *, and edits that have been
+undone are marked (undone).
+
+With --goto, the file is restored to its content right after the edit with that
+index. Later edits stay in the timeline and can be reached again with redo_edit
+or another --goto.
+
+Usage:
+	history path [--goto N] [--show-diff] [--show-result] [--force]
+
+Parameters:
+	path: The path to the file whose edit history to show.
+
+Flags:
+	--goto: Restore the file to the state after edit N (0 for the original content).
+	--show-diff: Show the changes made when moving with --goto.
+	--show-result: Show the new content after moving with --goto.
+	--force: Merge the move with changes made outside of eddie.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
+
+Example:
+	eddie history /path/to/file.txt
+	eddie history main.go --goto 2 --show-diff
+	eddie history main.go --goto 0`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: path is required")
+			return
+		}
+		path := args[0]
+
+		if !cmd.Flags().Changed("goto") {
+			checkErr(undo_edit.ShowHistory(path))
+			return
+		}
+
+		index, _ := cmd.Flags().GetInt("goto")
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+		force, _ := cmd.Flags().GetBool("force")
+
+		checkErr(undo_edit.GotoEdit(path, index, showChanges, showResult, force, preconditionFlags(cmd)))
+	},
+}
+
+func init() {
+	historyCmd.Flags().Int("goto", 0, "Restore the file to the state after edit N (0 for the original content)")
+	historyCmd.Flags().Bool("show-diff", false, "Show the changes made when moving with --goto")
+	historyCmd.Flags().Bool("show-result", false, "Show the new content after moving with --goto")
+	historyCmd.Flags().Bool("force", false, "Merge the move with changes made outside of eddie instead of failing")
+	addPreconditionFlags(historyCmd)
+	rootCmd.AddCommand(historyCmd)
+}
@@ -10,42 +10,73 @@ import (
 
 var insertCmd = &cobra.Command{
 	Use:   "insert",
-	Short: "Insert a new line at the specified line number in a file.",
-	Long: `Insert a new line at the specified line number in a file.
+	Short: "Insert lines at the specified line number in a file, or next to an anchor.",
+	Long: `Insert lines at the specified line number in a file, or next to an anchor.
 
 Usage:
-	insert path insert_line new_str [--show-diff] [--show-result]
+	insert path insert_line new_str [--auto-indent] [--show-diff] [--show-result]
+	insert path new_str (--after ANCHOR | --before ANCHOR) [--anchor-type TYPE] [--auto-indent] [--show-diff] [--show-result]
 
 Parameters:
 	path: The path to the file to modify.
-	insert_line: The line number where the new line should be inserted (1-based).
-	new_str: The content of the new line to insert.
+	insert_line: The line number where the new lines should be inserted (1-based).
+	new_str: The lines to insert. A trailing newline does not add an empty line.
 
 Flags:
+	--after: Insert after the lines of the only match of ANCHOR instead of at a line number.
+	--before: Insert before the lines of the only match of ANCHOR instead of at a line number.
+	--anchor-type: How ANCHOR is matched: string, regex or symbol (default: string). A symbol
+	               is a declaration name as accepted by view --symbol.
+	--auto-indent: Re-indent new_str to match the block it is inserted into.
 	--show-diff: Show the changes made to the file.
 	--show-result: Show the new content after the edit operation.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
 
 Example:
 	eddie insert /path/to/file.txt 5 "This is a new line"
 	eddie insert config.json 10 "  \"newKey\": \"newValue\"," --show-diff
-	eddie insert script.sh 1 "#!/bin/bash" --show-result`,
+	eddie insert script.sh 1 "#!/bin/bash" --show-result
+	eddie insert main.go 'import "os"' --after 'import "fmt"'
+	eddie insert main.go "func helper() {}" --after main --anchor-type symbol
+	eddie insert main.go "log.Println(\"start\")" --after '^func main\(\) \{$' --anchor-type regex --auto-indent`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) < 3 {
-			fmt.Println("Error: path, insert_line, and new_str are required")
-			return
-		}
-		path := args[0]
-		insertLine := args[1]
-		newStr := args[2]
+		after, _ := cmd.Flags().GetString("after")
+		before, _ := cmd.Flags().GetString("before")
+		anchorType, _ := cmd.Flags().GetString("anchor-type")
+		autoIndent, _ := cmd.Flags().GetBool("auto-indent")
 		showChanges, _ := cmd.Flags().GetBool("show-diff")
 		showResult, _ := cmd.Flags().GetBool("show-result")
 
-		checkErr(insert.Insert(path, insertLine, newStr, showChanges, showResult))
+		anchor, err := insert.NewAnchor(after, before, anchorType)
+		checkErr(err)
+
+		var path, insertLine, newStr string
+		if anchor.Pattern != "" {
+			if len(args) < 2 {
+				fmt.Println("Error: path and new_str are required")
+				return
+			}
+			path, newStr = args[0], args[1]
+		} else {
+			if len(args) < 3 {
+				fmt.Println("Error: path, insert_line, and new_str are required")
+				return
+			}
+			path, insertLine, newStr = args[0], args[1], args[2]
+		}
+
+		checkErr(insert.Insert(path, insertLine, newStr, anchor, autoIndent, showChanges, showResult, preconditionFlags(cmd)))
 	},
 }
 
 func init() {
+	insertCmd.Flags().String("after", "", "Insert after the only match of this anchor instead of at a line number")
+	insertCmd.Flags().String("before", "", "Insert before the only match of this anchor instead of at a line number")
+	insertCmd.Flags().String("anchor-type", insert.AnchorString, "How the anchor is matched: string, regex or symbol")
+	insertCmd.Flags().Bool("auto-indent", false, "Re-indent new_str to match the block it is inserted into")
 	insertCmd.Flags().Bool("show-diff", false, "Show the changes made to the file")
 	insertCmd.Flags().Bool("show-result", false, "Show the new content after the edit operation")
+	addPreconditionFlags(insertCmd)
 	rootCmd.AddCommand(insertCmd)
 }
@@ -17,6 +17,10 @@ Usage:
 Parameters:
 	[path]: (Optional) The path to the directory to list. Defaults to current directory if not provided.
 
+Flags:
+	--no-ignore: Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes
+	--hidden: Include files and directories whose name starts with a dot
+
 Example:
 	eddie ls
 	eddie ls /path/to/directory`,
@@ -28,10 +32,11 @@ Example:
 			path = "."
 		}
 
-		checkErr(ls.Ls(path))
+		checkErr(ls.Ls(path, ignoreFlags(cmd)))
 	},
 }
 
 func init() {
+	addIgnoreFlags(lsCmd)
 	rootCmd.AddCommand(lsCmd)
 }
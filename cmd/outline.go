@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/outline"
+)
+
+var outlineCmd = &cobra.Command{
+	Use:   "outline",
+	Short: "List the declarations of a source file with their line ranges.",
+	Long: `List the declarations of a source file with their line ranges.
+
+Functions, methods, types, classes, interfaces, traits and impls are found with
+tree-sitter and printed one per line with their signature and line range. Nested
+declarations, such as methods in a class, are indented under their parent. The
+line ranges can be passed to view to read a single declaration.
+
+Usage:
+	outline path [--json]
+
+Parameters:
+	path: The path to the source file. Every language supported by search is supported.
+
+Flags:
+	--json: Print the outline as JSON.
+
+Example:
+	eddie outline server.go
+	eddie outline app.py --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: path is required")
+			return
+		}
+		path := args[0]
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		checkErr(outline.Outline(path, asJSON))
+	},
+}
+
+func init() {
+	outlineCmd.Flags().Bool("json", false, "Print the outline as JSON")
+	rootCmd.AddCommand(outlineCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+)
+
+var redoEditCmd = &cobra.Command{
+	Use:   "redo_edit",
+	Short: "Redo edits to a file that were reverted by undo_edit.",
+	Long: `Redo edits to a file that were reverted by undo_edit.
+
+This command reapplies edits that were undone with undo_edit or history --goto.
+Undone edits stay available until a new edit is made to the file, at which
+point they are discarded. Like undo_edit, it refuses to run on a file changed
+outside of eddie unless --force is given to merge with the change.
+
+Usage:
+	redo_edit path [--show-diff] [--show-result] [--count N] [--force]
+
+Parameters:
+	path: The path to the file to redo edits on.
+
+Flags:
+	--show-diff: Show the changes made during the redo operation.
+	--show-result: Show the new content after the redo operation.
+	--count: Number of edits to redo (default: 1).
+	--force: Merge the redo with changes made outside of eddie.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
+
+Example:
+	eddie redo_edit /path/to/file.txt
+	eddie redo_edit config.json --show-diff
+	eddie redo_edit script.sh --count 2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: path is required")
+			return
+		}
+		path := args[0]
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+		count, _ := cmd.Flags().GetInt("count")
+		force, _ := cmd.Flags().GetBool("force")
+
+		checkErr(undo_edit.RedoEdit(path, showChanges, showResult, count, force, preconditionFlags(cmd)))
+	},
+}
+
+func init() {
+	redoEditCmd.Flags().Bool("show-diff", false, "Show the changes made during the redo operation")
+	redoEditCmd.Flags().Bool("show-result", false, "Show the new content after the redo operation")
+	redoEditCmd.Flags().Int("count", 1, "Number of edits to redo")
+	redoEditCmd.Flags().Bool("force", false, "Merge the redo with changes made outside of eddie instead of failing")
+	addPreconditionFlags(redoEditCmd)
+	rootCmd.AddCommand(redoEditCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/regex_replace"
+)
+
+var regexReplaceCmd = &cobra.Command{
+	Use:   "regex_replace",
+	Short: "Replace regular expression matches in a file, expanding capture groups.",
+	Long: `Replace regular expression matches in a file, expanding capture groups.
+
+The pattern uses Go RE2 syntax. The replacement may reference capture groups
+as $1 or ${name}; use $$ for a literal dollar sign.
+
+Usage:
+	regex_replace path pattern replacement [--multiline] [--ignore-case] [--max-replacements N] [--show-diff] [--show-result]
+
+Parameters:
+	path: The path to the file to modify.
+	pattern: The regular expression to search for.
+	replacement: The replacement text, with $1/${name} capture group expansion.
+
+Flags:
+	--multiline: Make ^ and $ match at line boundaries.
+	--ignore-case: Match case-insensitively.
+	--max-replacements: Replace at most N matches (default: all).
+	--show-diff: Show the changes made to the file.
+	--show-result: Show the new content after the edit operation.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
+
+Example:
+	eddie regex_replace main.go 'fooV1\(' 'fooV2('
+	eddie regex_replace main.go '(\w+)V1\((.*)\)' '${1}V2($2)' --show-diff
+	eddie regex_replace notes.md '^todo:' 'TODO:' --multiline --ignore-case`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			fmt.Println("Error: path, pattern, and replacement are required")
+			return
+		}
+		path := args[0]
+		pattern := args[1]
+		replacement := args[2]
+		multiline, _ := cmd.Flags().GetBool("multiline")
+		ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+		maxReplacements, _ := cmd.Flags().GetInt("max-replacements")
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+
+		checkErr(regex_replace.RegexReplace(path, pattern, replacement, multiline, ignoreCase, maxReplacements, showChanges, showResult, preconditionFlags(cmd)))
+	},
+}
+
+func init() {
+	regexReplaceCmd.Flags().Bool("multiline", false, "Make ^ and $ match at line boundaries")
+	regexReplaceCmd.Flags().Bool("ignore-case", false, "Match case-insensitively")
+	regexReplaceCmd.Flags().Int("max-replacements", 0, "Replace at most N matches (0 replaces all)")
+	regexReplaceCmd.Flags().Bool("show-diff", false, "Show the changes made to the file")
+	regexReplaceCmd.Flags().Bool("show-result", false, "Show the new content after the edit operation")
+	addPreconditionFlags(regexReplaceCmd)
+	rootCmd.AddCommand(regexReplaceCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/cmd/replace_lines"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+var replaceLinesCmd = &cobra.Command{
+	Use:   "replace_lines",
+	Short: "Replace a range of lines in a file with new content.",
+	Long: `Replace a range of lines in a file with new content.
+
+The replacement may contain any number of lines, including none.
+
+Usage:
+	replace_lines path range new_str [--show-diff] [--show-result]
+
+Parameters:
+	path: The path to the file to modify.
+	range: A line number "N" or an inclusive range "start,end" (1-based). Use -1 as
+	       end for the end of the file.
+	new_str: The lines to put in place of the range. An empty string removes the lines.
+
+Flags:
+	--show-diff: Show the changes made to the file.
+	--show-result: Show the new content after the edit operation.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
+
+Example:
+	eddie replace_lines main.go 40,55 "func main() {\n\trun()\n}"
+	eddie replace_lines config.json 3 '  "debug": false,' --show-diff
+	eddie replace_lines notes.txt 10,-1 "" --show-result`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			fmt.Println("Error: path, range, and new_str are required")
+			return
+		}
+		path := args[0]
+		startLine, endLine, err := fileops.ParseLineRange(args[1])
+		checkErr(err)
+		newStr := args[2]
+		showChanges, _ := cmd.Flags().GetBool("show-diff")
+		showResult, _ := cmd.Flags().GetBool("show-result")
+
+		checkErr(replace_lines.ReplaceLines(path, startLine, endLine, newStr, showChanges, showResult, preconditionFlags(cmd)))
+	},
+}
+
+func init() {
+	replaceLinesCmd.Flags().Bool("show-diff", false, "Show the changes made to the file")
+	replaceLinesCmd.Flags().Bool("show-result", false, "Show the new content after the edit operation")
+	addPreconditionFlags(replaceLinesCmd)
+	rootCmd.AddCommand(replaceLinesCmd)
+}
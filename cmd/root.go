@@ -6,11 +6,43 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "eddie",
 	Short: "A text editor designed for AI Agents (e.g. `claude` code), not humans.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		symlinks, _ := cmd.Flags().GetString("symlinks")
+		policy, err := fileops.ParseSymlinkPolicy(symlinks)
+		if err != nil {
+			return err
+		}
+		fileops.Symlinks = policy
+
+		diffContext, _ := cmd.Flags().GetInt("diff-context")
+		if diffContext < 0 {
+			return fmt.Errorf("--diff-context must be >= 0, got %d", diffContext)
+		}
+		display.DiffContext = diffContext
+
+		maxFileSize, _ := cmd.Flags().GetString("max-file-size")
+		size, err := fileops.ParseSize(maxFileSize)
+		if err != nil {
+			return fmt.Errorf("--max-file-size: %w", err)
+		}
+		fileops.MaxFileSize = size
+
+		fileops.AllowBinary, _ = cmd.Flags().GetBool("allow-binary")
+
+		format, _ := cmd.Flags().GetString("output")
+		output.Format, err = output.ParseFormat(format)
+		return err
+	},
 }
 
 func Execute() {
@@ -21,11 +53,43 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("symlinks", string(fileops.SymlinkFollow), "How to edit files that are symlinks: follow, refuse or replace")
+	rootCmd.PersistentFlags().Int("diff-context", display.DiffContext, "Number of unchanged lines shown around each change by --show-diff")
+	rootCmd.PersistentFlags().String("max-file-size", "64M", "Largest file read whole to edit or hash it, with an optional K, M or G suffix (0 for no limit)")
+	rootCmd.PersistentFlags().Bool("allow-binary", false, "Edit and view files that look binary")
+	rootCmd.PersistentFlags().String("output", output.FormatText, "Output format: text, or json for a typed result per command")
 }
 
 func checkErr(err error) {
 	if err != nil {
 		fmt.Fprintln(os.Stderr, color.RedString("Error:"), err)
+		fmt.Fprint(os.Stderr, output.ErrorDetails(err))
 		os.Exit(1)
 	}
 }
+
+// addPreconditionFlags registers the flags read by preconditionFlags on a
+// command that modifies a file.
+func addPreconditionFlags(c *cobra.Command) {
+	c.Flags().String("expected-sha256", "", "Fail with a conflict unless the file has this SHA-256, as printed by view")
+	c.Flags().String("expected-mtime", "", "Fail with a conflict unless the file has this modification time, as printed by view")
+}
+
+func preconditionFlags(c *cobra.Command) fileops.Precondition {
+	sha, _ := c.Flags().GetString("expected-sha256")
+	mtime, _ := c.Flags().GetString("expected-mtime")
+	return fileops.Precondition{SHA256: sha, ModTime: mtime}
+}
+
+// addIgnoreFlags registers the flags read by ignoreFlags on a command that
+// walks directories.
+func addIgnoreFlags(c *cobra.Command) {
+	c.Flags().Bool("no-ignore", false, "Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes")
+	c.Flags().Bool("hidden", false, "Include files and directories whose name starts with a dot")
+}
+
+func ignoreFlags(c *cobra.Command) ignore.Options {
+	noIgnore, _ := c.Flags().GetBool("no-ignore")
+	hidden, _ := c.Flags().GetBool("hidden")
+	return ignore.Options{NoIgnore: noIgnore, Hidden: hidden}
+}
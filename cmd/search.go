@@ -10,21 +10,51 @@ import (
 
 var searchCmd = &cobra.Command{
 	Use:   "search",
-	Short: "Search for code patterns using tree-sitter queries across files.",
-	Long: `Search for code patterns using tree-sitter queries across files.
+	Short: "Search for code patterns using tree-sitter queries, or for text, across files.",
+	Long: `Search for code patterns using tree-sitter queries, or for text, across files.
 
 Usage:
-	search <file|dir> --tree-sitter-query "<tree-sitter-query>"
+	search <file|dir> --tree-sitter-query "<tree-sitter-query>" [flags]
+	search <file|dir> --query-file <query.scm> [flags]
+	search <file|dir> --pattern "<regex>" [flags]
 
 Parameters:
 	<file|dir>: Path to file or directory to search.
 
 Flags:
-	--tree-sitter-query: Tree-sitter query pattern (required).
+	--tree-sitter-query: Tree-sitter query, one or more patterns. Predicates such as
+	  #eq?, #not-eq?, #match?, #not-match?, #any-of? and their any- forms filter the matches.
+	--query-file: File with the tree-sitter query, such as a .scm file with many patterns.
+	--capture NAME: Only print the nodes captured as @NAME (repeatable).
+	--show-text: Print the whole text of each captured node instead of its first line.
+	--show-span: Print the end position and byte range of each captured node.
+	--pattern: Regular expression (Go RE2 syntax) to search for in any text file, line by line.
+	--fixed-strings: Search for the pattern as a literal string.
+	--ignore-case: Match case-insensitively.
+	--word-regexp: Only match whole words.
+	--after-context N, --before-context N, --context N: Print N lines of context after, before or around each match.
+	--include GLOB: Only search files that match the glob (repeatable, gitignore syntax).
+	--exclude GLOB: Skip files and directories that match the glob (repeatable, gitignore syntax).
+	--files-with-matches: Only print the files that match.
+	--count: Only print the number of matching lines in each file.
+	--no-ignore: Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes.
+	--hidden: Include files and directories whose name starts with a dot.
+
+Exactly one of --tree-sitter-query, --query-file and --pattern is required.
+Matches are grouped by the index of the query pattern that captured them.
+--capture, --show-text and --show-span apply to tree-sitter queries only, and
+the context flags to both. The other flags except --no-ignore and --hidden apply to
+--pattern only. With --output json every match has its end position and byte
+range.
 
 Example:
 	eddie search ./src --tree-sitter-query "(function_declaration name: (identifier) @func)"
-	eddie search main.go --tree-sitter-query "(call_expression function: (identifier) @call)"`,
+	eddie search main.go --tree-sitter-query "(call_expression function: (identifier) @call)"
+	eddie search main.go -q "(function_declaration name: (identifier) @name) @func" --capture func --show-text
+	eddie search . -q '((function_declaration name: (identifier) @test) (#match? @test "^Test"))'
+	eddie search ./src --query-file queries/handlers.scm
+	eddie search . --pattern "TODO|FIXME" --include "*.md" -C 2
+	eddie search config/ --pattern "localhost:8080" --fixed-strings --files-with-matches`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			fmt.Println("Error: file or directory path is required")
@@ -32,16 +62,89 @@ Example:
 		}
 		path := args[0]
 		query, _ := cmd.Flags().GetString("tree-sitter-query")
-		if query == "" {
-			fmt.Println("Error: --tree-sitter-query flag is required")
+		queryFile, _ := cmd.Flags().GetString("query-file")
+		pattern, _ := cmd.Flags().GetString("pattern")
+		given := 0
+		for _, v := range []string{query, queryFile, pattern} {
+			if v != "" {
+				given++
+			}
+		}
+		if given != 1 {
+			fmt.Println("Error: exactly one of --tree-sitter-query, --query-file and --pattern is required")
 			return
 		}
 
-		checkErr(search.Search(path, query))
+		if queryFile != "" {
+			var err error
+			query, err = search.ReadQuery(queryFile)
+			checkErr(err)
+		}
+		if query != "" {
+			checkErr(search.Search(path, query, searchFlags(cmd), ignoreFlags(cmd)))
+			return
+		}
+		checkErr(search.Grep(path, grepFlags(cmd, pattern), ignoreFlags(cmd)))
 	},
 }
 
+func searchFlags(c *cobra.Command) search.SearchOptions {
+	var opts search.SearchOptions
+	opts.Captures, _ = c.Flags().GetStringArray("capture")
+	opts.ShowText, _ = c.Flags().GetBool("show-text")
+	opts.ShowSpan, _ = c.Flags().GetBool("show-span")
+	opts.Before, opts.After = contextFlags(c)
+	return opts
+}
+
+func grepFlags(c *cobra.Command, pattern string) search.GrepOptions {
+	opts := search.GrepOptions{Pattern: pattern}
+	opts.Literal, _ = c.Flags().GetBool("fixed-strings")
+	opts.IgnoreCase, _ = c.Flags().GetBool("ignore-case")
+	opts.WordRegexp, _ = c.Flags().GetBool("word-regexp")
+	opts.Before, opts.After = contextFlags(c)
+	opts.Include, _ = c.Flags().GetStringArray("include")
+	opts.Exclude, _ = c.Flags().GetStringArray("exclude")
+	opts.FilesWithMatches, _ = c.Flags().GetBool("files-with-matches")
+	opts.Count, _ = c.Flags().GetBool("count")
+	return opts
+}
+
+// contextFlags returns the lines of context before and after each match, with
+// --context applying to whichever of --before-context and --after-context is
+// not set.
+func contextFlags(c *cobra.Command) (before, after int) {
+	before, _ = c.Flags().GetInt("before-context")
+	after, _ = c.Flags().GetInt("after-context")
+	if c.Flags().Changed("context") {
+		context, _ := c.Flags().GetInt("context")
+		if !c.Flags().Changed("before-context") {
+			before = context
+		}
+		if !c.Flags().Changed("after-context") {
+			after = context
+		}
+	}
+	return before, after
+}
+
 func init() {
-	searchCmd.Flags().StringP("tree-sitter-query", "q", "", "Tree-sitter query pattern (required)")
+	searchCmd.Flags().StringP("tree-sitter-query", "q", "", "Tree-sitter query pattern")
+	searchCmd.Flags().String("query-file", "", "File with the tree-sitter query, such as a .scm file")
+	searchCmd.Flags().StringArray("capture", nil, "Only print the nodes captured with this name (repeatable)")
+	searchCmd.Flags().Bool("show-text", false, "Print the whole text of each captured node")
+	searchCmd.Flags().Bool("show-span", false, "Print the end position and byte range of each captured node")
+	searchCmd.Flags().StringP("pattern", "e", "", "Regular expression to search for in any text file")
+	searchCmd.Flags().BoolP("fixed-strings", "F", false, "Search for the pattern as a literal string")
+	searchCmd.Flags().BoolP("ignore-case", "i", false, "Match the pattern case-insensitively")
+	searchCmd.Flags().BoolP("word-regexp", "w", false, "Only match the pattern as a whole word")
+	searchCmd.Flags().IntP("after-context", "A", 0, "Lines of context to print after each match")
+	searchCmd.Flags().IntP("before-context", "B", 0, "Lines of context to print before each match")
+	searchCmd.Flags().IntP("context", "C", 0, "Lines of context to print around each match")
+	searchCmd.Flags().StringArray("include", nil, "Only search files that match this glob (repeatable)")
+	searchCmd.Flags().StringArray("exclude", nil, "Skip files and directories that match this glob (repeatable)")
+	searchCmd.Flags().BoolP("files-with-matches", "l", false, "Only print the files that match")
+	searchCmd.Flags().BoolP("count", "c", false, "Only print the number of matching lines in each file")
+	addIgnoreFlags(searchCmd)
 	rootCmd.AddCommand(searchCmd)
 }
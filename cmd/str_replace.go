@@ -14,7 +14,7 @@ var strReplaceCmd = &cobra.Command{
 	Long: `Replace all occurrences of a string in a file with another string.
 
 Usage:
-	str_replace path old_str new_str [--show-diff] [--show-result]
+	str_replace path old_str new_str [--show-diff] [--show-result] [--unique] [--expected-count N] [--fuzzy]
 
 Parameters:
 	path: The path to the file to modify.
@@ -24,11 +24,19 @@ Parameters:
 Flags:
 	--show-diff: Show the changes made to the file.
 	--show-result: Show the new content after the edit operation.
+	--unique: Fail unless old_str occurs exactly once in the file. Cannot be combined with --expected-count.
+	--expected-count: Fail unless old_str occurs exactly N times in the file.
+	--fuzzy: If old_str does not occur, replace its only occurrence ignoring differences
+	         in whitespace and indentation, re-indenting new_str to match.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
 
 Example:
 	eddie str_replace /path/to/file.txt "old text" "new text"
 	eddie str_replace config.json "localhost" "example.com" --show-diff
-	eddie str_replace config.json "localhost" "example.com" --show-result`,
+	eddie str_replace config.json "localhost" "example.com" --show-result
+	eddie str_replace main.go "return nil" "return err" --unique
+	eddie str_replace main.go "if err != nil {\n    return nil\n}" "if err != nil {\n    return err\n}" --fuzzy`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 3 {
 			fmt.Println("Error: path, old_str, and new_str are required")
@@ -39,13 +47,22 @@ Example:
 		newStr := args[2]
 		showChanges, _ := cmd.Flags().GetBool("show-diff")
 		showResult, _ := cmd.Flags().GetBool("show-result")
+		unique, _ := cmd.Flags().GetBool("unique")
+		expectedCount, _ := cmd.Flags().GetInt("expected-count")
+		fuzzy, _ := cmd.Flags().GetBool("fuzzy")
+		expectedCount, err := str_replace.ExpectedCount(unique, expectedCount)
+		checkErr(err)
 
-		checkErr(str_replace.StrReplace(path, oldStr, newStr, showChanges, showResult))
+		checkErr(str_replace.StrReplace(path, oldStr, newStr, expectedCount, fuzzy, showChanges, showResult, preconditionFlags(cmd)))
 	},
 }
 
 func init() {
 	strReplaceCmd.Flags().Bool("show-diff", false, "Show the changes made to the file")
 	strReplaceCmd.Flags().Bool("show-result", false, "Show the new content after the edit operation")
+	strReplaceCmd.Flags().Bool("unique", false, "Fail unless old_str occurs exactly once")
+	strReplaceCmd.Flags().Int("expected-count", 0, "Fail unless old_str occurs exactly this many times")
+	strReplaceCmd.Flags().Bool("fuzzy", false, "If old_str does not occur, replace its only occurrence ignoring whitespace and re-indent new_str")
+	addPreconditionFlags(strReplaceCmd)
 	rootCmd.AddCommand(strReplaceCmd)
 }
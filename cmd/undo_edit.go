@@ -15,10 +15,15 @@ var undoEditCmd = &cobra.Command{
 
 This command restores a file to its previous state before the last edit operation 
 (str_replace, insert, etc.). It looks for the most recent backup file and restores 
-the original content.
+the original content. Undoing a create removes the file along with any parent
+directories that were made for it.
+
+If the file was changed outside of eddie since its last tracked edit, the change
+is shown and the undo is refused. With --force, the undo is merged with the change
+instead, failing only if both touch the same lines.
 
 Usage:
-	undo_edit path [--show-diff] [--show-result] [--count N]
+	undo_edit path [--show-diff] [--show-result] [--count N] [--force]
 
 Parameters:
 	path: The path to the file to restore from backup.
@@ -27,6 +32,9 @@ Flags:
 	--show-diff: Show the changes made during the undo operation.
 	--show-result: Show the new content after the undo operation.
 	--count: Number of edits to undo (default: 1).
+	--force: Merge the undo with changes made outside of eddie.
+	--expected-sha256: Fail with a conflict unless the file has this SHA-256, as printed by view.
+	--expected-mtime: Fail with a conflict unless the file has this modification time, as printed by view.
 
 Example:
 	eddie undo_edit /path/to/file.txt
@@ -42,8 +50,9 @@ Example:
 		showChanges, _ := cmd.Flags().GetBool("show-diff")
 		showResult, _ := cmd.Flags().GetBool("show-result")
 		count, _ := cmd.Flags().GetInt("count")
+		force, _ := cmd.Flags().GetBool("force")
 
-		checkErr(undo_edit.UndoEdit(path, showChanges, showResult, count))
+		checkErr(undo_edit.UndoEdit(path, showChanges, showResult, count, force, preconditionFlags(cmd)))
 	},
 }
 
@@ -51,5 +60,7 @@ func init() {
 	undoEditCmd.Flags().Bool("show-diff", false, "Show the changes made during the undo operation")
 	undoEditCmd.Flags().Bool("show-result", false, "Show the new content after the undo operation")
 	undoEditCmd.Flags().Int("count", 1, "Number of edits to undo")
+	undoEditCmd.Flags().Bool("force", false, "Merge the undo with changes made outside of eddie instead of failing")
+	addPreconditionFlags(undoEditCmd)
 	rootCmd.AddCommand(undoEditCmd)
 }
@@ -13,17 +13,42 @@ var viewCmd = &cobra.Command{
 	Short: "Examine the contents of a file or list the contents of a directory. It can read the entire file or a specific range of lines.",
 	Long: `Examine the contents of a file or list the contents of a directory. It can read the entire file or a specific range of lines.
 
+File views start with a line giving the SHA-256 and modification time of the whole
+file. Pass either to an edit command as --expected-sha256 or --expected-mtime to
+make the edit fail if the file changes in between.
+Files over --max-file-size are not hashed; their header gives the size instead, and
+a range only reads the file up to its last line. Files that look binary are refused
+unless --allow-binary is set.
+
+Lines are numbered like cat -n. Lines longer than --max-line-length bytes are cut
+off with a marker, and output stops with a "N more lines" trailer once --max-lines
+lines or --max-bytes bytes have been printed.
+
 Usage:
-	view path [view_range]
+	view path [view_range | --symbol NAME] [--line-numbers=false] [--show-format] [--max-line-length N] [--max-lines N] [--max-bytes N]
 
 Parameters:
 	path: The path to the file or directory to view.
 	[view_range]: (Optional) An optional parameter specifying the range of lines to view in a file, formatted as "start,end". If "end" is -1, it means read to the end of the file. This parameter is ignored when viewing directories.
 
+Flags:
+	--symbol: View the declaration with this name instead of a range, including its doc
+	          comment. Qualify the name with its enclosing declarations to pick one of
+	          several matches, e.g. McpServer.handleBatch. Ambiguous names list the candidates.
+	--line-numbers: Prefix each line with its line number (default: true).
+	--show-format: Also print the detected encoding, BOM and line endings of the file, such as
+	               "format: UTF-16LE, BOM, CRLF". Files not in UTF-8 are shown decoded, and
+	               edits keep their format.
+	--max-line-length: Truncate lines longer than N bytes, 0 for no limit (default: 2000).
+	--max-lines: Print at most N lines, 0 for no limit.
+	--max-bytes: Print at most N bytes of lines, 0 for no limit.
+
 Example:
 	eddie view /path/to/file.txt
 	eddie view /path/to/directory
-	eddie view /path/to/file.txt 10,20`,
+	eddie view /path/to/file.txt 10,20
+	eddie view server.go --symbol McpServer.handleBatch
+	eddie view /path/to/bundle.min.js --max-line-length 200 --max-lines 100`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			fmt.Println("Error: path is required")
@@ -35,10 +60,23 @@ Example:
 			viewRange = args[1]
 		}
 
-		checkErr(view.View(path, viewRange))
+		symbol, _ := cmd.Flags().GetString("symbol")
+		lineNumbers, _ := cmd.Flags().GetBool("line-numbers")
+		showFormat, _ := cmd.Flags().GetBool("show-format")
+		maxLineLength, _ := cmd.Flags().GetInt("max-line-length")
+		maxLines, _ := cmd.Flags().GetInt("max-lines")
+		maxBytes, _ := cmd.Flags().GetInt("max-bytes")
+
+		checkErr(view.View(path, viewRange, symbol, lineNumbers, showFormat, maxLineLength, maxLines, maxBytes))
 	},
 }
 
 func init() {
+	viewCmd.Flags().String("symbol", "", "View the declaration with this name, e.g. Type.method")
+	viewCmd.Flags().Bool("line-numbers", true, "Prefix each line with its line number")
+	viewCmd.Flags().Bool("show-format", false, "Print the detected encoding, BOM and line endings of the file")
+	viewCmd.Flags().Int("max-line-length", view.DefaultMaxLineLength, "Truncate lines longer than N bytes (0 for no limit)")
+	viewCmd.Flags().Int("max-lines", 0, "Print at most N lines (0 for no limit)")
+	viewCmd.Flags().Int("max-bytes", 0, "Print at most N bytes of lines (0 for no limit)")
 	rootCmd.AddCommand(viewCmd)
 }
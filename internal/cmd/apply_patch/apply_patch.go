@@ -0,0 +1,12 @@
+package apply_patch
+
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func ApplyPatch(patch string, fuzz, maxOffset int, showChanges, showResult bool, pres map[string]fileops.Precondition) error {
+	_, err := NewPatcher(os.Stdout).ApplyPatch(patch, fuzz, maxOffset, showChanges, showResult, pres)
+	return err
+}
@@ -0,0 +1,404 @@
+package apply_patch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/diff"
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+// DefaultFuzz is the number of context lines ignored at each end of a hunk
+// that does not fit otherwise, as in patch.
+const DefaultFuzz = 2
+
+type Patcher struct {
+	fileOps *fileops.FileOps
+	out     io.Writer
+}
+
+func NewPatcher(w io.Writer) *Patcher {
+	return &Patcher{
+		fileOps: &fileops.FileOps{},
+		out:     w,
+	}
+}
+
+// fileChange is the result of applying the patches of one file. Patches
+// apply to text, the file decoded in format, which is encoded into After once
+// every patch has been applied. Info is nil for created files.
+type fileChange struct {
+	undo_edit.FileChange
+	info     os.FileInfo
+	mode     os.FileMode
+	format   fileops.Format
+	original string
+	text     string
+}
+
+// Hunk is the result of applying hunk Number of a file's patch. Line is
+// where it was applied, or where its header placed it if it failed.
+type Hunk struct {
+	Number  int  `json:"number"`
+	Applied bool `json:"applied"`
+	Line    int  `json:"line"`
+	Offset  int  `json:"offset,omitempty"`
+	Fuzz    int  `json:"fuzz,omitempty"`
+}
+
+// PatchedFile is the result of applying the hunks of one file in a patch.
+type PatchedFile struct {
+	Path  string `json:"path"`
+	Hunks []Hunk `json:"hunks"`
+}
+
+// Result is the result of ApplyPatch: every hunk of every file in the patch,
+// and the edits made once all of them applied.
+type Result struct {
+	Files   []PatchedFile  `json:"files"`
+	Edits   []*output.Edit `json:"edits,omitempty"`
+	Hunks   int            `json:"hunks"`
+	Failed  int            `json:"failed"`
+	Summary string         `json:"summary,omitempty"`
+}
+
+func (r *Result) String() string {
+	var b strings.Builder
+	for _, file := range r.Files {
+		fmt.Fprintf(&b, "patching file %s\n", file.Path)
+		for _, hunk := range file.Hunks {
+			b.WriteString(hunk.String() + "\n")
+		}
+	}
+	for _, edit := range r.Edits {
+		b.WriteString(edit.String())
+	}
+	if r.Summary != "" {
+		b.WriteString(r.Summary + "\n")
+	}
+	return b.String()
+}
+
+// ApplyPatch applies a unified diff that can change, create and delete any
+// number of files. Hunks that do not fit where their header places them are
+// moved up to maxOffset lines, or any distance if maxOffset is 0, and then
+// applied ignoring up to fuzz lines of context. Every hunk is reported, and
+// nothing is written unless all of them apply and every file can be written.
+// If writing a file still fails, the files already written are restored. The
+// changes are recorded as one edit, so undoing it in any of the files undoes
+// all of them. The hunks reported so far are printed and returned even if the
+// patch fails.
+//
+// pres maps paths changed by the patch to preconditions, which are checked
+// before anything is written.
+func (p *Patcher) ApplyPatch(patch string, fuzz, maxOffset int, showChanges, showResult bool, pres map[string]fileops.Precondition) (*Result, error) {
+	res, err := p.apply(patch, fuzz, maxOffset, showChanges, showResult, pres)
+	if res != nil {
+		printErr := output.Print(p.out, res)
+		if err == nil {
+			err = printErr
+		}
+	}
+	return res, err
+}
+
+func (p *Patcher) apply(patch string, fuzz, maxOffset int, showChanges, showResult bool, pres map[string]fileops.Precondition) (*Result, error) {
+	if fuzz < 0 {
+		return nil, fmt.Errorf("fuzz must be >= 0, got %d", fuzz)
+	}
+	if maxOffset <= 0 {
+		maxOffset = -1
+	}
+
+	files, err := diff.ParsePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("parse patch: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch")
+	}
+
+	res := &Result{}
+	var changes []*fileChange
+	byPath := make(map[string]*fileChange)
+	applied := make(map[string]int)
+	for _, file := range files {
+		change, err := p.change(file, byPath)
+		if err != nil {
+			return res, err
+		}
+		if _, ok := byPath[change.Path]; !ok {
+			byPath[change.Path] = change
+			changes = append(changes, change)
+		}
+
+		patched := PatchedFile{Path: change.Path, Hunks: []Hunk{}}
+		after, results := file.Apply(change.text, fuzz, maxOffset)
+		for i, result := range results {
+			res.Hunks++
+			hunk := Hunk{Number: i + 1, Applied: result.Applied, Line: result.Line, Offset: result.Offset, Fuzz: result.Fuzz}
+			if !result.Applied {
+				res.Failed++
+				hunk.Line = file.Hunks[i].OldStart
+			}
+			patched.Hunks = append(patched.Hunks, hunk)
+		}
+		res.Files = append(res.Files, patched)
+		applied[change.Path] += len(results)
+		change.text = after
+
+		if file.NewName == diff.DevNull {
+			if after != "" {
+				return res, fmt.Errorf("cannot delete %s: lines not removed by the patch remain", change.Path)
+			}
+			change.Deleted = true
+		}
+	}
+
+	if res.Failed > 0 {
+		return res, fmt.Errorf("%d of %d hunk(s) failed; no files were changed", res.Failed, res.Hunks)
+	}
+
+	for _, change := range changes {
+		change.After, err = change.format.EncodeText(change.text)
+		if err != nil {
+			return res, fmt.Errorf("encode %s: %w", change.Path, err)
+		}
+	}
+
+	err = checkPreconditions(changes, pres)
+	if err != nil {
+		return res, fmt.Errorf("%w; no files were changed", err)
+	}
+
+	var writes []*fileChange
+	for _, change := range changes {
+		if change.Created && change.Deleted || !change.Created && !change.Deleted && change.Before == change.After {
+			continue
+		}
+		if !change.Deleted {
+			err = p.fileOps.CheckWrite(change.Path)
+			if err != nil {
+				return res, fmt.Errorf("%w; no files were changed", err)
+			}
+		}
+		writes = append(writes, change)
+	}
+
+	var recorded []undo_edit.FileChange
+	for i, change := range writes {
+		err = p.write(change)
+		if err != nil {
+			res.Edits = nil
+			return res, p.rollback(writes[:i], err)
+		}
+		recorded = append(recorded, change.FileChange)
+
+		edit := &output.Edit{
+			Command: "apply_patch",
+			Path:    change.Path,
+			Count:   applied[change.Path],
+			Created: change.Created,
+			Deleted: change.Deleted,
+		}
+		if showChanges {
+			var unified string
+			if change.Created {
+				unified = display.NewFileDiff(change.Path, change.text)
+			} else {
+				unified = display.Diff(change.Path, change.original, change.text)
+			}
+			edit.Diff = &unified
+		}
+		if showResult && !change.Deleted {
+			edit.Content = &change.text
+		}
+		res.Edits = append(res.Edits, edit)
+	}
+
+	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
+	err = undoEditor.RecordGroup("apply_patch", recorded)
+	if err != nil {
+		return res, fmt.Errorf("record edit: %w", err)
+	}
+
+	res.Summary = fmt.Sprintf("Applied %d hunk(s) to %d file(s)", res.Hunks, len(recorded))
+	return res, nil
+}
+
+// checkPreconditions returns an error if a file in pres does not match its
+// precondition or is not changed by the patch.
+func checkPreconditions(changes []*fileChange, pres map[string]fileops.Precondition) error {
+	if len(pres) == 0 {
+		return nil
+	}
+
+	byPath := make(map[string]*fileChange, len(changes))
+	for _, change := range changes {
+		absPath, err := filepath.Abs(change.Path)
+		if err != nil {
+			return fmt.Errorf("get absolute path: %w", err)
+		}
+		byPath[absPath] = change
+	}
+
+	paths := make([]string, 0, len(pres))
+	for path := range pres {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("get absolute path: %w", err)
+		}
+		change, ok := byPath[absPath]
+		if !ok {
+			return fmt.Errorf("precondition for %s, which the patch does not change", path)
+		}
+		if change.info == nil {
+			return fmt.Errorf("%w: %s does not exist", fileops.ErrConflict, change.Path)
+		}
+		err = pres[path].Check(change.Path, change.Before, change.info)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// change returns the state of the file patched by file, reading it unless an
+// earlier part of the patch already changed it.
+func (p *Patcher) change(file diff.FilePatch, byPath map[string]*fileChange) (*fileChange, error) {
+	path := file.NewName
+	if file.NewName == diff.DevNull {
+		path = file.OldName
+	} else if file.OldName != diff.DevNull && file.OldName != file.NewName {
+		// Plain diffs name the original and the changed copy; patch the one
+		// that exists.
+		if _, err := os.Stat(file.NewName); err != nil {
+			path = file.OldName
+		}
+	}
+
+	if change, ok := byPath[path]; ok {
+		if change.Deleted {
+			return nil, fmt.Errorf("cannot patch %s: it is deleted earlier in the patch", path)
+		}
+		if file.OldName == diff.DevNull {
+			return nil, fmt.Errorf("cannot create %s: it is patched earlier in the patch", path)
+		}
+		return change, nil
+	}
+
+	if file.OldName == diff.DevNull {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("cannot create %s: file already exists", path)
+		}
+		return &fileChange{FileChange: undo_edit.FileChange{Path: path, Created: true}, mode: 0o644}, nil
+	}
+
+	content, info, err := p.fileOps.ReadFileContentForOperation(path, "patch")
+	if err != nil {
+		return nil, err
+	}
+	text, format, err := fileops.DecodeText(content)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return &fileChange{
+		FileChange: undo_edit.FileChange{Path: path, Before: content},
+		info:       info,
+		mode:       info.Mode(),
+		format:     format,
+		original:   text,
+		text:       text,
+	}, nil
+}
+
+func (p *Patcher) write(change *fileChange) error {
+	switch {
+	case change.Created:
+		createdDirs, err := p.fileOps.CreateFile(change.Path, change.After)
+		if err != nil {
+			return err
+		}
+		change.CreatedDirs = createdDirs
+	case change.Deleted:
+		err := os.Remove(change.Path)
+		if err != nil {
+			return fmt.Errorf("remove %s: %w", change.Path, err)
+		}
+	default:
+		err := p.fileOps.WriteFileContent(change.Path, change.After, change.mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback reverts written, the changes written before writing the patch
+// failed with err, last first. Changes that cannot be reverted are recorded
+// instead, so undo_edit can take them back.
+func (p *Patcher) rollback(written []*fileChange, err error) error {
+	var kept []undo_edit.FileChange
+	var errs []error
+	for i := len(written) - 1; i >= 0; i-- {
+		revertErr := p.revert(written[i])
+		if revertErr != nil {
+			errs = append(errs, revertErr)
+			kept = append(kept, written[i].FileChange)
+		}
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("%w; no files were changed", err)
+	}
+
+	errs = append(errs, undo_edit.NewUndoEditor(os.Stdout).RecordGroup("apply_patch", kept))
+	return fmt.Errorf("%w; rolling back failed, so the files left patched were recorded as one edit: %w", err, errors.Join(errs...))
+}
+
+// revert puts the file written by write for change back as it was.
+func (p *Patcher) revert(change *fileChange) error {
+	if !change.Created {
+		return p.fileOps.WriteFileContent(change.Path, change.Before, change.mode)
+	}
+
+	err := os.Remove(change.Path)
+	if err != nil {
+		return fmt.Errorf("remove %s: %w", change.Path, err)
+	}
+	for i := len(change.CreatedDirs) - 1; i >= 0; i-- {
+		_ = os.Remove(change.CreatedDirs[i])
+	}
+	return nil
+}
+
+// String describes the hunk the way patch does.
+func (h Hunk) String() string {
+	if !h.Applied {
+		return fmt.Sprintf("Hunk #%d FAILED at %d.", h.Number, h.Line)
+	}
+
+	report := fmt.Sprintf("Hunk #%d succeeded at %d", h.Number, h.Line)
+	if h.Fuzz > 0 {
+		report += fmt.Sprintf(" with fuzz %d", h.Fuzz)
+	}
+	switch h.Offset {
+	case 0:
+	case 1, -1:
+		report += fmt.Sprintf(" (offset %d line)", h.Offset)
+	default:
+		report += fmt.Sprintf(" (offset %d lines)", h.Offset)
+	}
+	return report + "."
+}
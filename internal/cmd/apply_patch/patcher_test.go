@@ -0,0 +1,338 @@
+package apply_patch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func setCacheHome(t *testing.T) {
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	t.Cleanup(func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	})
+	os.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func TestPatcher_ApplyPatch(t *testing.T) {
+	setCacheHome(t)
+	tmpDir := t.TempDir()
+
+	main := filepath.Join(tmpDir, "main.go")
+	old := filepath.Join(tmpDir, "old.txt")
+	created := filepath.Join(tmpDir, "sub", "new.txt")
+	require.NoError(t, os.WriteFile(main, []byte("package main\n\n// added above\nfunc main() {\n\tprintln(1)\n}\n"), 0o644))
+	require.NoError(t, os.WriteFile(old, []byte("bye\n"), 0o644))
+
+	patch := "diff --git a/main.go b/main.go\n" +
+		"--- a/" + main + "\n" +
+		"+++ b/" + main + "\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" package main\n" +
+		"\n" +
+		" func main() {\n" +
+		"-\tprintln(1)\n" +
+		"+\tprintln(2)\n" +
+		" }\n" +
+		"--- " + old + "\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-bye\n" +
+		"--- /dev/null\n" +
+		"+++ " + created + "\n" +
+		"@@ -0,0 +1,2 @@\n" +
+		"+hello\n" +
+		"+world\n"
+
+	var buf bytes.Buffer
+	p := NewPatcher(&buf)
+	res, err := p.ApplyPatch(patch, DefaultFuzz, 0, false, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Applied 3 hunk(s) to 3 file(s)", res.Summary)
+	assert.Len(t, res.Edits, 3)
+	assert.Equal(t, buf.String(), res.String())
+
+	content, err := os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\n// added above\nfunc main() {\n\tprintln(2)\n}\n", string(content))
+	assert.NoFileExists(t, old)
+	content, err = os.ReadFile(created)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", string(content))
+
+	// Undoing the patch in one file undoes it in all of them.
+	u := undo_edit.NewUndoEditor(&buf)
+	_, err = u.UndoEdit(created, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\n// added above\nfunc main() {\n\tprintln(1)\n}\n", string(content))
+	content, err = os.ReadFile(old)
+	require.NoError(t, err)
+	assert.Equal(t, "bye\n", string(content))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "sub"))
+
+	_, err = u.RedoEdit(main, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "println(2)")
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, created)
+}
+
+func TestPatcher_ApplyPatch_CRLF(t *testing.T) {
+	setCacheHome(t)
+	path := filepath.Join(t.TempDir(), "win.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xef\xbb\xbfone\r\ntwo\r\nthree\r\n"), 0o644))
+
+	patch := "--- " + path + "\n" +
+		"+++ " + path + "\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"+2\n" +
+		"+2.5\n" +
+		" three\n"
+
+	var buf bytes.Buffer
+	_, err := NewPatcher(&buf).ApplyPatch(patch, DefaultFuzz, 0, false, false, nil)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "\xef\xbb\xbfone\r\n2\r\n2.5\r\nthree\r\n", string(content))
+}
+
+func TestPatcher_ApplyPatch_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		fuzz    int
+		deletes bool
+		wantErr string
+	}{
+		{
+			name:    "failed hunk",
+			content: "a\nb\nc\n",
+			patch:   "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -3 +3 @@\n-x\n+X\n",
+			fuzz:    DefaultFuzz,
+			wantErr: "1 of 2 hunk(s) failed; no files were changed",
+		},
+		{
+			name:    "context needs fuzz",
+			content: "a\nb\nc\n",
+			patch:   "@@ -1,3 +1,3 @@\n z\n-b\n+B\n c\n",
+			wantErr: "1 of 1 hunk(s) failed",
+		},
+		{
+			name:    "delete leaves lines",
+			content: "a\nb\n",
+			patch:   "@@ -1 +0,0 @@\n-a\n",
+			deletes: true,
+			wantErr: "lines not removed by the patch remain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCacheHome(t)
+			path := filepath.Join(t.TempDir(), "file.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			newName := path
+			if tt.deletes {
+				newName = "/dev/null"
+			}
+			patch := "--- " + path + "\n+++ " + newName + "\n" + tt.patch
+
+			var buf bytes.Buffer
+			res, err := NewPatcher(&buf).ApplyPatch(patch, tt.fuzz, 0, false, false, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			require.NotNil(t, res)
+			assert.Empty(t, res.Edits)
+			assert.Contains(t, buf.String(), "patching file "+path+"\n")
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(content))
+		})
+	}
+}
+
+func TestPatcher_ApplyPatch_WriteFails(t *testing.T) {
+	setCacheHome(t)
+	tmpDir := t.TempDir()
+
+	main := filepath.Join(tmpDir, "main.txt")
+	require.NoError(t, os.WriteFile(main, []byte("one\n"), 0o644))
+	modify := "--- " + main + "\n+++ " + main + "\n@@ -1 +1 @@\n-one\n+two\n"
+
+	t.Run("refused symlink", func(t *testing.T) {
+		oldPolicy := fileops.Symlinks
+		defer func() { fileops.Symlinks = oldPolicy }()
+		fileops.Symlinks = fileops.SymlinkRefuse
+
+		target := filepath.Join(tmpDir, "target.txt")
+		link := filepath.Join(tmpDir, "link.txt")
+		require.NoError(t, os.WriteFile(target, []byte("a\n"), 0o644))
+		require.NoError(t, os.Symlink(target, link))
+		patch := modify + "--- " + link + "\n+++ " + link + "\n@@ -1 +1 @@\n-a\n+b\n"
+
+		_, err := NewPatcher(&bytes.Buffer{}).ApplyPatch(patch, DefaultFuzz, 0, false, false, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "refusing to write through symlink")
+		content, err := os.ReadFile(main)
+		require.NoError(t, err)
+		assert.Equal(t, "one\n", string(content))
+	})
+
+	t.Run("rolled back", func(t *testing.T) {
+		// Creating dir/file.txt fails once dir has been created as a file.
+		dir := filepath.Join(tmpDir, "dir")
+		patch := modify +
+			"--- /dev/null\n+++ " + dir + "\n@@ -0,0 +1 @@\n+file\n" +
+			"--- /dev/null\n+++ " + filepath.Join(dir, "file.txt") + "\n@@ -0,0 +1 @@\n+nested\n"
+
+		res, err := NewPatcher(&bytes.Buffer{}).ApplyPatch(patch, DefaultFuzz, 0, false, false, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no files were changed")
+		assert.Empty(t, res.Edits)
+
+		content, err := os.ReadFile(main)
+		require.NoError(t, err)
+		assert.Equal(t, "one\n", string(content))
+		assert.NoFileExists(t, dir)
+
+		_, err = undo_edit.NewUndoEditor(&bytes.Buffer{}).UndoEdit(main, false, false, 1, false, fileops.Precondition{})
+		assert.Error(t, err, "nothing should be recorded")
+	})
+}
+
+func TestPatcher_ApplyPatch_Preconditions(t *testing.T) {
+	setCacheHome(t)
+	tmpDir := t.TempDir()
+
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	created := filepath.Join(tmpDir, "new.txt")
+	patch := "--- " + a + "\n+++ " + a + "\n@@ -1 +1 @@\n-a\n+A\n" +
+		"--- " + b + "\n+++ " + b + "\n@@ -1 +1 @@\n-b\n+B\n" +
+		"--- /dev/null\n+++ " + created + "\n@@ -0,0 +1 @@\n+new\n"
+
+	tests := []struct {
+		name    string
+		pres    func(info os.FileInfo) map[string]fileops.Precondition
+		wantErr string
+	}{
+		{
+			name: "sha256 mismatch",
+			pres: func(os.FileInfo) map[string]fileops.Precondition {
+				return map[string]fileops.Precondition{
+					a: {SHA256: fileops.HashContent("a\n")},
+					b: {SHA256: fileops.HashContent("stale\n")},
+				}
+			},
+			wantErr: "conflict: " + b + " has sha256",
+		},
+		{
+			name: "mtime mismatch",
+			pres: func(info os.FileInfo) map[string]fileops.Precondition {
+				return map[string]fileops.Precondition{
+					b: {ModTime: fileops.FormatModTime(info.ModTime().Add(-time.Second))},
+				}
+			},
+			wantErr: "conflict: " + b + " was modified at",
+		},
+		{
+			name: "created file",
+			pres: func(os.FileInfo) map[string]fileops.Precondition {
+				return map[string]fileops.Precondition{created: {SHA256: fileops.HashContent("")}}
+			},
+			wantErr: "conflict: " + created + " does not exist",
+		},
+		{
+			name: "file not in patch",
+			pres: func(os.FileInfo) map[string]fileops.Precondition {
+				return map[string]fileops.Precondition{filepath.Join(tmpDir, "other.txt"): {SHA256: fileops.HashContent("")}}
+			},
+			wantErr: "which the patch does not change",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(a, []byte("a\n"), 0o644))
+			require.NoError(t, os.WriteFile(b, []byte("b\n"), 0o644))
+			info, err := os.Stat(b)
+			require.NoError(t, err)
+
+			_, err = NewPatcher(&bytes.Buffer{}).ApplyPatch(patch, DefaultFuzz, 0, false, false, tt.pres(info))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Contains(t, err.Error(), "no files were changed")
+
+			content, err := os.ReadFile(a)
+			require.NoError(t, err)
+			assert.Equal(t, "a\n", string(content))
+			assert.NoFileExists(t, created)
+		})
+	}
+
+	t.Run("match", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(a, []byte("a\n"), 0o644))
+		require.NoError(t, os.WriteFile(b, []byte("b\n"), 0o644))
+		info, err := os.Stat(b)
+		require.NoError(t, err)
+
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		rel, err := filepath.Rel(wd, a)
+		require.NoError(t, err)
+		pres := map[string]fileops.Precondition{
+			rel: {SHA256: fileops.HashContent("a\n")},
+			b:   {SHA256: fileops.HashContent("b\n"), ModTime: fileops.FormatModTime(info.ModTime())},
+		}
+		res, err := NewPatcher(&bytes.Buffer{}).ApplyPatch(patch, DefaultFuzz, 0, false, false, pres)
+		require.NoError(t, err)
+		assert.Len(t, res.Edits, 3)
+
+		content, err := os.ReadFile(b)
+		require.NoError(t, err)
+		assert.Equal(t, "B\n", string(content))
+	})
+}
+
+func TestHunk_String(t *testing.T) {
+	tests := []struct {
+		name string
+		hunk Hunk
+		want string
+	}{
+		{"exact", Hunk{Number: 1, Applied: true, Line: 3}, "Hunk #1 succeeded at 3."},
+		{"offset", Hunk{Number: 1, Applied: true, Line: 5, Offset: 2}, "Hunk #1 succeeded at 5 (offset 2 lines)."},
+		{"offset one line", Hunk{Number: 1, Applied: true, Line: 2, Offset: -1}, "Hunk #1 succeeded at 2 (offset -1 line)."},
+		{"fuzz", Hunk{Number: 1, Applied: true, Line: 4, Fuzz: 1, Offset: 1}, "Hunk #1 succeeded at 4 with fuzz 1 (offset 1 line)."},
+		{"failed", Hunk{Number: 1, Line: 3}, "Hunk #1 FAILED at 3."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hunk.String())
+		})
+	}
+}
@@ -9,13 +9,21 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/RRethy/eddie/internal/cmd/apply_patch"
 	"github.com/RRethy/eddie/internal/cmd/create"
+	"github.com/RRethy/eddie/internal/cmd/delete_lines"
 	"github.com/RRethy/eddie/internal/cmd/insert"
 	"github.com/RRethy/eddie/internal/cmd/ls"
+	"github.com/RRethy/eddie/internal/cmd/outline"
+	"github.com/RRethy/eddie/internal/cmd/regex_replace"
+	"github.com/RRethy/eddie/internal/cmd/replace_lines"
 	"github.com/RRethy/eddie/internal/cmd/search"
 	"github.com/RRethy/eddie/internal/cmd/str_replace"
 	"github.com/RRethy/eddie/internal/cmd/undo_edit"
 	"github.com/RRethy/eddie/internal/cmd/view"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type Processor struct {
@@ -41,62 +49,146 @@ func (p *Processor) ProcessBatch(req *BatchRequest) (*BatchResponse, error) {
 
 func (p *Processor) processOperation(op Operation) OperationResult {
 	var buf bytes.Buffer
+	var res output.Result
 	var err error
 
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	outputDone := make(chan string)
-	go func() {
-		var output bytes.Buffer
-		output.ReadFrom(r)
-		outputDone <- output.String()
-	}()
+	pre := fileops.Precondition{SHA256: op.ExpectedSHA256, ModTime: op.ExpectedMtime}
+	walk := ignore.Options{NoIgnore: op.NoIgnore, Hidden: op.Hidden}
 
 	switch op.Type {
 	case "view":
-		err = view.View(op.Path, op.ViewRange)
+		lineNumbers := op.LineNumbers == nil || *op.LineNumbers
+		maxLineLength := op.MaxLineLength
+		if maxLineLength == 0 {
+			maxLineLength = view.DefaultMaxLineLength
+		}
+		res, err = result(view.NewViewer(&buf).View(op.Path, op.ViewRange, op.Symbol, lineNumbers, op.ShowFormat, maxLineLength, op.MaxLines, op.MaxBytes))
 	case "str_replace":
-		err = str_replace.NewReplacer(&buf).StrReplace(op.Path, op.OldStr, op.NewStr, op.ShowChanges, op.ShowResult)
+		var expectedCount int
+		expectedCount, err = str_replace.ExpectedCount(op.Unique, op.ExpectedCount)
+		if err != nil {
+			break
+		}
+		res, err = result(str_replace.NewReplacer(&buf).StrReplace(op.Path, op.OldStr, op.NewStr, expectedCount, op.Fuzzy, op.ShowChanges, op.ShowResult, pre))
+	case "regex_replace":
+		res, err = result(regex_replace.NewReplacer(&buf).RegexReplace(op.Path, op.Pattern, op.Replacement, op.Multiline, op.IgnoreCase, op.MaxReplacements, op.ShowChanges, op.ShowResult, pre))
 	case "create":
-		err = create.NewCreator(&buf).Create(op.Path, op.Content, op.ShowChanges, op.ShowResult)
+		res, err = result(create.NewCreator(&buf).Create(op.Path, op.Content, op.ShowChanges, op.ShowResult))
 	case "insert":
-		insertLine := strconv.Itoa(op.InsertLine)
-		err = insert.NewInserter(&buf).Insert(op.Path, insertLine, op.NewStr, op.ShowChanges, op.ShowResult)
+		anchorType := op.AnchorType
+		if anchorType == "" {
+			anchorType = insert.AnchorString
+		}
+		var anchor insert.Anchor
+		anchor, err = insert.NewAnchor(op.After, op.Before, anchorType)
+		if err != nil {
+			break
+		}
+		insertLine := ""
+		if anchor.Pattern == "" {
+			insertLine = strconv.Itoa(op.InsertLine)
+		}
+		res, err = result(insert.NewInserter(&buf).Insert(op.Path, insertLine, op.NewStr, anchor, op.AutoIndent, op.ShowChanges, op.ShowResult, pre))
+	case "replace_lines":
+		res, err = result(replace_lines.NewLineReplacer(&buf).ReplaceLines(op.Path, op.StartLine, op.EndLine, op.NewStr, op.ShowChanges, op.ShowResult, pre))
+	case "delete_lines":
+		res, err = result(delete_lines.NewLineDeleter(&buf).DeleteLines(op.Path, op.Ranges, op.ShowChanges, op.ShowResult, pre))
+	case "apply_patch":
+		fuzz := apply_patch.DefaultFuzz
+		if op.Fuzz != nil {
+			fuzz = *op.Fuzz
+		}
+		res, err = result(apply_patch.NewPatcher(&buf).ApplyPatch(op.Patch, fuzz, op.MaxOffset, op.ShowChanges, op.ShowResult, op.Preconditions))
 	case "undo_edit":
-		err = undo_edit.NewUndoEditor(&buf).UndoEdit(op.Path, op.ShowChanges, op.ShowResult, op.Count)
+		res, err = result(undo_edit.NewUndoEditor(&buf).UndoEdit(op.Path, op.ShowChanges, op.ShowResult, max(op.Count, 1), op.Force, pre))
+	case "redo_edit":
+		res, err = result(undo_edit.NewUndoEditor(&buf).RedoEdit(op.Path, op.ShowChanges, op.ShowResult, max(op.Count, 1), op.Force, pre))
+	case "history":
+		if op.Goto != nil {
+			res, err = result(undo_edit.NewUndoEditor(&buf).GotoEdit(op.Path, *op.Goto, op.ShowChanges, op.ShowResult, op.Force, pre))
+		} else {
+			res, err = result(undo_edit.NewUndoEditor(&buf).ShowHistory(op.Path))
+		}
 	case "ls":
-		err = ls.Ls(op.Path)
+		res, err = result(ls.NewLister(&buf).Ls(op.Path, walk))
 	case "search":
-		err = search.Search(op.Path, op.TreeQuery)
+		opts := search.SearchOptions{
+			Captures: op.Capture,
+			ShowText: op.ShowText,
+			ShowSpan: op.ShowSpan,
+			JSON:     op.JSON,
+		}
+		opts.Before, opts.After = contextOf(op)
+		query := op.TreeQuery
+		if op.QueryFile != "" {
+			query, err = search.ReadQuery(op.QueryFile)
+			if err != nil {
+				break
+			}
+		}
+		res, err = result(search.NewSearcher(&buf).Search(op.Path, query, opts, walk))
+	case "grep":
+		opts := search.GrepOptions{
+			Pattern:          op.Pattern,
+			Literal:          op.FixedStrings,
+			IgnoreCase:       op.IgnoreCase,
+			WordRegexp:       op.WordRegexp,
+			Include:          op.Include,
+			Exclude:          op.Exclude,
+			FilesWithMatches: op.FilesWithMatches,
+			Count:            op.CountMatches,
+		}
+		opts.Before, opts.After = contextOf(op)
+		res, err = result(search.NewSearcher(&buf).Grep(op.Path, opts, walk))
+	case "outline":
+		res, err = result(outline.NewOutliner(&buf).Outline(op.Path, op.JSON))
 	default:
 		err = fmt.Errorf("unknown operation type: %s", op.Type)
 	}
 
-	w.Close()
-	os.Stdout = oldStdout
-	capturedOutput := <-outputDone
-
-	var output string
-	if buf.Len() > 0 {
-		output = buf.String()
-	} else {
-		output = capturedOutput
+	if err != nil {
+		buf.WriteString(output.ErrorDetails(err))
 	}
 
-	result := OperationResult{
+	opResult := OperationResult{
 		Operation: op,
 		Success:   err == nil,
-		Output:    output,
+		Output:    buf.String(),
+		Result:    res,
 	}
 
 	if err != nil {
 		errStr := err.Error()
-		result.Error = &errStr
+		opResult.Error = &errStr
+	}
+
+	return opResult
+}
+
+// result turns the typed result of a command into an output.Result, keeping
+// a nil result nil rather than wrapping it in a non-nil interface.
+func result[T any, P interface {
+	*T
+	output.Result
+}](res P, err error) (output.Result, error) {
+	if res == nil {
+		return nil, err
 	}
+	return res, err
+}
 
-	return result
+// contextOf returns the lines of context op asks for before and after each
+// match, with before_context and after_context taking precedence over context
+// whenever they are set, even to 0.
+func contextOf(op Operation) (before, after int) {
+	before, after = op.Context, op.Context
+	if op.BeforeContext != nil {
+		before = *op.BeforeContext
+	}
+	if op.AfterContext != nil {
+		after = *op.AfterContext
+	}
+	return before, after
 }
 
 func ParseFromStdin() (*BatchRequest, error) {
@@ -163,6 +255,12 @@ func ParseFromOps(ops []string) (*BatchRequest, error) {
 			}
 			operation.OldStr = parts[2]
 			operation.NewStr = parts[3]
+		case "regex_replace":
+			if len(parts) < 4 {
+				return nil, fmt.Errorf("regex_replace requires pattern and replacement: %s", op)
+			}
+			operation.Pattern = parts[2]
+			operation.Replacement = parts[3]
 		case "create":
 			if len(parts) < 3 {
 				return nil, fmt.Errorf("create requires content: %s", op)
@@ -178,13 +276,44 @@ func ParseFromOps(ops []string) (*BatchRequest, error) {
 			}
 			operation.InsertLine = line
 			operation.NewStr = parts[3]
+		case "replace_lines":
+			if len(parts) < 5 {
+				return nil, fmt.Errorf("replace_lines requires start line, end line and content: %s", op)
+			}
+			start, end, err := fileops.ParseLineRange(parts[2] + "," + parts[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid line range in replace_lines: %s", op)
+			}
+			operation.StartLine = start
+			operation.EndLine = end
+			operation.NewStr = strings.Join(parts[4:], ",")
+		case "delete_lines":
+			if len(parts) < 3 || len(parts) > 4 {
+				return nil, fmt.Errorf("delete_lines requires a start line and optional end line: %s", op)
+			}
+			operation.Ranges = []string{strings.Join(parts[2:], ",")}
 		case "undo_edit":
+		case "redo_edit":
+		case "history":
+			if len(parts) > 2 {
+				index, err := strconv.Atoi(parts[2])
+				if err != nil {
+					return nil, fmt.Errorf("invalid edit index in history: %s", op)
+				}
+				operation.Goto = &index
+			}
 		case "ls":
 		case "search":
 			if len(parts) < 3 {
 				return nil, fmt.Errorf("search requires tree-sitter query: %s", op)
 			}
 			operation.TreeQuery = parts[2]
+		case "grep":
+			if len(parts) < 3 {
+				return nil, fmt.Errorf("grep requires pattern: %s", op)
+			}
+			operation.Pattern = strings.Join(parts[2:], ",")
+		case "outline":
 		default:
 			return nil, fmt.Errorf("unknown operation type: %s", parts[0])
 		}
@@ -2,6 +2,7 @@ package batch
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -98,6 +99,7 @@ func TestParseFromFile(t *testing.T) {
 }
 
 func TestParseFromOps(t *testing.T) {
+	two := 2
 	tests := []struct {
 		name    string
 		ops     []string
@@ -131,6 +133,15 @@ func TestParseFromOps(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "regex_replace operation",
+			ops:  []string{`regex_replace,test.txt,fooV1\((.*)\),fooV2($1)`},
+			want: &BatchRequest{
+				Operations: []Operation{
+					{Type: "regex_replace", Path: "test.txt", Pattern: `fooV1\((.*)\)`, Replacement: "fooV2($1)"},
+				},
+			},
+		},
 		{
 			name: "create operation",
 			ops:  []string{"create,new.txt,content"},
@@ -149,6 +160,33 @@ func TestParseFromOps(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "replace_lines operation",
+			ops:  []string{"replace_lines,test.txt,2,-1,new line"},
+			want: &BatchRequest{
+				Operations: []Operation{
+					{Type: "replace_lines", Path: "test.txt", StartLine: 2, EndLine: -1, NewStr: "new line"},
+				},
+			},
+		},
+		{
+			name: "delete_lines operation",
+			ops:  []string{"delete_lines,test.txt,3,5"},
+			want: &BatchRequest{
+				Operations: []Operation{
+					{Type: "delete_lines", Path: "test.txt", Ranges: []string{"3,5"}},
+				},
+			},
+		},
+		{
+			name: "history goto operation",
+			ops:  []string{"history,test.txt,2"},
+			want: &BatchRequest{
+				Operations: []Operation{
+					{Type: "history", Path: "test.txt", Goto: &two},
+				},
+			},
+		},
 		{
 			name: "search operation",
 			ops:  []string{"search,test.txt,query"},
@@ -158,6 +196,15 @@ func TestParseFromOps(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "grep operation",
+			ops:  []string{"grep,config,host,port"},
+			want: &BatchRequest{
+				Operations: []Operation{
+					{Type: "grep", Path: "config", Pattern: "host,port"},
+				},
+			},
+		},
 		{
 			name: "multiple operations",
 			ops:  []string{"view,test.txt", "create,new.txt,content"},
@@ -178,6 +225,11 @@ func TestParseFromOps(t *testing.T) {
 			ops:     []string{"str_replace,test.txt,old"},
 			wantErr: true,
 		},
+		{
+			name:    "regex_replace missing args",
+			ops:     []string{"regex_replace,test.txt,pattern"},
+			wantErr: true,
+		},
 		{
 			name:    "create missing content",
 			ops:     []string{"create,test.txt"},
@@ -193,11 +245,21 @@ func TestParseFromOps(t *testing.T) {
 			ops:     []string{"insert,test.txt,abc,content"},
 			wantErr: true,
 		},
+		{
+			name:    "replace_lines missing content",
+			ops:     []string{"replace_lines,test.txt,1,2"},
+			wantErr: true,
+		},
 		{
 			name:    "search missing query",
 			ops:     []string{"search,test.txt"},
 			wantErr: true,
 		},
+		{
+			name:    "grep missing pattern",
+			ops:     []string{"grep,test.txt"},
+			wantErr: true,
+		},
 		{
 			name:    "unknown operation type",
 			ops:     []string{"unknown,test.txt"},
@@ -260,6 +322,24 @@ func TestProcessor_ProcessBatch(t *testing.T) {
 				assert.Contains(t, *result.Error, "no such file or directory")
 			},
 		},
+		{
+			name: "str_replace with unique and expected_count",
+			req: &BatchRequest{
+				Operations: []Operation{
+					{Type: "str_replace", Path: testFile, OldStr: "line1", NewStr: "first", Unique: true, ExpectedCount: 2},
+				},
+			},
+			want: func(t *testing.T, resp *BatchResponse) {
+				require.Len(t, resp.Results, 1)
+				result := resp.Results[0]
+				assert.False(t, result.Success)
+				require.NotNil(t, result.Error)
+				assert.Contains(t, *result.Error, "cannot use both unique and an expected count")
+				content, err := os.ReadFile(testFile)
+				require.NoError(t, err)
+				assert.Equal(t, "line1\nline2\nline3\n", string(content))
+			},
+		},
 		{
 			name: "unknown operation type",
 			req: &BatchRequest{
@@ -304,6 +384,31 @@ func TestProcessor_ProcessBatch(t *testing.T) {
 	}
 }
 
+func TestContextOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		wantBefore int
+		wantAfter  int
+	}{
+		{name: "none", json: `{}`},
+		{name: "context", json: `{"context": 2}`, wantBefore: 2, wantAfter: 2},
+		{name: "before overrides", json: `{"context": 2, "before_context": 1}`, wantBefore: 1, wantAfter: 2},
+		{name: "after overrides", json: `{"context": 2, "after_context": 3}`, wantBefore: 2, wantAfter: 3},
+		{name: "explicit zero overrides", json: `{"context": 2, "before_context": 0}`, wantBefore: 0, wantAfter: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var op Operation
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &op))
+			before, after := contextOf(op)
+			assert.Equal(t, tt.wantBefore, before)
+			assert.Equal(t, tt.wantAfter, after)
+		})
+	}
+}
+
 func TestParseFromStdin(t *testing.T) {
 	tests := []struct {
 		name    string
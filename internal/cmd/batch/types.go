@@ -1,5 +1,10 @@
 package batch
 
+import (
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
 type BatchRequest struct {
 	Operations []Operation `json:"operations"`
 }
@@ -9,18 +14,66 @@ type Operation struct {
 
 	Path      string `json:"path,omitempty"`
 	ViewRange string `json:"view_range,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+
+	LineNumbers   *bool `json:"line_numbers,omitempty"`
+	ShowFormat    bool  `json:"show_format,omitempty"`
+	MaxLineLength int   `json:"max_line_length,omitempty"`
+	MaxLines      int   `json:"max_lines,omitempty"`
+	MaxBytes      int   `json:"max_bytes,omitempty"`
+
+	OldStr        string `json:"old_str,omitempty"`
+	NewStr        string `json:"new_str,omitempty"`
+	ShowChanges   bool   `json:"show_changes,omitempty"`
+	ShowResult    bool   `json:"show_result,omitempty"`
+	Unique        bool   `json:"unique,omitempty"`
+	ExpectedCount int    `json:"expected_count,omitempty"`
+	Fuzzy         bool   `json:"fuzzy,omitempty"`
 
-	OldStr      string `json:"old_str,omitempty"`
-	NewStr      string `json:"new_str,omitempty"`
-	ShowChanges bool   `json:"show_changes,omitempty"`
-	ShowResult  bool   `json:"show_result,omitempty"`
+	ExpectedSHA256 string `json:"expected_sha256,omitempty"`
+	ExpectedMtime  string `json:"expected_mtime,omitempty"`
 
 	Content string `json:"content,omitempty"`
 
-	InsertLine int    `json:"insert_line,omitempty"`
-	Count      int    `json:"count,omitempty"`
-	TreeQuery  string `json:"tree_sitter_query,omitempty"`
-	Pattern    string `json:"pattern,omitempty"`
+	Replacement     string `json:"replacement,omitempty"`
+	Multiline       bool   `json:"multiline,omitempty"`
+	IgnoreCase      bool   `json:"ignore_case,omitempty"`
+	MaxReplacements int    `json:"max_replacements,omitempty"`
+
+	Patch         string                          `json:"patch,omitempty"`
+	Fuzz          *int                            `json:"fuzz,omitempty"`
+	MaxOffset     int                             `json:"max_offset,omitempty"`
+	Preconditions map[string]fileops.Precondition `json:"preconditions,omitempty"`
+
+	InsertLine       int      `json:"insert_line,omitempty"`
+	After            string   `json:"after,omitempty"`
+	Before           string   `json:"before,omitempty"`
+	AnchorType       string   `json:"anchor_type,omitempty"`
+	AutoIndent       bool     `json:"auto_indent,omitempty"`
+	StartLine        int      `json:"start_line,omitempty"`
+	EndLine          int      `json:"end_line,omitempty"`
+	Ranges           []string `json:"ranges,omitempty"`
+	Count            int      `json:"count,omitempty"`
+	Goto             *int     `json:"goto,omitempty"`
+	Force            bool     `json:"force,omitempty"`
+	TreeQuery        string   `json:"tree_sitter_query,omitempty"`
+	QueryFile        string   `json:"query_file,omitempty"`
+	Capture          []string `json:"capture,omitempty"`
+	ShowText         bool     `json:"show_text,omitempty"`
+	ShowSpan         bool     `json:"show_span,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	JSON             bool     `json:"json,omitempty"`
+	NoIgnore         bool     `json:"no_ignore,omitempty"`
+	FixedStrings     bool     `json:"fixed_strings,omitempty"`
+	WordRegexp       bool     `json:"word_regexp,omitempty"`
+	BeforeContext    *int     `json:"before_context,omitempty"`
+	AfterContext     *int     `json:"after_context,omitempty"`
+	Context          int      `json:"context,omitempty"`
+	Include          []string `json:"include,omitempty"`
+	Exclude          []string `json:"exclude,omitempty"`
+	FilesWithMatches bool     `json:"files_with_matches,omitempty"`
+	CountMatches     bool     `json:"count_matches,omitempty"`
+	Hidden           bool     `json:"hidden,omitempty"`
 }
 
 type BatchResponse struct {
@@ -28,8 +81,9 @@ type BatchResponse struct {
 }
 
 type OperationResult struct {
-	Operation Operation `json:"operation"`
-	Success   bool      `json:"success"`
-	Output    string    `json:"output"`
-	Error     *string   `json:"error"`
+	Operation Operation     `json:"operation"`
+	Success   bool          `json:"success"`
+	Output    string        `json:"output"`
+	Result    output.Result `json:"result,omitempty"`
+	Error     *string       `json:"error"`
 }
@@ -0,0 +1,18 @@
+package checkpoint
+
+import "os"
+
+func Create(name, root string) error {
+	_, err := NewCheckpointer(os.Stdout).Create(name, root)
+	return err
+}
+
+func List() error {
+	_, err := NewCheckpointer(os.Stdout).List()
+	return err
+}
+
+func Restore(name string, showChanges bool) error {
+	_, err := NewCheckpointer(os.Stdout).Restore(name, showChanges)
+	return err
+}
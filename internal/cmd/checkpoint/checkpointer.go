@@ -0,0 +1,299 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+type Checkpointer struct {
+	undoEditor *undo_edit.UndoEditor
+	out        io.Writer
+}
+
+func NewCheckpointer(w io.Writer) *Checkpointer {
+	return &Checkpointer{
+		undoEditor: undo_edit.NewUndoEditor(w),
+		out:        w,
+	}
+}
+
+// Checkpoint records the state of every tracked file under Root when it was
+// created. Files maps each absolute path to its snapshot hash, which is empty
+// if the file did not exist. Files first edited after the checkpoint are
+// restored to their content before that edit.
+type Checkpoint struct {
+	Name      string            `json:"name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Root      string            `json:"root"`
+	Files     map[string]string `json:"files"`
+}
+
+// Info describes a checkpoint without the snapshots of its files.
+type Info struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Root      string    `json:"root"`
+	Files     int       `json:"files"`
+}
+
+func (c *Checkpoint) info() Info {
+	return Info{Name: c.Name, CreatedAt: c.CreatedAt, Root: c.Root, Files: len(c.Files)}
+}
+
+// CreateResult is the result of Create. Warnings name the edit histories
+// that could not be read or migrated, whose files are not in the checkpoint.
+type CreateResult struct {
+	Checkpoint Info     `json:"checkpoint"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+func (r *CreateResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Created checkpoint %s for %s\n", r.Checkpoint.Name, r.Checkpoint.Root)
+	writeWarnings(&b, r.Warnings)
+	return b.String()
+}
+
+// ListResult is the result of List, oldest checkpoint first.
+type ListResult struct {
+	Checkpoints []Info `json:"checkpoints"`
+}
+
+func (r *ListResult) String() string {
+	if len(r.Checkpoints) == 0 {
+		return "No checkpoints\n"
+	}
+	var b strings.Builder
+	for _, checkpoint := range r.Checkpoints {
+		fmt.Fprintf(&b, "%s  %s  %s\n", checkpoint.Name, checkpoint.CreatedAt.Format(time.RFC3339), checkpoint.Root)
+	}
+	return b.String()
+}
+
+// RestoreResult is the result of Restore, with an edit for every file that
+// was reverted. Warnings name the edit histories that could not be read or
+// migrated, whose files were not restored.
+type RestoreResult struct {
+	Name     string         `json:"name"`
+	Edits    []*output.Edit `json:"edits"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+func (r *RestoreResult) String() string {
+	var b strings.Builder
+	if len(r.Edits) == 0 {
+		fmt.Fprintf(&b, "No files changed since checkpoint %s\n", r.Name)
+		writeWarnings(&b, r.Warnings)
+		return b.String()
+	}
+	for _, edit := range r.Edits {
+		b.WriteString(edit.String())
+	}
+	fmt.Fprintf(&b, "Restored %d file(s) to checkpoint %s:\n", len(r.Edits), r.Name)
+	for _, edit := range r.Edits {
+		fmt.Fprintf(&b, "  %s\n", edit.Path)
+	}
+	writeWarnings(&b, r.Warnings)
+	return b.String()
+}
+
+func writeWarnings(b *strings.Builder, warnings []string) {
+	for _, warning := range warnings {
+		b.WriteString("Warning: " + warning + "\n")
+	}
+}
+
+func (c *Checkpointer) Create(name, root string) (*CreateResult, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid checkpoint name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+
+	checkpointPath, err := c.getCheckpointPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(checkpointPath); err == nil {
+		return nil, fmt.Errorf("checkpoint already exists: %s", name)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path: %w", err)
+	}
+
+	histories, warnings, err := c.undoEditor.ListHistories(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("list edit histories: %w", err)
+	}
+
+	checkpoint := &Checkpoint{
+		Name:      name,
+		CreatedAt: time.Now(),
+		Root:      absRoot,
+		Files:     make(map[string]string, len(histories)),
+	}
+	for _, history := range histories {
+		checkpoint.Files[history.FilePath] = history.State()
+	}
+
+	err = c.writeCheckpoint(checkpointPath, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	res := &CreateResult{Checkpoint: checkpoint.info(), Warnings: warnings}
+	return res, output.Print(c.out, res)
+}
+
+func (c *Checkpointer) List() (*ListResult, error) {
+	checkpointDir, err := c.getCheckpointDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(checkpointDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read checkpoint directory %s: %w", checkpointDir, err)
+	}
+
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		checkpoint, err := c.readCheckpoint(filepath.Join(checkpointDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].CreatedAt.Before(checkpoints[j].CreatedAt)
+	})
+	res := &ListResult{Checkpoints: make([]Info, len(checkpoints))}
+	for i, checkpoint := range checkpoints {
+		res.Checkpoints[i] = checkpoint.info()
+	}
+	return res, output.Print(c.out, res)
+}
+
+// Restore reverts every tracked file under the checkpoint's root that changed
+// since the checkpoint was created. Each revert is recorded as an edit, so
+// undo_edit can take it back file by file. Nothing is written unless all of
+// the files are unchanged since eddie last edited them, and if restoring one
+// fails, the files already restored are rolled back.
+func (c *Checkpointer) Restore(name string, showChanges bool) (*RestoreResult, error) {
+	checkpointPath, err := c.getCheckpointPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := c.readCheckpoint(checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("checkpoint not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	histories, warnings, err := c.undoEditor.ListHistories(checkpoint.Root)
+	if err != nil {
+		return nil, fmt.Errorf("list edit histories: %w", err)
+	}
+
+	targets := make(map[string]string)
+	var paths []string
+	for _, history := range histories {
+		target, ok := checkpoint.Files[history.FilePath]
+		if !ok {
+			target, ok = history.StateBefore(checkpoint.CreatedAt)
+		}
+		if !ok || target == history.State() {
+			continue
+		}
+		targets[history.FilePath] = target
+		paths = append(paths, history.FilePath)
+	}
+
+	res := &RestoreResult{Name: name, Edits: []*output.Edit{}, Warnings: warnings}
+	if len(paths) == 0 {
+		return res, output.Print(c.out, res)
+	}
+
+	edits, err := c.undoEditor.RestoreStates(paths, targets, "checkpoint_restore", showChanges)
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore checkpoint %s: %w", name, err)
+	}
+	res.Edits = append(res.Edits, edits...)
+	return res, output.Print(c.out, res)
+}
+
+func (c *Checkpointer) getCheckpointDir() (string, error) {
+	cacheDir := os.Getenv("XDG_CACHE_HOME")
+	if cacheDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("get user home directory: %w", err)
+		}
+		cacheDir = filepath.Join(homeDir, ".cache")
+	}
+
+	return filepath.Join(cacheDir, "eddie", "checkpoints"), nil
+}
+
+func (c *Checkpointer) getCheckpointPath(name string) (string, error) {
+	checkpointDir, err := c.getCheckpointDir()
+	if err != nil {
+		return "", fmt.Errorf("get checkpoint directory: %w", err)
+	}
+	return filepath.Join(checkpointDir, name+".json"), nil
+}
+
+func (c *Checkpointer) readCheckpoint(checkpointPath string) (*Checkpoint, error) {
+	data, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint %s: %w", checkpointPath, err)
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal checkpoint %s: %w", checkpointPath, err)
+	}
+
+	return &checkpoint, nil
+}
+
+func (c *Checkpointer) writeCheckpoint(checkpointPath string, checkpoint *Checkpoint) error {
+	err := os.MkdirAll(filepath.Dir(checkpointPath), 0o755)
+	if err != nil {
+		return fmt.Errorf("create checkpoint directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal JSON: %w", err)
+	}
+
+	err = fileops.WriteFileAtomic(checkpointPath, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
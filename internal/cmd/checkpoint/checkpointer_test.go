@@ -0,0 +1,259 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func setCacheHome(t *testing.T, dir string) {
+	oldCacheHome, ok := os.LookupEnv("XDG_CACHE_HOME")
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	})
+	os.Setenv("XDG_CACHE_HOME", dir)
+}
+
+func edit(t *testing.T, path, content string) {
+	u := undo_edit.NewUndoEditor(io.Discard)
+	before, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		require.NoError(t, u.RecordCreate(path, content, nil))
+		return
+	}
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, u.RecordEdit(path, "str_replace", string(before), content))
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestCheckpointer_Restore(t *testing.T) {
+	tmpDir := t.TempDir()
+	setCacheHome(t, filepath.Join(tmpDir, "cache"))
+	root := filepath.Join(tmpDir, "project")
+	require.NoError(t, os.MkdirAll(root, 0o755))
+
+	a := filepath.Join(root, "a.go")
+	b := filepath.Join(root, "b.go")
+	c := filepath.Join(root, "c.go")
+	untracked := filepath.Join(root, "untracked.go")
+
+	edit(t, a, "a1\n")
+	edit(t, a, "a2\n")
+	edit(t, b, "b1\n")
+	require.NoError(t, os.WriteFile(c, []byte("c0\n"), 0o644))
+	require.NoError(t, os.WriteFile(untracked, []byte("u0\n"), 0o644))
+
+	cp := NewCheckpointer(io.Discard)
+	_, err := cp.Create("before-refactor", root)
+	require.NoError(t, err)
+
+	edit(t, a, "a3\n")
+	edit(t, a, "a4\n")
+	edit(t, c, "c1\n")
+	createdDir := filepath.Join(root, "pkg")
+	created := filepath.Join(createdDir, "new.go")
+	require.NoError(t, os.Mkdir(createdDir, 0o755))
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0o644))
+	require.NoError(t, undo_edit.NewUndoEditor(io.Discard).RecordCreate(created, "new\n", []string{createdDir}))
+	_, err = undo_edit.NewUndoEditor(io.Discard).UndoEdit(b, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	res, err := cp.Restore("before-refactor", false)
+	require.NoError(t, err)
+	require.Len(t, res.Edits, 4)
+	assert.True(t, res.Edits[3].Deleted)
+
+	assert.Equal(t, "a2\n", readFile(t, a))
+	assert.Equal(t, "b1\n", readFile(t, b))
+	assert.Equal(t, "c0\n", readFile(t, c))
+	assert.Equal(t, "u0\n", readFile(t, untracked))
+	assert.NoFileExists(t, created)
+	assert.NoDirExists(t, createdDir)
+
+	_, err = undo_edit.NewUndoEditor(io.Discard).UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.Equal(t, "a4\n", readFile(t, a), "restore should be undoable per file")
+}
+
+func TestCheckpointer_Restore_StaleFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	setCacheHome(t, filepath.Join(tmpDir, "cache"))
+	root := filepath.Join(tmpDir, "project")
+	require.NoError(t, os.MkdirAll(root, 0o755))
+
+	a := filepath.Join(root, "a.go")
+	b := filepath.Join(root, "b.go")
+	edit(t, a, "a1\n")
+	edit(t, b, "b1\n")
+
+	cp := NewCheckpointer(io.Discard)
+	_, err := cp.Create("cp", root)
+	require.NoError(t, err)
+
+	edit(t, a, "a2\n")
+	edit(t, b, "b2\n")
+	require.NoError(t, os.WriteFile(b, []byte("changed by hand\n"), 0o644))
+	info, err := os.Stat(b)
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(b, info.ModTime(), info.ModTime().Add(1)))
+
+	_, err = cp.Restore("cp", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modified since last tracked edit")
+
+	assert.Equal(t, "a2\n", readFile(t, a), "no file should be restored when any is stale")
+}
+
+func TestCheckpointer_Restore_RollsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheHome := filepath.Join(tmpDir, "cache")
+	setCacheHome(t, cacheHome)
+	root := filepath.Join(tmpDir, "project")
+	require.NoError(t, os.MkdirAll(root, 0o755))
+
+	a := filepath.Join(root, "a.go")
+	b := filepath.Join(root, "b.go")
+	edit(t, a, "a1\n")
+	edit(t, b, "b1\n")
+
+	cp := NewCheckpointer(io.Discard)
+	_, err := cp.Create("cp", root)
+	require.NoError(t, err)
+
+	edit(t, a, "a2\n")
+	edit(t, b, "b2\n")
+
+	// Restoring b fails after a has been restored.
+	snapshot := filepath.Join(cacheHome, "eddie", "snapshots", fileops.HashContent("b1\n"))
+	require.NoError(t, os.Remove(snapshot))
+
+	_, err = cp.Restore("cp", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "restore "+b)
+	assert.Equal(t, "a2\n", readFile(t, a), "restored files should be rolled back")
+	assert.Equal(t, "b2\n", readFile(t, b))
+
+	_, err = undo_edit.NewUndoEditor(io.Discard).UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.Equal(t, "a1\n", readFile(t, a), "edit history should be rolled back")
+}
+
+// downgradeHistory rewrites the edit history of path in the version 1
+// format, which had no timeline position.
+func downgradeHistory(t *testing.T, editDir, path string) {
+	entries, err := os.ReadDir(editDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		editPath := filepath.Join(editDir, entry.Name())
+		data, err := os.ReadFile(editPath)
+		require.NoError(t, err)
+		var history map[string]any
+		require.NoError(t, json.Unmarshal(data, &history))
+		if history["file_path"] != path {
+			continue
+		}
+
+		history["version"] = 1
+		delete(history, "current")
+		data, err = json.Marshal(history)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(editPath, data, 0o644))
+		return
+	}
+	t.Fatalf("no edit history for %s", path)
+}
+
+func TestCheckpointer_LegacyHistories(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheHome := filepath.Join(tmpDir, "cache")
+	setCacheHome(t, cacheHome)
+	root := filepath.Join(tmpDir, "project")
+	require.NoError(t, os.MkdirAll(root, 0o755))
+
+	a := filepath.Join(root, "a.go")
+	b := filepath.Join(root, "b.go")
+	edit(t, a, "a1\n")
+	edit(t, a, "a2\n")
+	edit(t, b, "b1\n")
+
+	editDir := filepath.Join(cacheHome, "eddie", "edits")
+	downgradeHistory(t, editDir, a)
+	broken := filepath.Join(editDir, "broken.json")
+	require.NoError(t, os.WriteFile(broken, []byte("{"), 0o644))
+
+	cp := NewCheckpointer(io.Discard)
+	created, err := cp.Create("cp", root)
+	require.NoError(t, err)
+	assert.Equal(t, 2, created.Checkpoint.Files, "the version 1 history should be included")
+	require.Len(t, created.Warnings, 1)
+	assert.Contains(t, created.Warnings[0], broken)
+
+	edit(t, a, "a3\n")
+	edit(t, b, "b2\n")
+
+	restored, err := cp.Restore("cp", false)
+	require.NoError(t, err)
+	assert.Len(t, restored.Edits, 2)
+	require.Len(t, restored.Warnings, 1)
+	assert.Contains(t, restored.Warnings[0], broken)
+	assert.Equal(t, "a2\n", readFile(t, a))
+	assert.Equal(t, "b1\n", readFile(t, b))
+}
+
+func TestCheckpointer_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	setCacheHome(t, tmpDir)
+
+	cp := NewCheckpointer(io.Discard)
+	_, err := cp.Create("existing", tmpDir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		run     func() (any, error)
+		wantErr string
+	}{
+		{
+			name:    "invalid name",
+			run:     func() (any, error) { return cp.Create("../escape", tmpDir) },
+			wantErr: "invalid checkpoint name",
+		},
+		{
+			name:    "duplicate name",
+			run:     func() (any, error) { return cp.Create("existing", tmpDir) },
+			wantErr: "checkpoint already exists",
+		},
+		{
+			name:    "restore missing checkpoint",
+			run:     func() (any, error) { return cp.Restore("missing", false) },
+			wantErr: "checkpoint not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.run()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
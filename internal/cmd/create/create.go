@@ -3,5 +3,6 @@ package create
 import "os"
 
 func Create(path, fileText string, showChanges, showResult bool) error {
-	return NewCreator(os.Stdout).Create(path, fileText, showChanges, showResult)
+	_, err := NewCreator(os.Stdout).Create(path, fileText, showChanges, showResult)
+	return err
 }
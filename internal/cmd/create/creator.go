@@ -3,37 +3,46 @@ package create
 import (
 	"fmt"
 	"io"
+	"os"
 
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
 	"github.com/RRethy/eddie/internal/display"
 	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type Creator struct {
 	fileOps *fileops.FileOps
-	display *display.Display
+	out     io.Writer
 }
 
 func NewCreator(w io.Writer) *Creator {
 	return &Creator{
 		fileOps: &fileops.FileOps{},
-		display: display.New(w),
+		out:     w,
 	}
 }
 
-func (c *Creator) Create(path, fileText string, showChanges, showResult bool) error {
-	err := c.fileOps.CreateFile(path, fileText)
+func (c *Creator) Create(path, fileText string, showChanges, showResult bool) (*output.Edit, error) {
+	createdDirs, err := c.fileOps.CreateFile(path, fileText)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if showChanges {
-		c.display.ShowNewFileContent(path, fileText)
+	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
+	err = undoEditor.RecordCreate(path, fileText, createdDirs)
+	if err != nil {
+		return nil, fmt.Errorf("record edit: %w", err)
 	}
 
+	res := &output.Edit{Command: "create", Path: path, Count: len(fileText), Created: true}
+	if showChanges {
+		diff := display.NewFileDiff(path, fileText)
+		res.Diff = &diff
+	}
 	if showResult {
-		c.display.ShowResult(path, fileText)
+		res.Content = &fileText
 	}
-
-	fmt.Printf("Created file: %s (%d bytes)\n", path, len(fileText))
-	return nil
+	res.Summary = fmt.Sprintf("Created file: %s (%d bytes)", path, len(fileText))
+	return res, output.Print(c.out, res)
 }
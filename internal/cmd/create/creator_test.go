@@ -7,6 +7,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func TestCreator_Create(t *testing.T) {
@@ -104,7 +107,7 @@ func TestCreator_Create(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Creator{}
 			path := tt.setup()
-			err := c.Create(path, tt.fileText, false, false)
+			_, err := c.Create(path, tt.fileText, false, false)
 
 			if tt.wantErr != "" {
 				require.Error(t, err)
@@ -137,3 +140,29 @@ func TestCreator_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestCreator_Create_Undo(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+
+	dir := filepath.Join(tmpDir, "src", "pkg")
+	path := filepath.Join(dir, "new.go")
+
+	c := &Creator{}
+	_, err := c.Create(path, "package pkg\n", false, false)
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+
+	require.NoError(t, undo_edit.UndoEdit(path, false, false, 1, false, fileops.Precondition{}))
+	assert.NoFileExists(t, path)
+	assert.NoDirExists(t, filepath.Join(tmpDir, "src"))
+}
@@ -0,0 +1,12 @@
+package delete_lines
+
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func DeleteLines(path string, ranges []string, showChanges, showResult bool, pre fileops.Precondition) error {
+	_, err := NewLineDeleter(os.Stdout).DeleteLines(path, ranges, showChanges, showResult, pre)
+	return err
+}
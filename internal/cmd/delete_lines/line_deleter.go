@@ -0,0 +1,145 @@
+package delete_lines
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+type LineDeleter struct {
+	fileOps *fileops.FileOps
+	out     io.Writer
+}
+
+func NewLineDeleter(w io.Writer) *LineDeleter {
+	return &LineDeleter{
+		fileOps: &fileops.FileOps{},
+		out:     w,
+	}
+}
+
+type lineRange struct {
+	start int
+	end   int
+}
+
+// DeleteLines removes every line covered by ranges. Each range is "N" or
+// "start,end" (1-based, inclusive, end of -1 meaning the last line), and
+// ranges may be given in any order and may overlap.
+func (d *LineDeleter) DeleteLines(path string, ranges []string, showChanges, showResult bool, pre fileops.Precondition) (*output.Edit, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("at least one line range is required")
+	}
+
+	raw, info, err := d.fileOps.ReadFileContentForOperation(path, "delete lines in")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pre.Check(path, raw, info); err != nil {
+		return nil, err
+	}
+
+	original, format, err := fileops.DecodeText(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	modified, hunks, err := d.deleteLines(original, ranges)
+	if err != nil {
+		return nil, fmt.Errorf("delete lines: %w", err)
+	}
+
+	encoded, err := format.EncodeText(modified)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	err = d.fileOps.WriteFileContent(path, encoded, info.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
+	err = undoEditor.RecordEdit(path, "delete_lines", raw, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("record edit: %w", err)
+	}
+
+	deleted := 0
+	for _, h := range hunks {
+		deleted += h.OldCount
+	}
+	res := &output.Edit{Command: "delete_lines", Path: path, Count: deleted}
+	if showChanges {
+		diff := display.Diff(path, original, modified)
+		res.Diff = &diff
+	}
+	if showResult {
+		res.Content = &modified
+	}
+	res.Summary = fmt.Sprintf("Deleted %d line(s) in %d range(s) from %s", deleted, len(hunks), path)
+	return res, output.Print(d.out, res)
+}
+
+func (d *LineDeleter) deleteLines(content string, ranges []string) (string, []fileops.LineHunk, error) {
+	lines, hasTrailingNewline := fileops.SplitLines(content)
+
+	merged, err := d.mergeRanges(ranges, len(lines))
+	if err != nil {
+		return "", nil, err
+	}
+
+	result := make([]string, 0, len(lines))
+	hunks := make([]fileops.LineHunk, 0, len(merged))
+	next := 1
+	for _, r := range merged {
+		result = append(result, lines[next-1:r.start-1]...)
+		hunks = append(hunks, fileops.LineHunk{Start: r.start, OldCount: r.end - r.start + 1})
+		next = r.end + 1
+	}
+	result = append(result, lines[next-1:]...)
+
+	return fileops.JoinLines(result, hasTrailingNewline), hunks, nil
+}
+
+// mergeRanges parses, validates, sorts and coalesces overlapping or adjacent
+// ranges so each line is deleted at most once.
+func (d *LineDeleter) mergeRanges(ranges []string, lineCount int) ([]lineRange, error) {
+	parsed := make([]lineRange, 0, len(ranges))
+	for _, s := range ranges {
+		start, end, err := fileops.ParseLineRange(s)
+		if err != nil {
+			return nil, err
+		}
+		if end == -1 {
+			end = lineCount
+		}
+		if start > lineCount || end > lineCount {
+			return nil, fmt.Errorf("line range %q exceeds file length (%d lines)", s, lineCount)
+		}
+		parsed = append(parsed, lineRange{start: start, end: end})
+	}
+
+	sort.Slice(parsed, func(i, j int) bool {
+		return parsed[i].start < parsed[j].start
+	})
+
+	merged := []lineRange{parsed[0]}
+	for _, r := range parsed[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.end+1 {
+			last.end = max(last.end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged, nil
+}
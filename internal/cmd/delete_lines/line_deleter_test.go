@@ -0,0 +1,106 @@
+package delete_lines
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func TestLineDeleter_deleteLines(t *testing.T) {
+	d := &LineDeleter{}
+
+	tests := []struct {
+		name      string
+		content   string
+		want      string
+		wantErr   string
+		ranges    []string
+		wantHunks int
+	}{
+		{
+			name:      "single line",
+			content:   "a\nb\nc\n",
+			ranges:    []string{"2"},
+			want:      "a\nc\n",
+			wantHunks: 1,
+		},
+		{
+			name:      "multiple ranges out of order",
+			content:   "1\n2\n3\n4\n5\n6\n",
+			ranges:    []string{"5,6", "1"},
+			want:      "2\n3\n4\n",
+			wantHunks: 2,
+		},
+		{
+			name:      "overlapping ranges merge",
+			content:   "1\n2\n3\n4\n5\n",
+			ranges:    []string{"2,3", "3,4"},
+			want:      "1\n5\n",
+			wantHunks: 1,
+		},
+		{
+			name:      "to end of file",
+			content:   "1\n2\n3\n",
+			ranges:    []string{"2,-1"},
+			want:      "1\n",
+			wantHunks: 1,
+		},
+		{
+			name:      "all lines",
+			content:   "1\n2\n",
+			ranges:    []string{"1,-1"},
+			want:      "",
+			wantHunks: 1,
+		},
+		{
+			name:    "past end",
+			content: "1\n2\n",
+			ranges:  []string{"3"},
+			wantErr: "exceeds file length",
+		},
+		{
+			name:    "invalid range",
+			content: "1\n2\n",
+			ranges:  []string{"x"},
+			wantErr: "invalid start",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hunks, err := d.deleteLines(tt.content, tt.ranges)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, hunks, tt.wantHunks)
+		})
+	}
+}
+
+func TestLineDeleter_DeleteLines(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("a\nb\nc\nd\n"), 0o644))
+
+	d := &LineDeleter{}
+	_, err := d.DeleteLines(testFile, []string{"1", "3,4"}, false, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(content))
+
+	_, err = d.DeleteLines(testFile, nil, false, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one line range")
+}
@@ -1,5 +1,12 @@
 package glob
 
-func Glob(pattern, path string) error {
-	return (&Globber{}).Glob(pattern, path)
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+func Glob(pattern, path string, opts ignore.Options) error {
+	_, err := NewGlobber(os.Stdout).Glob(pattern, path, opts)
+	return err
 }
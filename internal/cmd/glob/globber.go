@@ -2,21 +2,48 @@ package glob
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
-type Globber struct{}
+type Globber struct {
+	out io.Writer
+}
+
+func NewGlobber(out io.Writer) *Globber {
+	return &Globber{out: out}
+}
+
+// Entry is a path matched by Glob with its modification time.
+type Entry struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mtime"`
+}
 
-type fileInfo struct {
-	modTime time.Time
-	path    string
+// Result is the result of Glob, most recently modified first.
+type Result struct {
+	Pattern string  `json:"pattern"`
+	Entries []Entry `json:"entries"`
 }
 
-func (g *Globber) Glob(pattern, path string) error {
+func (r *Result) String() string {
+	var b strings.Builder
+	for _, entry := range r.Entries {
+		b.WriteString(entry.Path + "\n")
+	}
+	return b.String()
+}
+
+// Glob prints the paths under path that match pattern and that opts does not
+// ignore, most recently modified first.
+func (g *Globber) Glob(pattern, path string, opts ignore.Options) (*Result, error) {
 	if path == "" {
 		path = "."
 	}
@@ -24,65 +51,88 @@ func (g *Globber) Glob(pattern, path string) error {
 	var matches []string
 	var err error
 
-	if strings.Contains(pattern, "**") {
-		if path == "." {
-			matches, err = g.recursiveGlobFromPattern(pattern)
-		} else {
-			matches, err = g.recursiveGlob(pattern, path)
+	switch {
+	case strings.Count(pattern, "**") == 1:
+		// The walk starts at the directories written out before "**", so
+		// they are not skipped for being hidden, as in a plain glob.
+		fullPattern := filepath.Join(path, pattern)
+		if strings.HasSuffix(pattern, "/") {
+			fullPattern += "/"
 		}
-	} else {
+		matches, err = g.recursiveGlobFromPattern(fullPattern, opts)
+	case strings.Contains(pattern, "**"):
+		matches, err = g.recursiveGlob(pattern, path, opts)
+	default:
 		fullPattern := filepath.Join(path, pattern)
 		matches, err = filepath.Glob(fullPattern)
+		if err == nil {
+			matches = unignored(matches, path, pattern, opts)
+		}
 	}
 
 	if err != nil {
-		return fmt.Errorf("glob %s: %w", pattern, err)
-	}
-
-	if len(matches) == 0 {
-		return nil
+		return nil, fmt.Errorf("glob %s: %w", pattern, err)
 	}
 
-	files := make([]fileInfo, 0, len(matches))
+	res := &Result{Pattern: pattern, Entries: make([]Entry, 0, len(matches))}
 	for _, match := range matches {
 		info, err := os.Lstat(match)
 		if err != nil {
 			continue
 		}
-		files = append(files, fileInfo{
-			path:    match,
-			modTime: info.ModTime(),
+		res.Entries = append(res.Entries, Entry{
+			Path:    match,
+			ModTime: info.ModTime(),
 		})
 	}
 
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].modTime.After(files[j].modTime)
+	sort.Slice(res.Entries, func(i, j int) bool {
+		return res.Entries[i].ModTime.After(res.Entries[j].ModTime)
 	})
 
-	for _, f := range files {
-		fmt.Println(f.path)
+	return res, output.Print(g.out, res)
+}
+
+// unignored returns the matches of pattern under root that opts does not
+// ignore, checking the directories between root and each match as a walk
+// would. Hidden files and directories are only left out where a wildcard
+// matched them, not where pattern names them.
+func unignored(matches []string, root, pattern string, opts ignore.Options) []string {
+	parts := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+	named := make([]bool, len(parts))
+	for i, part := range parts {
+		named[i] = !strings.ContainsAny(part, `*?[\`)
 	}
 
-	return nil
+	m := ignore.New(root, opts)
+	kept := matches[:0]
+	for _, match := range matches {
+		info, err := os.Lstat(match)
+		if err == nil && m.Skipped(match, info.IsDir(), named) {
+			continue
+		}
+		kept = append(kept, match)
+	}
+	return kept
 }
 
-func (g *Globber) recursiveGlobFromPattern(pattern string) ([]string, error) {
+func (g *Globber) recursiveGlobFromPattern(pattern string, opts ignore.Options) ([]string, error) {
 	parts := strings.Split(pattern, "**")
 	if len(parts) != 2 {
-		return g.recursiveGlob(pattern, ".")
+		return g.recursiveGlob(pattern, ".", opts)
 	}
 
 	basePart := strings.TrimSuffix(parts[0], "/")
 	suffixPart := strings.TrimPrefix(parts[1], "/")
 
 	if basePart == "" {
-		return g.recursiveGlob(pattern, ".")
+		return g.recursiveGlob(pattern, ".", opts)
 	}
 
 	var matches []string
 	dirsOnly := strings.HasSuffix(pattern, "/")
 
-	err := filepath.WalkDir(basePart, func(path string, d os.DirEntry, err error) error {
+	err := ignore.Walk(basePart, opts, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -119,11 +169,11 @@ func (g *Globber) recursiveGlobFromPattern(pattern string) ([]string, error) {
 	return matches, err
 }
 
-func (g *Globber) recursiveGlob(pattern, basePath string) ([]string, error) {
+func (g *Globber) recursiveGlob(pattern, basePath string, opts ignore.Options) ([]string, error) {
 	var matches []string
 	dirsOnly := strings.HasSuffix(pattern, "/")
 
-	err := filepath.WalkDir(basePath, func(path string, d os.DirEntry, err error) error {
+	err := ignore.Walk(basePath, opts, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -2,10 +2,13 @@ package glob
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/ignore"
 )
 
 func TestGlobber_Glob(t *testing.T) {
@@ -52,7 +55,7 @@ func TestGlobber_Glob(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &Globber{}
-			err := g.Glob(tt.pattern, tt.path)
+			_, err := g.Glob(tt.pattern, tt.path, ignore.Options{})
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -73,6 +76,78 @@ func TestGlobber_GlobWithTempFiles(t *testing.T) {
 	}
 
 	g := &Globber{}
-	err := g.Glob("*.txt", tmpDir)
-	assert.NoError(t, err)
+	res, err := g.Glob("*.txt", tmpDir, ignore.Options{})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 2)
+	for _, entry := range res.Entries {
+		assert.Equal(t, ".txt", filepath.Ext(entry.Path))
+		assert.False(t, entry.ModTime.IsZero())
+	}
+}
+
+func TestGlobber_Glob_Ignore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+
+	for _, f := range []string{".gitignore", "src/app.js", "dist/app.js", "node_modules/dep/index.js", ".cache/app.js"} {
+		path := filepath.Join(tmpDir, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("dist/\n"), 0o644))
+	}
+
+	paths := func(res *Result) []string {
+		var paths []string
+		for _, entry := range res.Entries {
+			rel, err := filepath.Rel(tmpDir, entry.Path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return paths
+	}
+
+	g := &Globber{}
+
+	res, err := g.Glob("**/*.js", tmpDir, ignore.Options{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"src/app.js"}, paths(res))
+
+	res, err = g.Glob("*/app.js", tmpDir, ignore.Options{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"src/app.js"}, paths(res))
+
+	res, err = g.Glob("*/app.js", tmpDir, ignore.Options{NoIgnore: true, Hidden: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"src/app.js", "dist/app.js", ".cache/app.js"}, paths(res))
+
+	// Hidden paths written out in the pattern are kept, whichever way the
+	// pattern is matched, but hidden paths matched by a wildcard are not.
+	for _, f := range []string{".env", ".github/workflows/ci.yml", ".github/workflows/.draft.yml"} {
+		path := filepath.Join(tmpDir, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("x\n"), 0o644))
+	}
+
+	oldDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(oldDir)
+
+	for _, dir := range []string{tmpDir, "."} {
+		res, err = g.Glob(".env", dir, ignore.Options{})
+		require.NoError(t, err)
+		assert.Len(t, res.Entries, 1, dir)
+
+		for _, pattern := range []string{".github/workflows/*.yml", ".github/**/*.yml"} {
+			res, err = g.Glob(pattern, dir, ignore.Options{})
+			require.NoError(t, err)
+			require.Len(t, res.Entries, 1, "%s in %s", pattern, dir)
+			assert.Equal(t, "ci.yml", filepath.Base(res.Entries[0].Path))
+		}
+
+		res, err = g.Glob("*", dir, ignore.Options{})
+		require.NoError(t, err)
+		for _, entry := range res.Entries {
+			assert.NotEqual(t, ".env", filepath.Base(entry.Path))
+		}
+	}
 }
@@ -0,0 +1,227 @@
+package insert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/cmd/outline"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+// Anchor kinds accepted by Insert.
+const (
+	AnchorString = "string"
+	AnchorRegex  = "regex"
+	AnchorSymbol = "symbol"
+)
+
+// Anchor places an insertion next to the only match of Pattern instead of at
+// a line number. Kind says whether Pattern is a plain string, a regular
+// expression (Go RE2 syntax, with ^ and $ matching at line boundaries) or the
+// name of a declaration as accepted by view --symbol. The text goes after the
+// lines of the match, or before them if Before is set.
+type Anchor struct {
+	Pattern string
+	Kind    string
+	Before  bool
+}
+
+// NewAnchor returns the anchor inserting after the match of after or before
+// the match of before, of which at most one can be set. Neither being set
+// gives the zero Anchor, which inserts at a line number.
+func NewAnchor(after, before, kind string) (Anchor, error) {
+	if after != "" && before != "" {
+		return Anchor{}, fmt.Errorf("cannot insert both after and before an anchor")
+	}
+	if before != "" {
+		return Anchor{Pattern: before, Kind: kind, Before: true}, nil
+	}
+	return Anchor{Pattern: after, Kind: kind}, nil
+}
+
+// lineSpan is a 1-based inclusive range of lines.
+type lineSpan struct {
+	start int
+	end   int
+}
+
+// locate returns the lines of content matched by anchor. A symbol's lines
+// include its doc comment.
+func (i *Inserter) locate(path, content string, anchor Anchor) (lineSpan, error) {
+	if anchor.Pattern == "" {
+		return lineSpan{}, fmt.Errorf("anchor must not be empty")
+	}
+
+	var locs [][]int
+	switch anchor.Kind {
+	case AnchorString, "":
+		for offset := 0; ; {
+			idx := strings.Index(content[offset:], anchor.Pattern)
+			if idx < 0 {
+				break
+			}
+			start := offset + idx
+			locs = append(locs, []int{start, start + len(anchor.Pattern)})
+			offset = start + len(anchor.Pattern)
+		}
+	case AnchorRegex:
+		re, err := regexp.Compile("(?m)" + anchor.Pattern)
+		if err != nil {
+			return lineSpan{}, fmt.Errorf("compile anchor: %w", err)
+		}
+		locs = re.FindAllStringIndex(content, -1)
+	case AnchorSymbol:
+		symbol, err := outline.Resolve(path, []byte(content), anchor.Pattern)
+		if err != nil {
+			return lineSpan{}, fmt.Errorf("resolve anchor: %w", err)
+		}
+		start := symbol.StartLine
+		if symbol.DocLine > 0 {
+			start = symbol.DocLine
+		}
+		return lineSpan{start: start, end: symbol.EndLine}, nil
+	default:
+		return lineSpan{}, fmt.Errorf("invalid anchor kind %q: use %s, %s or %s", anchor.Kind, AnchorString, AnchorRegex, AnchorSymbol)
+	}
+
+	if len(locs) == 0 {
+		return lineSpan{}, fmt.Errorf("anchor %q not found in %s", anchor.Pattern, path)
+	}
+	if len(locs) > 1 {
+		lines := make([]string, len(locs))
+		for n, loc := range locs {
+			lines[n] = strconv.Itoa(strings.Count(content[:loc[0]], "\n") + 1)
+		}
+		return lineSpan{}, fmt.Errorf("anchor %q matches %d times in %s, at lines %s; make it unique",
+			anchor.Pattern, len(locs), path, strings.Join(lines, ", "))
+	}
+
+	start, end := locs[0][0], locs[0][1]
+	if end > start && content[end-1] == '\n' {
+		// A match ending with a newline ends on the line before.
+		end--
+	}
+	return lineSpan{
+		start: strings.Count(content[:start], "\n") + 1,
+		end:   strings.Count(content[:end], "\n") + 1,
+	}, nil
+}
+
+// indentFor returns the indentation of a block inserted at lineNum. Next to
+// an anchor the block lines up with the anchor's first line, or one level
+// deeper if it is inserted after an anchor that opens a block. At a line
+// number it follows the line before it if that opens a block, and otherwise
+// the line after it unless that closes a block.
+func indentFor(lines []string, lineNum int, anchor *lineSpan, before bool) string {
+	unit := indentUnit(lines)
+	if anchor != nil {
+		if !before && opensBlock(lines[anchor.end-1]) {
+			return fileops.Indentation(lines[anchor.end-1]) + unit
+		}
+		return fileops.Indentation(lines[anchor.start-1])
+	}
+
+	prev, next := "", ""
+	for n := lineNum - 2; n >= 0; n-- {
+		if strings.TrimSpace(lines[n]) != "" {
+			prev = lines[n]
+			break
+		}
+	}
+	for n := lineNum - 1; n < len(lines); n++ {
+		if strings.TrimSpace(lines[n]) != "" {
+			next = lines[n]
+			break
+		}
+	}
+
+	switch {
+	case prev != "" && opensBlock(prev):
+		return fileops.Indentation(prev) + unit
+	case next != "" && !closesBlock(next):
+		return fileops.Indentation(next)
+	default:
+		return fileops.Indentation(prev)
+	}
+}
+
+func opensBlock(line string) bool {
+	line = strings.TrimRight(line, " \t\r")
+	return strings.HasSuffix(line, "{") || strings.HasSuffix(line, "(") ||
+		strings.HasSuffix(line, "[") || strings.HasSuffix(line, ":")
+}
+
+func closesBlock(line string) bool {
+	line = strings.TrimLeft(line, " \t")
+	return strings.HasPrefix(line, "}") || strings.HasPrefix(line, ")") || strings.HasPrefix(line, "]")
+}
+
+// indentUnit returns the most common step of indentation between consecutive
+// lines, or a tab or four spaces if there is none. Of steps that are as
+// common, the shortest wins, then the one seen first.
+func indentUnit(lines []string) string {
+	counts := make(map[string]int)
+	var seen []string
+	prev := ""
+	usesTabs := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := fileops.Indentation(line)
+		usesTabs = usesTabs || strings.HasPrefix(indent, "\t")
+		if extra, ok := strings.CutPrefix(indent, prev); ok && extra != "" {
+			if counts[extra] == 0 {
+				seen = append(seen, extra)
+			}
+			counts[extra]++
+		}
+		prev = indent
+	}
+
+	unit, best := "", 0
+	for _, extra := range seen {
+		n := counts[extra]
+		if n > best || n == best && len(extra) < len(unit) {
+			unit, best = extra, n
+		}
+	}
+	if unit != "" {
+		return unit
+	}
+	if usesTabs {
+		return "\t"
+	}
+	return "    "
+}
+
+// reindent moves block to indent, keeping the indentation of its lines
+// relative to each other. Blank lines are left empty.
+func reindent(block []string, indent string) []string {
+	common := ""
+	first := true
+	for _, line := range block {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := fileops.Indentation(line)
+		if first {
+			common, first = lineIndent, false
+			continue
+		}
+		for !strings.HasPrefix(lineIndent, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	out := make([]string, len(block))
+	for n, line := range block {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		out[n] = indent + strings.TrimPrefix(line, common)
+	}
+	return out
+}
@@ -1,7 +1,12 @@
 package insert
 
-import "os"
+import (
+	"os"
 
-func Insert(path, insertLine, newStr string, showChanges, showResult bool) error {
-	return NewInserter(os.Stdout).Insert(path, insertLine, newStr, showChanges, showResult)
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func Insert(path, insertLine, newStr string, anchor Anchor, autoIndent, showChanges, showResult bool, pre fileops.Precondition) error {
+	_, err := NewInserter(os.Stdout).Insert(path, insertLine, newStr, anchor, autoIndent, showChanges, showResult, pre)
+	return err
 }
@@ -10,57 +10,100 @@ import (
 	"github.com/RRethy/eddie/internal/cmd/undo_edit"
 	"github.com/RRethy/eddie/internal/display"
 	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type Inserter struct {
 	fileOps *fileops.FileOps
-	display *display.Display
+	out     io.Writer
 }
 
 func NewInserter(w io.Writer) *Inserter {
 	return &Inserter{
 		fileOps: &fileops.FileOps{},
-		display: display.New(w),
+		out:     w,
 	}
 }
 
-func (i *Inserter) Insert(path, insertLine, newStr string, showChanges, showResult bool) error {
-	original, info, err := i.fileOps.ReadFileContentForOperation(path, "insert line in")
+// Insert inserts newStr as one or more lines at line insertLine of path, or
+// next to anchor if its pattern is set. A trailing newline in newStr does not
+// add an empty line. With autoIndent, the lines are re-indented to match the
+// block they are inserted into.
+func (i *Inserter) Insert(path, insertLine, newStr string, anchor Anchor, autoIndent, showChanges, showResult bool, pre fileops.Precondition) (*output.Edit, error) {
+	raw, info, err := i.fileOps.ReadFileContentForOperation(path, "insert line in")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	lineNum, err := i.parseLineNumber(insertLine)
-	if err != nil {
-		return fmt.Errorf("parse line number: %w", err)
+	if err := pre.Check(path, raw, info); err != nil {
+		return nil, err
 	}
 
-	modified, err := i.insertLine(original, lineNum, newStr)
+	original, format, err := fileops.DecodeText(raw)
 	if err != nil {
-		return fmt.Errorf("insert line: %w", err)
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	newStr, anchor.Pattern = format.NormalizeText(newStr), format.NormalizeText(anchor.Pattern)
+
+	var lineNum int
+	var span *lineSpan
+	if anchor.Pattern != "" {
+		if insertLine != "" {
+			return nil, fmt.Errorf("cannot insert at both a line number and an anchor")
+		}
+		s, err := i.locate(path, original, anchor)
+		if err != nil {
+			return nil, err
+		}
+		span = &s
+		lineNum = s.end + 1
+		if anchor.Before {
+			lineNum = s.start
+		}
+	} else {
+		lineNum, err = i.parseLineNumber(insertLine)
+		if err != nil {
+			return nil, fmt.Errorf("parse line number: %w", err)
+		}
 	}
 
-	if showChanges {
-		i.display.ShowInsertDiff(path, original, modified, lineNum)
+	block := strings.Split(strings.TrimSuffix(newStr, "\n"), "\n")
+	if autoIndent {
+		lines, _ := fileops.SplitLines(original)
+		block = reindent(block, indentFor(lines, lineNum, span, anchor.Before))
 	}
 
-	err = i.fileOps.WriteFileContent(path, modified, info.Mode())
+	modified, err := i.insertLine(original, lineNum, strings.Join(block, "\n"))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("insert line: %w", err)
 	}
 
-	if showResult {
-		i.display.ShowResult(path, modified)
+	encoded, err := format.EncodeText(modified)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	err = i.fileOps.WriteFileContent(path, encoded, info.Mode())
+	if err != nil {
+		return nil, err
 	}
 
 	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
-	err = undoEditor.RecordEdit(path, "insert", "", newStr, lineNum)
+	err = undoEditor.RecordEdit(path, "insert", raw, encoded)
 	if err != nil {
-		return fmt.Errorf("record edit: %w", err)
+		return nil, fmt.Errorf("record edit: %w", err)
 	}
 
-	fmt.Printf("Inserted line at position %d in %s\n", lineNum, path)
-	return nil
+	res := &output.Edit{Command: "insert", Path: path, Count: len(block)}
+	if showChanges {
+		diff := display.Diff(path, original, modified)
+		res.Diff = &diff
+	}
+	if showResult {
+		res.Content = &modified
+	}
+	res.Summary = fmt.Sprintf("Inserted %d line(s) at line %d in %s", len(block), lineNum, path)
+	return res, output.Print(i.out, res)
 }
 
 func (i *Inserter) parseLineNumber(insertLine string) (int, error) {
@@ -81,12 +124,7 @@ func (i *Inserter) insertLine(content string, lineNum int, newStr string) (strin
 		return newStr + "\n", nil
 	}
 
-	lines := strings.Split(content, "\n")
-	hasTrailingNewline := strings.HasSuffix(content, "\n")
-
-	if hasTrailingNewline && len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
-	}
+	lines, hasTrailingNewline := fileops.SplitLines(content)
 
 	if lineNum > len(lines)+1 {
 		return "", fmt.Errorf("line number %d exceeds file length (%d lines)", lineNum, len(lines))
@@ -106,10 +144,5 @@ func (i *Inserter) insertLine(content string, lineNum int, newStr string) (strin
 		result = append(result, lines[lineNum-1:]...)
 	}
 
-	joined := strings.Join(result, "\n")
-	if hasTrailingNewline {
-		joined += "\n"
-	}
-
-	return joined, nil
+	return fileops.JoinLines(result, hasTrailingNewline), nil
 }
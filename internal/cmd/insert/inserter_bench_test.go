@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func BenchmarkInserter_Insert(b *testing.B) {
@@ -36,7 +38,7 @@ func BenchmarkInserter_Insert(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = i.Insert(testFile, "50", "inserted line", false, false)
+				_, err = i.Insert(testFile, "50", "inserted line", Anchor{}, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -75,7 +77,7 @@ func BenchmarkInserter_InsertPosition(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = i.Insert(testFile, pos.line, "inserted line", false, false)
+				_, err = i.Insert(testFile, pos.line, "inserted line", Anchor{}, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -113,7 +115,7 @@ func BenchmarkInserter_InsertContentLength(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = i.Insert(testFile, "500", cl.content, false, false)
+				_, err = i.Insert(testFile, "500", cl.content, Anchor{}, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -1,12 +1,16 @@
 package insert
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func TestInserter_parseLineNumber(t *testing.T) {
@@ -198,7 +202,7 @@ func TestInserter_Insert(t *testing.T) {
 			require.NoError(t, os.WriteFile(testFile, []byte(tt.initialContent), 0o644))
 
 			i := &Inserter{}
-			err := i.Insert(testFile, tt.insertLine, tt.newStr, false, false)
+			_, err := i.Insert(testFile, tt.insertLine, tt.newStr, Anchor{}, false, false, false, fileops.Precondition{})
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -248,7 +252,7 @@ func TestInserter_Insert_Errors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup()
-			err := i.Insert(path, tt.line, tt.content, false, false)
+			_, err := i.Insert(path, tt.line, tt.content, Anchor{}, false, false, false, fileops.Precondition{})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
@@ -301,7 +305,134 @@ func TestInserter_Insert_EdgeCases(t *testing.T) {
 			testFile := filepath.Join(tmpDir, "edge_"+tt.name+".txt")
 			require.NoError(t, os.WriteFile(testFile, []byte(tt.initialContent), 0o644))
 
-			err := i.Insert(testFile, tt.insertLine, tt.newStr, false, false)
+			_, err := i.Insert(testFile, tt.insertLine, tt.newStr, Anchor{}, false, false, false, fileops.Precondition{})
+			require.NoError(t, err)
+
+			result, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(result))
+		})
+	}
+}
+
+func TestInserter_Insert_Anchor(t *testing.T) {
+	tmpDir := t.TempDir()
+	goSource := "package main\n\nimport \"fmt\"\n\n// main prints.\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+
+	tests := []struct {
+		name        string
+		filename    string
+		content     string
+		newStr      string
+		anchor      Anchor
+		autoIndent  bool
+		wantContent string
+		wantErr     string
+	}{
+		{
+			name:        "after string",
+			filename:    "a.txt",
+			content:     "one\ntwo\nthree\n",
+			newStr:      "new",
+			anchor:      Anchor{Pattern: "two", Kind: AnchorString},
+			wantContent: "one\ntwo\nnew\nthree\n",
+		},
+		{
+			name:        "before string",
+			filename:    "b.txt",
+			content:     "one\ntwo\nthree\n",
+			newStr:      "new",
+			anchor:      Anchor{Pattern: "two", Kind: AnchorString, Before: true},
+			wantContent: "one\nnew\ntwo\nthree\n",
+		},
+		{
+			name:        "after multi-line string",
+			filename:    "c.txt",
+			content:     "one\ntwo\nthree\n",
+			newStr:      "new1\nnew2\n",
+			anchor:      Anchor{Pattern: "one\ntwo\n", Kind: AnchorString},
+			wantContent: "one\ntwo\nnew1\nnew2\nthree\n",
+		},
+		{
+			name:        "after regex",
+			filename:    "d.txt",
+			content:     "one\ntwo\nthree\n",
+			newStr:      "new",
+			anchor:      Anchor{Pattern: `^t\w+e$`, Kind: AnchorRegex},
+			wantContent: "one\ntwo\nthree\nnew\n",
+		},
+		{
+			name:        "after symbol",
+			filename:    "e.go",
+			content:     goSource,
+			newStr:      "func helper() {}",
+			anchor:      Anchor{Pattern: "main", Kind: AnchorSymbol},
+			wantContent: goSource + "func helper() {}\n",
+		},
+		{
+			name:        "before symbol includes doc comment",
+			filename:    "f.go",
+			content:     goSource,
+			newStr:      "var x = 1\n",
+			anchor:      Anchor{Pattern: "main", Kind: AnchorSymbol, Before: true},
+			wantContent: "package main\n\nimport \"fmt\"\n\nvar x = 1\n// main prints.\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+		},
+		{
+			name:        "after block opener with auto-indent",
+			filename:    "g.go",
+			content:     goSource,
+			newStr:      "x := 1\nif x > 0 {\n\tx--\n}",
+			anchor:      Anchor{Pattern: "func main() {", Kind: AnchorString},
+			autoIndent:  true,
+			wantContent: "package main\n\nimport \"fmt\"\n\n// main prints.\nfunc main() {\n\tx := 1\n\tif x > 0 {\n\t\tx--\n\t}\n\tfmt.Println(\"hi\")\n}\n",
+		},
+		{
+			name:        "before indented line with auto-indent",
+			filename:    "h.py",
+			content:     "def f():\n    return 1\n",
+			newStr:      "x = 2\n\ny = 3",
+			anchor:      Anchor{Pattern: "return", Kind: AnchorString, Before: true},
+			autoIndent:  true,
+			wantContent: "def f():\n    x = 2\n\n    y = 3\n    return 1\n",
+		},
+		{
+			name:     "ambiguous string",
+			filename: "i.txt",
+			content:  "x\ny\nx\n",
+			newStr:   "new",
+			anchor:   Anchor{Pattern: "x", Kind: AnchorString},
+			wantErr:  "matches 2 times",
+		},
+		{
+			name:     "missing regex",
+			filename: "j.txt",
+			content:  "x\n",
+			newStr:   "new",
+			anchor:   Anchor{Pattern: "^z", Kind: AnchorRegex},
+			wantErr:  "not found",
+		},
+		{
+			name:     "invalid kind",
+			filename: "k.txt",
+			content:  "x\n",
+			newStr:   "new",
+			anchor:   Anchor{Pattern: "x", Kind: "glob"},
+			wantErr:  "invalid anchor kind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, tt.filename)
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			i := &Inserter{}
+			_, err := i.Insert(testFile, "", tt.newStr, tt.anchor, tt.autoIndent, false, false, fileops.Precondition{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
 			require.NoError(t, err)
 
 			result, err := os.ReadFile(testFile)
@@ -310,3 +441,88 @@ func TestInserter_Insert_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestInserter_Insert_AutoIndentAtLine(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.go")
+	require.NoError(t, os.WriteFile(testFile, []byte("func f() {\n\treturn\n}\n"), 0o644))
+
+	i := &Inserter{}
+	_, err := i.Insert(testFile, "3", "  a()\n  b()", Anchor{}, true, false, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	result, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "func f() {\n\treturn\n\ta()\n\tb()\n}\n", string(result))
+
+	_, err = i.Insert(testFile, "1", "x", Anchor{Pattern: "return"}, false, false, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "both a line number and an anchor")
+}
+
+func TestIndentUnit(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{name: "most common", lines: []string{"a", "  b", "    c", "d", "\te"}, want: "  "},
+		{name: "shortest of a tie", lines: []string{"a", "    b", "c", "  d"}, want: "  "},
+		{name: "first of a tie", lines: []string{"a", "\t\tb", "c", "  d"}, want: "\t\t"},
+		{name: "first of a tie reversed", lines: []string{"a", "  b", "c", "\t\td"}, want: "  "},
+		{name: "tabs", lines: []string{"a", "\tb", "\tc"}, want: "\t"},
+		{name: "no steps", lines: []string{"a", "b"}, want: "    "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				assert.Equal(t, tt.want, indentUnit(tt.lines))
+			}
+		})
+	}
+}
+
+func TestInserter_Insert_MultiLineUndo(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("one\ntwo\n"), 0o644))
+
+	i := &Inserter{}
+	_, err := i.Insert(testFile, "2", "a\nb\nc\n", Anchor{}, false, false, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	result, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "one\na\nb\nc\ntwo\n", string(result))
+
+	_, err = undo_edit.NewUndoEditor(io.Discard).UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	result, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", string(result))
+}
+
+func TestInserter_Insert_CRLF(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("one\r\ntwo\r\n"), 0o644))
+
+	i := &Inserter{}
+	_, err := i.Insert(testFile, "", "a\nb", Anchor{Pattern: "one\r\n", Kind: AnchorString}, false, false, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	result, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "one\r\na\r\nb\r\ntwo\r\n", string(result))
+}
@@ -2,19 +2,57 @@ package ls
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
-type Lister struct{}
+type Lister struct {
+	out io.Writer
+}
+
+func NewLister(out io.Writer) *Lister {
+	return &Lister{out: out}
+}
+
+// Entry is a file or directory listed by Ls.
+type Entry struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+// Result is the result of Ls, sorted by name.
+type Result struct {
+	Path    string  `json:"path"`
+	Entries []Entry `json:"entries"`
+}
+
+func (r *Result) String() string {
+	var b strings.Builder
+	for _, entry := range r.Entries {
+		b.WriteString(entry.Name + "\n")
+	}
+	return b.String()
+}
 
-func (l *Lister) Ls(path string) error {
+// Ls prints the entries of the directory at path that opts does not ignore.
+func (l *Lister) Ls(path string, opts ignore.Options) (*Result, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
-		return fmt.Errorf("read dir %s: %w", path, err)
+		return nil, fmt.Errorf("read dir %s: %w", path, err)
 	}
 
+	m := ignore.New(path, opts)
+	res := &Result{Path: path, Entries: make([]Entry, 0, len(entries))}
 	for _, entry := range entries {
-		fmt.Println(entry.Name())
+		if m.Ignored(filepath.Join(path, entry.Name()), entry.IsDir()) {
+			continue
+		}
+		res.Entries = append(res.Entries, Entry{Name: entry.Name(), Dir: entry.IsDir()})
 	}
-	return nil
+	return res, output.Print(l.out, res)
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"testing"
+
+	"github.com/RRethy/eddie/internal/ignore"
 )
 
 func BenchmarkLister_Ls(b *testing.B) {
@@ -33,7 +35,7 @@ func BenchmarkLister_Ls(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				err := l.Ls(testDir)
+				_, err := l.Ls(testDir, ignore.Options{})
 				if err != nil {
 					b.Fatal(err)
 				}
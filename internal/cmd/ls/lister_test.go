@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/ignore"
 )
 
 func TestLister_Ls(t *testing.T) {
@@ -30,7 +32,7 @@ func TestLister_Ls(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := l.Ls(tt.path)
+			_, err := l.Ls(tt.path, ignore.Options{})
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -38,4 +40,32 @@ func TestLister_Ls(t *testing.T) {
 			}
 		})
 	}
+
+	res, err := l.Ls(tmpDir, ignore.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: "file1.txt"}, {Name: "file2.go"}, {Name: "subdir", Dir: true}}, res.Entries)
+	assert.Equal(t, "file1.txt\nfile2.go\nsubdir\n", res.String())
+}
+
+func TestLister_Ls_Ignore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("*.log\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "debug.log"), []byte("log"), 0o644))
+
+	l := &Lister{}
+
+	res, err := l.Ls(tmpDir, ignore.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: "main.go"}}, res.Entries)
+
+	res, err = l.Ls(tmpDir, ignore.Options{Hidden: true})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: ".gitignore"}, {Name: "main.go"}}, res.Entries)
+
+	res, err = l.Ls(tmpDir, ignore.Options{NoIgnore: true, Hidden: true})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: ".gitignore"}, {Name: "debug.log"}, {Name: "main.go"}}, res.Entries)
 }
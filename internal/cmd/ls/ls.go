@@ -1,5 +1,12 @@
 package ls
 
-func Ls(path string) error {
-	return (&Lister{}).Ls(path)
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+func Ls(path string, opts ignore.Options) error {
+	_, err := NewLister(os.Stdout).Ls(path, opts)
+	return err
 }
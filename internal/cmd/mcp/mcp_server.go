@@ -5,22 +5,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/RRethy/eddie/internal/cmd/apply_patch"
 	"github.com/RRethy/eddie/internal/cmd/batch"
+	"github.com/RRethy/eddie/internal/cmd/checkpoint"
 	"github.com/RRethy/eddie/internal/cmd/create"
+	"github.com/RRethy/eddie/internal/cmd/delete_lines"
 	"github.com/RRethy/eddie/internal/cmd/glob"
 	"github.com/RRethy/eddie/internal/cmd/insert"
 	"github.com/RRethy/eddie/internal/cmd/ls"
+	"github.com/RRethy/eddie/internal/cmd/outline"
+	"github.com/RRethy/eddie/internal/cmd/regex_replace"
+	"github.com/RRethy/eddie/internal/cmd/replace_lines"
 	"github.com/RRethy/eddie/internal/cmd/search"
 	"github.com/RRethy/eddie/internal/cmd/str_replace"
 	"github.com/RRethy/eddie/internal/cmd/undo_edit"
 	"github.com/RRethy/eddie/internal/cmd/view"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type McpServer struct{}
@@ -34,12 +41,23 @@ func (m *McpServer) Mcp() error {
 
 	s.AddTool(*m.createViewTool(), m.handleView)
 	s.AddTool(*m.createStrReplaceTool(), m.handleStrReplace)
+	s.AddTool(*m.createRegexReplaceTool(), m.handleRegexReplace)
 	s.AddTool(*m.createCreateTool(), m.handleCreate)
 	s.AddTool(*m.createInsertTool(), m.handleInsert)
+	s.AddTool(*m.createReplaceLinesTool(), m.handleReplaceLines)
+	s.AddTool(*m.createDeleteLinesTool(), m.handleDeleteLines)
+	s.AddTool(*m.createApplyPatchTool(), m.handleApplyPatch)
 	s.AddTool(*m.createUndoEditTool(), m.handleUndoEdit)
+	s.AddTool(*m.createRedoEditTool(), m.handleRedoEdit)
+	s.AddTool(*m.createHistoryTool(), m.handleHistory)
+	s.AddTool(*m.createCheckpointCreateTool(), m.handleCheckpointCreate)
+	s.AddTool(*m.createCheckpointListTool(), m.handleCheckpointList)
+	s.AddTool(*m.createCheckpointRestoreTool(), m.handleCheckpointRestore)
 	s.AddTool(*m.createGlobTool(), m.handleGlob)
 	s.AddTool(*m.createLsTool(), m.handleLs)
 	s.AddTool(*m.createSearchTool(), m.handleSearch)
+	s.AddTool(*m.createGrepTool(), m.handleGrep)
+	s.AddTool(*m.createOutlineTool(), m.handleOutline)
 	s.AddTool(*m.createBatchTool(), m.handleBatch)
 
 	return server.ServeStdio(s)
@@ -47,9 +65,15 @@ func (m *McpServer) Mcp() error {
 
 func (m *McpServer) createViewTool() *mcp.Tool {
 	tool := mcp.NewTool("view",
-		mcp.WithDescription("View file contents or list directory contents"),
+		mcp.WithDescription("View file contents or list directory contents. File views start with the SHA-256 and modification time of the file, which can be passed to an edit as expected_sha256 or expected_mtime"),
 		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file or directory to view")),
 		mcp.WithString("range", mcp.Description("Range of lines to view in format \"start,end\". If \"end\" is -1, reads to end of file. Ignored for directories.")),
+		mcp.WithString("symbol", mcp.Description("View the declaration with this name instead of a range, including its doc comment. Qualify it with enclosing declarations to disambiguate, e.g. \"McpServer.handleBatch\"")),
+		mcp.WithBoolean("line_numbers", mcp.Description("Prefix each line with its line number (default: true)")),
+		mcp.WithBoolean("show_format", mcp.Description("Also report the file's detected encoding, byte order mark and line endings, which edits preserve")),
+		mcp.WithNumber("max_line_length", mcp.Description("Truncate lines longer than this many bytes, 0 for no limit (default: 2000)")),
+		mcp.WithNumber("max_lines", mcp.Description("Stop after this many lines with a \"N more lines\" trailer")),
+		mcp.WithNumber("max_bytes", mcp.Description("Stop before printing more than this many bytes of lines, with a \"N more lines\" trailer")),
 		mcp.WithReadOnlyHintAnnotation(true),
 	)
 	return &tool
@@ -63,6 +87,28 @@ func (m *McpServer) createStrReplaceTool() *mcp.Tool {
 		mcp.WithString("new_str", mcp.Required(), mcp.Description("The string to replace old_str with")),
 		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to the file")),
 		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the edit operation")),
+		mcp.WithBoolean("unique", mcp.Description("Fail unless old_str occurs exactly once. The error lists the line of every match.")),
+		mcp.WithNumber("expected_count", mcp.Description("Fail unless old_str occurs exactly this many times")),
+		mcp.WithBoolean("fuzzy", mcp.Description("If old_str does not occur, replace its only occurrence ignoring differences in whitespace and indentation, re-indenting new_str to match. If there is no single such occurrence, the error suggests the closest snippets")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createRegexReplaceTool() *mcp.Tool {
+	tool := mcp.NewTool("regex_replace",
+		mcp.WithDescription("Replace regular expression matches in a file using Go RE2 syntax, with $1/${name} capture group expansion in the replacement"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to modify")),
+		mcp.WithString("pattern", mcp.Required(), mcp.Description("The RE2 regular expression to search for")),
+		mcp.WithString("replacement", mcp.Required(), mcp.Description("The replacement text. $1 or ${name} expands to a capture group; $$ is a literal dollar sign")),
+		mcp.WithBoolean("multiline", mcp.Description("Make ^ and $ match at line boundaries")),
+		mcp.WithBoolean("ignore_case", mcp.Description("Match case-insensitively")),
+		mcp.WithNumber("max_replacements", mcp.Description("Replace at most this many matches. Omit to replace all")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to the file")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the edit operation")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
 	)
 	return &tool
 }
@@ -80,12 +126,59 @@ func (m *McpServer) createCreateTool() *mcp.Tool {
 
 func (m *McpServer) createInsertTool() *mcp.Tool {
 	tool := mcp.NewTool("insert",
-		mcp.WithDescription("Insert a new line at specified line number"),
+		mcp.WithDescription("Insert new lines at a line number, or after or before the only match of an anchor"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to modify")),
+		mcp.WithNumber("line", mcp.Description("The line number where the new lines should be inserted (1-based). Required unless after or before is set")),
+		mcp.WithString("content", mcp.Required(), mcp.Description("The lines to insert. A trailing newline does not add an empty line")),
+		mcp.WithString("after", mcp.Description("Insert after the lines of the only match of this anchor instead of at a line number")),
+		mcp.WithString("before", mcp.Description("Insert before the lines of the only match of this anchor instead of at a line number")),
+		mcp.WithString("anchor_type", mcp.Description("How after or before is matched: string (default), regex (Go RE2, ^ and $ match at line boundaries) or symbol (a declaration name as accepted by view's symbol)")),
+		mcp.WithBoolean("auto_indent", mcp.Description("Re-indent content to match the block it is inserted into")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to the file")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the edit operation")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createReplaceLinesTool() *mcp.Tool {
+	tool := mcp.NewTool("replace_lines",
+		mcp.WithDescription("Replace a range of lines in a file with any number of new lines"),
 		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to modify")),
-		mcp.WithNumber("line", mcp.Required(), mcp.Description("The line number where the new line should be inserted (1-based)")),
-		mcp.WithString("content", mcp.Required(), mcp.Description("The content of the new line to insert")),
+		mcp.WithNumber("start_line", mcp.Required(), mcp.Description("The first line to replace (1-based)")),
+		mcp.WithNumber("end_line", mcp.Required(), mcp.Description("The last line to replace (inclusive). Use -1 for the end of the file")),
+		mcp.WithString("new_str", mcp.Required(), mcp.Description("The lines to put in place of the range. An empty string removes the lines")),
 		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to the file")),
 		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the edit operation")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createDeleteLinesTool() *mcp.Tool {
+	tool := mcp.NewTool("delete_lines",
+		mcp.WithDescription("Delete one or more ranges of lines from a file"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to modify")),
+		mcp.WithArray("ranges", mcp.Required(), mcp.Items(map[string]any{"type": "string"}), mcp.Description("Line ranges to delete, each \"N\" or \"start,end\" (1-based, inclusive). Use -1 as end for the end of the file")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to the file")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the edit operation")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createApplyPatchTool() *mcp.Tool {
+	tool := mcp.NewTool("apply_patch",
+		mcp.WithDescription("Apply a unified diff, as written by diff -u or git diff, to one or more files. Hunks from /dev/null create files and hunks to /dev/null delete them. Every hunk is reported, nothing is written unless all of them apply, and undo_edit on any patched file undoes the whole patch"),
+		mcp.WithString("patch", mcp.Required(), mcp.Description("The unified diff to apply")),
+		mcp.WithNumber("fuzz", mcp.Description("Ignore up to this many lines of context at each end of a hunk that does not fit (default: 2)")),
+		mcp.WithNumber("max_offset", mcp.Description("Move a hunk at most this many lines from where its header places it. Omit for no limit")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to each file")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content of each file after the patch")),
+		mcp.WithObject("preconditions", mcp.Description("Fail with a conflict error, before writing anything, unless each file named here matches. Maps a path to {\"sha256\": ..., \"mtime\": ...} as reported by view")),
 	)
 	return &tool
 }
@@ -96,6 +189,63 @@ func (m *McpServer) createUndoEditTool() *mcp.Tool {
 		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to restore from backup")),
 		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made during the undo operation")),
 		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the undo operation")),
+		mcp.WithBoolean("force", mcp.Description("If the file was modified outside of eddie, merge the undo with those changes instead of failing")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createRedoEditTool() *mcp.Tool {
+	tool := mcp.NewTool("redo_edit",
+		mcp.WithDescription("Redo edits to a file that were reverted by undo_edit or history goto"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file to redo edits on")),
+		mcp.WithNumber("count", mcp.Description("Number of edits to redo (default: 1)")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made during the redo operation")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content after the redo operation")),
+		mcp.WithBoolean("force", mcp.Description("If the file was modified outside of eddie, merge the redo with those changes instead of failing")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createHistoryTool() *mcp.Tool {
+	tool := mcp.NewTool("history",
+		mcp.WithDescription("List the recorded edits of a file with their index, timestamp, type and a short diff, or restore the file to the state after any of them"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the file whose edit history to show")),
+		mcp.WithNumber("goto", mcp.Description("Restore the file to the state after this edit index (0 for the content before the first edit). Omit to list the history.")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made when moving with goto")),
+		mcp.WithBoolean("show_result", mcp.Description("Show the new content after moving with goto")),
+		mcp.WithBoolean("force", mcp.Description("If the file was modified outside of eddie, merge the move with those changes instead of failing")),
+		mcp.WithString("expected_sha256", mcp.Description("Fail with a conflict error unless the file's SHA-256, as reported by view, matches")),
+		mcp.WithString("expected_mtime", mcp.Description("Fail with a conflict error unless the file's modification time, as reported by view, matches")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createCheckpointCreateTool() *mcp.Tool {
+	tool := mcp.NewTool("checkpoint_create",
+		mcp.WithDescription("Create a named checkpoint of every file edited under a directory, so coordinated edits across many files can be rolled back together"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The checkpoint name, made of letters, digits, '.', '_' and '-'")),
+		mcp.WithString("root", mcp.Description("Directory whose files the checkpoint covers. Defaults to the current working directory.")),
+	)
+	return &tool
+}
+
+func (m *McpServer) createCheckpointListTool() *mcp.Tool {
+	tool := mcp.NewTool("checkpoint_list",
+		mcp.WithDescription("List checkpoints with their creation time and root directory"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	return &tool
+}
+
+func (m *McpServer) createCheckpointRestoreTool() *mcp.Tool {
+	tool := mcp.NewTool("checkpoint_restore",
+		mcp.WithDescription("Revert every file changed since a checkpoint to its state at the checkpoint. Files created after it are deleted."),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The checkpoint to restore")),
+		mcp.WithBoolean("show_changes", mcp.Description("Show the changes made to each file")),
 	)
 	return &tool
 }
@@ -105,6 +255,8 @@ func (m *McpServer) createGlobTool() *mcp.Tool {
 		mcp.WithDescription("Fast file pattern matching tool that works with any codebase size"),
 		mcp.WithString("pattern", mcp.Required(), mcp.Description("The glob pattern to match files against")),
 		mcp.WithString("path", mcp.Description("The directory to search in. If not specified, the current working directory will be used. IMPORTANT: Omit this field to use the default directory. DO NOT enter \"undefined\" or \"null\" - simply omit it for the default behavior. Must be a valid directory path if provided.")),
+		mcp.WithBoolean("no_ignore", mcp.Description("Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes")),
+		mcp.WithBoolean("hidden", mcp.Description("Include files and directories whose name starts with a dot")),
 		mcp.WithReadOnlyHintAnnotation(true),
 	)
 	return &tool
@@ -126,35 +278,110 @@ func (m *McpServer) handleView(ctx context.Context, req mcp.CallToolRequest) (*m
 		rangeStr = r
 	}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	symbol := ""
+	if s, ok := args["symbol"].(string); ok {
+		symbol = s
+	}
 
-	err := view.View(path, rangeStr)
+	lineNumbers := true
+	if ln, ok := args["line_numbers"].(bool); ok {
+		lineNumbers = ln
+	}
 
-	w.Close()
-	os.Stdout = old
+	showFormat := false
+	if sf, ok := args["show_format"].(bool); ok {
+		showFormat = sf
+	}
 
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	output := buf.String()
+	maxLineLength := view.DefaultMaxLineLength
+	if ml, ok := args["max_line_length"].(float64); ok {
+		maxLineLength = int(ml)
+	}
+
+	maxLines := 0
+	if ml, ok := args["max_lines"].(float64); ok {
+		maxLines = int(ml)
+	}
+
+	maxBytes := 0
+	if mb, ok := args["max_bytes"].(float64); ok {
+		maxBytes = int(mb)
+	}
 
+	res, err := view.NewViewer(nil).View(path, rangeStr, symbol, lineNumbers, showFormat, maxLineLength, maxLines, maxBytes)
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent(output),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
 
+// errorText renders err as the text of a failed tool call, followed by any
+// details it carries.
+func errorText(err error) string {
+	return fmt.Sprintf("Error: %v", err) + output.ErrorDetails(err)
+}
+
+// preconditionArgs reads the optional expected_sha256 and expected_mtime
+// arguments of a mutating tool.
+func preconditionArgs(args map[string]any) fileops.Precondition {
+	var pre fileops.Precondition
+	if sha, ok := args["expected_sha256"].(string); ok {
+		pre.SHA256 = sha
+	}
+	if mtime, ok := args["expected_mtime"].(string); ok {
+		pre.ModTime = mtime
+	}
+	return pre
+}
+
+// patchPreconditionArgs reads the optional preconditions argument of
+// apply_patch, which maps paths to their expected sha256 and mtime.
+func patchPreconditionArgs(args map[string]any) (map[string]fileops.Precondition, error) {
+	obj, ok := args["preconditions"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	pres := make(map[string]fileops.Precondition, len(obj))
+	for path, value := range obj {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("precondition for %s must be an object", path)
+		}
+		var pre fileops.Precondition
+		if sha, ok := fields["sha256"].(string); ok {
+			pre.SHA256 = sha
+		}
+		if mtime, ok := fields["mtime"].(string); ok {
+			pre.ModTime = mtime
+		}
+		pres[path] = pre
+	}
+	return pres, nil
+}
+
+// ignoreArgs reads the optional no_ignore and hidden arguments of a tool that
+// walks directories.
+func ignoreArgs(args map[string]any) ignore.Options {
+	var opts ignore.Options
+	if ni, ok := args["no_ignore"].(bool); ok {
+		opts.NoIgnore = ni
+	}
+	if h, ok := args["hidden"].(bool); ok {
+		opts.Hidden = h
+	}
+	return opts
+}
+
 func (m *McpServer) handleStrReplace(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
@@ -184,19 +411,95 @@ func (m *McpServer) handleStrReplace(ctx context.Context, req mcp.CallToolReques
 		showResult = sr
 	}
 
-	err := str_replace.StrReplace(path, oldStr, newStr, showChanges, showResult)
+	expectedCount := 0
+	if ec, ok := args["expected_count"].(float64); ok {
+		expectedCount = int(ec)
+	}
+	unique, _ := args["unique"].(bool)
+	expectedCount, err := str_replace.ExpectedCount(unique, expectedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	fuzzy := false
+	if f, ok := args["fuzzy"].(bool); ok {
+		fuzzy = f
+	}
+
+	res, err := str_replace.NewReplacer(nil).StrReplace(path, oldStr, newStr, expectedCount, fuzzy, showChanges, showResult, preconditionArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleRegexReplace(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
+	pattern, ok := args["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern parameter required")
+	}
+	replacement, ok := args["replacement"].(string)
+	if !ok {
+		return nil, fmt.Errorf("replacement parameter required")
+	}
+
+	multiline := false
+	if ml, ok := args["multiline"].(bool); ok {
+		multiline = ml
+	}
+
+	ignoreCase := false
+	if ic, ok := args["ignore_case"].(bool); ok {
+		ignoreCase = ic
+	}
+
+	maxReplacements := 0
+	if mr, ok := args["max_replacements"].(float64); ok {
+		maxReplacements = int(mr)
+	}
+
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
+
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
+
+	res, err := regex_replace.NewReplacer(nil).RegexReplace(path, pattern, replacement, multiline, ignoreCase, maxReplacements, showChanges, showResult, preconditionArgs(args))
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent("String replacement completed successfully"),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
@@ -226,19 +529,19 @@ func (m *McpServer) handleCreate(ctx context.Context, req mcp.CallToolRequest) (
 		showResult = sr
 	}
 
-	err := create.Create(path, content, showChanges, showResult)
+	res, err := create.NewCreator(nil).Create(path, content, showChanges, showResult)
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent("File created successfully"),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
@@ -253,16 +556,39 @@ func (m *McpServer) handleInsert(ctx context.Context, req mcp.CallToolRequest) (
 	if !ok {
 		return nil, fmt.Errorf("path parameter required")
 	}
-	lineFloat, ok := args["line"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("line parameter required")
-	}
-	line := strconv.Itoa(int(lineFloat))
 	content, ok := args["content"].(string)
 	if !ok {
 		return nil, fmt.Errorf("content parameter required")
 	}
 
+	after, _ := args["after"].(string)
+	before, _ := args["before"].(string)
+	anchorType := insert.AnchorString
+	if at, ok := args["anchor_type"].(string); ok && at != "" {
+		anchorType = at
+	}
+	anchor, err := insert.NewAnchor(after, before, anchorType)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	line := ""
+	if lineFloat, ok := args["line"].(float64); ok {
+		line = strconv.Itoa(int(lineFloat))
+	} else if anchor.Pattern == "" {
+		return nil, fmt.Errorf("line parameter required unless after or before is set")
+	}
+
+	autoIndent := false
+	if ai, ok := args["auto_indent"].(bool); ok {
+		autoIndent = ai
+	}
+
 	showChanges := false
 	if sc, ok := args["show_changes"].(bool); ok {
 		showChanges = sc
@@ -273,24 +599,24 @@ func (m *McpServer) handleInsert(ctx context.Context, req mcp.CallToolRequest) (
 		showResult = sr
 	}
 
-	err := insert.Insert(path, line, content, showChanges, showResult)
+	res, err := insert.NewInserter(nil).Insert(path, line, content, anchor, autoIndent, showChanges, showResult, preconditionArgs(args))
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent("Line inserted successfully"),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
 
-func (m *McpServer) handleUndoEdit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (m *McpServer) handleReplaceLines(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments")
@@ -300,6 +626,18 @@ func (m *McpServer) handleUndoEdit(ctx context.Context, req mcp.CallToolRequest)
 	if !ok {
 		return nil, fmt.Errorf("path parameter required")
 	}
+	startFloat, ok := args["start_line"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("start_line parameter required")
+	}
+	endFloat, ok := args["end_line"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("end_line parameter required")
+	}
+	newStr, ok := args["new_str"].(string)
+	if !ok {
+		return nil, fmt.Errorf("new_str parameter required")
+	}
 
 	showChanges := false
 	if sc, ok := args["show_changes"].(bool); ok {
@@ -311,69 +649,132 @@ func (m *McpServer) handleUndoEdit(ctx context.Context, req mcp.CallToolRequest)
 		showResult = sr
 	}
 
-	err := undo_edit.UndoEdit(path, showChanges, showResult, 1)
+	res, err := replace_lines.NewLineReplacer(nil).ReplaceLines(path, int(startFloat), int(endFloat), newStr, showChanges, showResult, preconditionArgs(args))
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent("Edit undone successfully"),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
 
-func (m *McpServer) handleGlob(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (m *McpServer) handleDeleteLines(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments")
 	}
 
-	pattern, ok := args["pattern"].(string)
+	path, ok := args["path"].(string)
 	if !ok {
-		return nil, fmt.Errorf("pattern parameter required")
+		return nil, fmt.Errorf("path parameter required")
+	}
+	rangeArgs, ok := args["ranges"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("ranges parameter required")
+	}
+	ranges := make([]string, 0, len(rangeArgs))
+	for _, r := range rangeArgs {
+		s, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("ranges must be strings")
+		}
+		ranges = append(ranges, s)
 	}
 
-	path := ""
-	if p, ok := args["path"].(string); ok {
-		path = p
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
 	}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
 
-	err := glob.Glob(pattern, path)
+	res, err := delete_lines.NewLineDeleter(nil).DeleteLines(path, ranges, showChanges, showResult, preconditionArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
 
-	w.Close()
-	os.Stdout = old
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
 
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	output := buf.String()
+func (m *McpServer) handleApplyPatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	patch, ok := args["patch"].(string)
+	if !ok {
+		return nil, fmt.Errorf("patch parameter required")
+	}
+
+	fuzz := apply_patch.DefaultFuzz
+	if f, ok := args["fuzz"].(float64); ok {
+		fuzz = int(f)
+	}
 
+	maxOffset := 0
+	if mo, ok := args["max_offset"].(float64); ok {
+		maxOffset = int(mo)
+	}
+
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
+
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
+
+	pres, err := patchPreconditionArgs(args)
 	if err != nil {
+		return nil, err
+	}
+
+	res, err := apply_patch.NewPatcher(nil).ApplyPatch(patch, fuzz, maxOffset, showChanges, showResult, pres)
+	if err != nil {
+		// The hunk report tells which hunks need fixing.
+		var report string
+		if res != nil {
+			report = output.Render(res)
+		}
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(report + errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent(output),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
 
-func (m *McpServer) handleSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (m *McpServer) handleUndoEdit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments")
@@ -384,36 +785,308 @@ func (m *McpServer) handleSearch(ctx context.Context, req mcp.CallToolRequest) (
 		return nil, fmt.Errorf("path parameter required")
 	}
 
-	query, ok := args["tree_sitter_query"].(string)
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
+
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
+
+	force := false
+	if f, ok := args["force"].(bool); ok {
+		force = f
+	}
+
+	res, err := undo_edit.NewUndoEditor(nil).UndoEdit(path, showChanges, showResult, 1, force, preconditionArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleRedoEdit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("tree_sitter_query parameter required")
+		return nil, fmt.Errorf("invalid arguments")
 	}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
 
-	err := search.Search(path, query)
+	count := 1
+	if c, ok := args["count"].(float64); ok {
+		count = int(c)
+	}
 
-	w.Close()
-	os.Stdout = old
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
 
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	output := buf.String()
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
+
+	force := false
+	if f, ok := args["force"].(bool); ok {
+		force = f
+	}
+
+	res, err := undo_edit.NewUndoEditor(nil).RedoEdit(path, showChanges, showResult, count, force, preconditionArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleHistory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
+
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
+
+	showResult := false
+	if sr, ok := args["show_result"].(bool); ok {
+		showResult = sr
+	}
+
+	force := false
+	if f, ok := args["force"].(bool); ok {
+		force = f
+	}
+
+	var res output.Result
+	var err error
+	if g, ok := args["goto"].(float64); ok {
+		res, err = undo_edit.NewUndoEditor(nil).GotoEdit(path, int(g), showChanges, showResult, force, preconditionArgs(args))
+	} else {
+		res, err = undo_edit.NewUndoEditor(nil).ShowHistory(path)
+	}
+
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleCheckpointCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	name, ok := args["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("name parameter required")
+	}
+
+	root := "."
+	if r, ok := args["root"].(string); ok && r != "" {
+		root = r
+	}
+
+	res, err := checkpoint.NewCheckpointer(nil).Create(name, root)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleCheckpointList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	res, err := checkpoint.NewCheckpointer(nil).List()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleCheckpointRestore(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	name, ok := args["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("name parameter required")
+	}
+
+	showChanges := false
+	if sc, ok := args["show_changes"].(bool); ok {
+		showChanges = sc
+	}
+
+	res, err := checkpoint.NewCheckpointer(nil).Restore(name, showChanges)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleGlob(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	pattern, ok := args["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern parameter required")
+	}
+
+	path := ""
+	if p, ok := args["path"].(string); ok {
+		path = p
+	}
+
+	res, err := glob.NewGlobber(nil).Glob(pattern, path, ignoreArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
+
+	query, _ := args["tree_sitter_query"].(string)
+	queryFile, _ := args["query_file"].(string)
+	if (query == "") == (queryFile == "") {
+		return nil, fmt.Errorf("exactly one of tree_sitter_query and query_file parameters required")
+	}
+	if queryFile != "" {
+		var err error
+		query, err = search.ReadQuery(queryFile)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					mcp.NewTextContent(errorText(err)),
+				},
+			}, nil
+		}
+	}
+
+	var opts search.SearchOptions
+	opts.Captures = stringsArg(args, "capture")
+	if st, ok := args["show_text"].(bool); ok {
+		opts.ShowText = st
+	}
+	if sp, ok := args["show_span"].(bool); ok {
+		opts.ShowSpan = sp
+	}
+	opts.Before, opts.After = contextArgs(args)
+	if j, ok := args["json"].(bool); ok {
+		opts.JSON = j
+	}
 
+	res, err := search.NewSearcher(nil).Search(path, query, opts, ignoreArgs(args))
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent(output),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
@@ -422,6 +1095,8 @@ func (m *McpServer) createLsTool() *mcp.Tool {
 	tool := mcp.NewTool("ls",
 		mcp.WithDescription("List directory contents"),
 		mcp.WithString("path", mcp.Description("The directory to search in. If not specified, the current working directory will be used. IMPORTANT: Omit this field to use the default directory. DO NOT enter \"undefined\" or \"null\" - simply omit it for the default behavior. Must be a valid directory path if provided.")),
+		mcp.WithBoolean("no_ignore", mcp.Description("Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes")),
+		mcp.WithBoolean("hidden", mcp.Description("Include files and directories whose name starts with a dot")),
 		mcp.WithReadOnlyHintAnnotation(true),
 	)
 	return &tool
@@ -429,9 +1104,51 @@ func (m *McpServer) createLsTool() *mcp.Tool {
 
 func (m *McpServer) createSearchTool() *mcp.Tool {
 	tool := mcp.NewTool("search",
-		mcp.WithDescription("Search for code patterns using tree-sitter queries across files"),
+		mcp.WithDescription("Search for code patterns using tree-sitter queries across files. Predicates such as #eq?, #not-eq?, #match?, #not-match? and #any-of? filter the matches, and the matches of a query with several patterns are grouped by pattern index"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Path to file or directory to search")),
+		mcp.WithString("tree_sitter_query", mcp.Description("Tree-sitter query, one or more patterns. Required unless query_file is set")),
+		mcp.WithString("query_file", mcp.Description("Path to a file with the tree-sitter query, such as a .scm file with many patterns, instead of tree_sitter_query")),
+		mcp.WithArray("capture", mcp.Items(map[string]any{"type": "string"}), mcp.Description("Only return the nodes captured with one of these names, without the @")),
+		mcp.WithBoolean("show_text", mcp.Description("Return the whole text of each captured node instead of its first line")),
+		mcp.WithBoolean("show_span", mcp.Description("Return the end position and byte range of each captured node")),
+		mcp.WithNumber("before_context", mcp.Description("Lines of context to return before each node")),
+		mcp.WithNumber("after_context", mcp.Description("Lines of context to return after each node")),
+		mcp.WithNumber("context", mcp.Description("Lines of context to return around each node, unless before_context or after_context is set")),
+		mcp.WithBoolean("json", mcp.Description("Return the matches as JSON with their start and end lines, columns and byte ranges, ready for view and line-range edits")),
+		mcp.WithBoolean("no_ignore", mcp.Description("Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes")),
+		mcp.WithBoolean("hidden", mcp.Description("Include files and directories whose name starts with a dot")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	return &tool
+}
+
+func (m *McpServer) createGrepTool() *mcp.Tool {
+	tool := mcp.NewTool("grep",
+		mcp.WithDescription("Search any text file for lines matching a regular expression (Go RE2 syntax) or a literal string, like grep. Use it for config, docs, SQL and other files search cannot parse"),
 		mcp.WithString("path", mcp.Required(), mcp.Description("Path to file or directory to search")),
-		mcp.WithString("tree_sitter_query", mcp.Required(), mcp.Description("Tree-sitter query pattern")),
+		mcp.WithString("pattern", mcp.Required(), mcp.Description("Regular expression to search for")),
+		mcp.WithBoolean("fixed_strings", mcp.Description("Search for the pattern as a literal string")),
+		mcp.WithBoolean("ignore_case", mcp.Description("Match case-insensitively")),
+		mcp.WithBoolean("word_regexp", mcp.Description("Only match whole words")),
+		mcp.WithNumber("before_context", mcp.Description("Lines of context to return before each match")),
+		mcp.WithNumber("after_context", mcp.Description("Lines of context to return after each match")),
+		mcp.WithNumber("context", mcp.Description("Lines of context to return around each match, unless before_context or after_context is set")),
+		mcp.WithArray("include", mcp.Items(map[string]any{"type": "string"}), mcp.Description("Only search files matching one of these globs (gitignore syntax)")),
+		mcp.WithArray("exclude", mcp.Items(map[string]any{"type": "string"}), mcp.Description("Skip files and directories matching one of these globs (gitignore syntax)")),
+		mcp.WithBoolean("files_with_matches", mcp.Description("Only return the files that match")),
+		mcp.WithBoolean("count_matches", mcp.Description("Only return the number of matching lines in each file")),
+		mcp.WithBoolean("no_ignore", mcp.Description("Include files excluded by .gitignore, .ignore, .eddieignore and the global excludes")),
+		mcp.WithBoolean("hidden", mcp.Description("Include files and directories whose name starts with a dot")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	return &tool
+}
+
+func (m *McpServer) createOutlineTool() *mcp.Tool {
+	tool := mcp.NewTool("outline",
+		mcp.WithDescription("List the functions, methods, types, classes and impls of a source file with their signatures and line ranges, nested by scope. Use the ranges with view to read a single declaration"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("The path to the source file")),
+		mcp.WithBoolean("json", mcp.Description("Return the outline as JSON")),
 		mcp.WithReadOnlyHintAnnotation(true),
 	)
 	return &tool
@@ -445,42 +1162,143 @@ func (m *McpServer) createBatchTool() *mcp.Tool {
 	return &tool
 }
 
-func (m *McpServer) handleLs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (m *McpServer) handleGrep(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, ok := req.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments")
 	}
 
-	path := "."
-	if p, ok := args["path"].(string); ok {
-		path = p
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
+	pattern, ok := args["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern parameter required")
 	}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	opts := search.GrepOptions{Pattern: pattern}
+	if f, ok := args["fixed_strings"].(bool); ok {
+		opts.Literal = f
+	}
+	if ic, ok := args["ignore_case"].(bool); ok {
+		opts.IgnoreCase = ic
+	}
+	if w, ok := args["word_regexp"].(bool); ok {
+		opts.WordRegexp = w
+	}
+	opts.Before, opts.After = contextArgs(args)
+	opts.Include = stringsArg(args, "include")
+	opts.Exclude = stringsArg(args, "exclude")
+	if fwm, ok := args["files_with_matches"].(bool); ok {
+		opts.FilesWithMatches = fwm
+	}
+	if cm, ok := args["count_matches"].(bool); ok {
+		opts.Count = cm
+	}
 
-	err := ls.Ls(path)
+	res, err := search.NewSearcher(nil).Grep(path, opts, ignoreArgs(args))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
 
-	w.Close()
-	os.Stdout = old
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
 
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	output := buf.String()
+// stringsArg reads the optional array of strings argument called name,
+// leaving out items that are not strings.
+func stringsArg(args map[string]any, name string) []string {
+	items, _ := args[name].([]any)
+	var values []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// contextArgs returns the lines of context asked for before and after each
+// match, with before_context and after_context taking precedence over context.
+func contextArgs(args map[string]any) (before, after int) {
+	if c, ok := args["context"].(float64); ok {
+		before, after = int(c), int(c)
+	}
+	if b, ok := args["before_context"].(float64); ok {
+		before = int(b)
+	}
+	if a, ok := args["after_context"].(float64); ok {
+		after = int(a)
+	}
+	return before, after
+}
+
+func (m *McpServer) handleOutline(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required")
+	}
+
+	asJSON := false
+	if j, ok := args["json"].(bool); ok {
+		asJSON = j
+	}
+
+	res, err := outline.NewOutliner(nil).Outline(path, asJSON)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(errorText(err)),
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(output.Render(res)),
+		},
+	}, nil
+}
+
+func (m *McpServer) handleLs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := req.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+
+	path := "."
+	if p, ok := args["path"].(string); ok {
+		path = p
+	}
 
+	res, err := ls.NewLister(nil).Ls(path, ignoreArgs(args))
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				mcp.NewTextContent(fmt.Sprintf("Error: %v", err)),
+				mcp.NewTextContent(errorText(err)),
 			},
 		}, nil
 	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent(output),
+			mcp.NewTextContent(output.Render(res)),
 		},
 	}, nil
 }
@@ -518,7 +1336,7 @@ func (m *McpServer) handleBatch(ctx context.Context, req mcp.CallToolRequest) (*
 		}, nil
 	}
 
-	output, err := json.Marshal(batchResp)
+	data, err := json.Marshal(batchResp)
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -530,7 +1348,7 @@ func (m *McpServer) handleBatch(ctx context.Context, req mcp.CallToolRequest) (*
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			mcp.NewTextContent(string(output)),
+			mcp.NewTextContent(string(data)),
 		},
 	}, nil
 }
@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +10,10 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/cmd/search"
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func TestMcpServer_createViewTool(t *testing.T) {
@@ -29,6 +34,15 @@ func TestMcpServer_createStrReplaceTool(t *testing.T) {
 	assert.Contains(t, tool.Description, "Replace all occurrences")
 }
 
+func TestMcpServer_createRegexReplaceTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createRegexReplaceTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "regex_replace", tool.Name)
+	assert.Contains(t, tool.Description, "Replace regular expression matches")
+}
+
 func TestMcpServer_createCreateTool(t *testing.T) {
 	m := &McpServer{}
 	tool := m.createCreateTool()
@@ -44,7 +58,57 @@ func TestMcpServer_createInsertTool(t *testing.T) {
 
 	assert.NotNil(t, tool)
 	assert.Equal(t, "insert", tool.Name)
-	assert.Contains(t, tool.Description, "Insert a new line")
+	assert.Contains(t, tool.Description, "Insert new lines")
+}
+
+func TestMcpServer_createReplaceLinesTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createReplaceLinesTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "replace_lines", tool.Name)
+	assert.Contains(t, tool.Description, "Replace a range of lines")
+}
+
+func TestMcpServer_createDeleteLinesTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createDeleteLinesTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "delete_lines", tool.Name)
+	assert.Contains(t, tool.Description, "Delete one or more ranges")
+}
+
+func TestMcpServer_createApplyPatchTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createApplyPatchTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "apply_patch", tool.Name)
+	assert.Contains(t, tool.Description, "unified diff")
+	assert.Contains(t, tool.InputSchema.Properties, "preconditions")
+}
+
+func TestPatchPreconditionArgs(t *testing.T) {
+	pres, err := patchPreconditionArgs(map[string]any{
+		"preconditions": map[string]any{
+			"a.go": map[string]any{"sha256": "abc", "mtime": "2024-01-02T03:04:05Z"},
+			"b.go": map[string]any{"sha256": "def"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]fileops.Precondition{
+		"a.go": {SHA256: "abc", ModTime: "2024-01-02T03:04:05Z"},
+		"b.go": {SHA256: "def"},
+	}, pres)
+
+	pres, err = patchPreconditionArgs(map[string]any{})
+	require.NoError(t, err)
+	assert.Nil(t, pres)
+
+	_, err = patchPreconditionArgs(map[string]any{"preconditions": map[string]any{"a.go": "abc"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "precondition for a.go must be an object")
 }
 
 func TestMcpServer_createUndoEditTool(t *testing.T) {
@@ -56,6 +120,51 @@ func TestMcpServer_createUndoEditTool(t *testing.T) {
 	assert.Contains(t, tool.Description, "Undo the last edit")
 }
 
+func TestMcpServer_createRedoEditTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createRedoEditTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "redo_edit", tool.Name)
+	assert.Contains(t, tool.Description, "Redo edits")
+}
+
+func TestMcpServer_createHistoryTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createHistoryTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "history", tool.Name)
+	assert.Contains(t, tool.Description, "List the recorded edits")
+}
+
+func TestMcpServer_createCheckpointTools(t *testing.T) {
+	m := &McpServer{}
+
+	tests := []struct {
+		tool *mcp.Tool
+		name string
+	}{
+		{m.createCheckpointCreateTool(), "checkpoint_create"},
+		{m.createCheckpointListTool(), "checkpoint_list"},
+		{m.createCheckpointRestoreTool(), "checkpoint_restore"},
+	}
+
+	for _, tt := range tests {
+		assert.NotNil(t, tt.tool)
+		assert.Equal(t, tt.name, tt.tool.Name)
+	}
+}
+
+func TestMcpServer_createOutlineTool(t *testing.T) {
+	m := &McpServer{}
+	tool := m.createOutlineTool()
+
+	assert.NotNil(t, tool)
+	assert.Equal(t, "outline", tool.Name)
+	assert.Contains(t, tool.Description, "line ranges")
+}
+
 func TestMcpServer_createGlobTool(t *testing.T) {
 	m := &McpServer{}
 	tool := m.createGlobTool()
@@ -100,6 +209,10 @@ func goodbye() {
 	err := os.WriteFile(goFile, []byte(goContent), 0o644)
 	require.NoError(t, err)
 
+	queryFile := filepath.Join(tmpDir, "queries.scm")
+	err = os.WriteFile(queryFile, []byte("((identifier) @name (#eq? @name \"goodbye\"))\n"), 0o644)
+	require.NoError(t, err)
+
 	m := &McpServer{}
 
 	tests := []struct {
@@ -107,6 +220,23 @@ func goodbye() {
 		name    string
 		wantErr bool
 	}{
+		{
+			name: "search with query file",
+			args: map[string]any{
+				"path":       goFile,
+				"query_file": queryFile,
+			},
+			wantErr: false,
+		},
+		{
+			name: "query and query file",
+			args: map[string]any{
+				"path":              goFile,
+				"tree_sitter_query": "(identifier) @id",
+				"query_file":        queryFile,
+			},
+			wantErr: true,
+		},
 		{
 			name: "search Go functions",
 			args: map[string]any{
@@ -115,6 +245,17 @@ func goodbye() {
 			},
 			wantErr: false,
 		},
+		{
+			name: "search Go functions as JSON",
+			args: map[string]any{
+				"path":              goFile,
+				"tree_sitter_query": "(function_declaration name: (identifier) @name) @func",
+				"capture":           []any{"func"},
+				"show_text":         true,
+				"json":              true,
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing path",
 			args: map[string]any{
@@ -156,10 +297,112 @@ func goodbye() {
 				assert.Contains(t, textContent.Text, "hello")
 				assert.Contains(t, textContent.Text, "goodbye")
 			}
+			if tt.name == "search with query file" {
+				textContent, ok := result.Content[0].(mcp.TextContent)
+				require.True(t, ok)
+				assert.Equal(t, goFile+":7:6: @name: func goodbye() {\n", textContent.Text)
+			}
+			if tt.name == "search Go functions as JSON" {
+				textContent, ok := result.Content[0].(mcp.TextContent)
+				require.True(t, ok)
+				var res struct {
+					Matches []search.Match `json:"matches"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(textContent.Text), &res))
+				require.Len(t, res.Matches, 2)
+				assert.Equal(t, 3, res.Matches[0].Line)
+				assert.Equal(t, 5, res.Matches[0].EndLine)
+				assert.Equal(t, "func hello() {\n\tprintln(\"Hello, World!\")\n}", res.Matches[0].Text)
+			}
 		})
 	}
 }
 
+func TestMcpServer_handleGrep(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	err := os.WriteFile(configFile, []byte("server:\n  host: localhost\n  port: 8080\n"), 0o644)
+	require.NoError(t, err)
+
+	m := &McpServer{}
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]any{
+				"path":           tmpDir,
+				"pattern":        "PORT",
+				"ignore_case":    true,
+				"before_context": float64(1),
+				"include":        []any{"*.yaml"},
+			},
+		},
+	}
+	result, err := m.handleGrep(context.Background(), req)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, configFile+"-2-  host: localhost\n"+configFile+":3:3:  port: 8080\n", textContent.Text)
+
+	req.Params.Arguments = map[string]any{"path": tmpDir, "pattern": "("}
+	result, err = m.handleGrep(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	req.Params.Arguments = map[string]any{"path": tmpDir}
+	_, err = m.handleGrep(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestMcpServer_handleStrReplace_UniqueAndExpectedCount(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("a\na\n"), 0o644))
+
+	m := &McpServer{}
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]any{
+				"path":           file,
+				"old_str":        "a",
+				"new_str":        "b",
+				"unique":         true,
+				"expected_count": float64(2),
+			},
+		},
+	}
+	_, err := m.handleStrReplace(context.Background(), req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot use both unique and an expected count")
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "a\na\n", string(content))
+}
+
+func TestMcpServer_handleUndoEdit_Modified(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	file := filepath.Join(tmpDir, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("two\n"), 0o644))
+	require.NoError(t, undo_edit.NewUndoEditor(nil).RecordEdit(file, "str_replace", "one\n", "two\n"))
+	require.NoError(t, os.WriteFile(file, []byte("changed\n"), 0o644))
+
+	m := &McpServer{}
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]any{"path": file},
+		},
+	}
+	result, err := m.handleUndoEdit(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, textContent.Text, "Error: file has been modified since last tracked edit")
+	assert.Contains(t, textContent.Text, "Changes in "+file+":")
+	assert.Contains(t, textContent.Text, "+changed")
+}
+
 func TestMcpServer_handleBatch(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
@@ -0,0 +1,8 @@
+package outline
+
+import "os"
+
+func Outline(path string, asJSON bool) error {
+	_, err := NewOutliner(os.Stdout).Outline(path, asJSON)
+	return err
+}
@@ -0,0 +1,95 @@
+package outline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+type Outliner struct {
+	out io.Writer
+}
+
+func NewOutliner(out io.Writer) *Outliner {
+	return &Outliner{out: out}
+}
+
+// Result is the outline of a file: its top-level declarations, each with
+// the declarations nested in it.
+type Result struct {
+	Path    string   `json:"path"`
+	Symbols []Symbol `json:"symbols"`
+
+	asJSON bool
+}
+
+func (r *Result) String() string {
+	if r.asJSON {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("Error: %v\n", err)
+		}
+		return string(data) + "\n"
+	}
+	if len(r.Symbols) == 0 {
+		return fmt.Sprintf("No declarations found in %s\n", r.Path)
+	}
+	var b strings.Builder
+	writeSymbols(&b, r.Symbols, 0)
+	return b.String()
+}
+
+func writeSymbols(b *strings.Builder, symbols []Symbol, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, symbol := range symbols {
+		fmt.Fprintf(b, "%s%s [%d-%d]\n", indent, symbol.Signature, symbol.StartLine, symbol.EndLine)
+		writeSymbols(b, symbol.Children, depth+1)
+	}
+}
+
+// Outline prints the declarations of the source file at path, one per line
+// indented by nesting depth with their line ranges, or as JSON if asJSON is
+// set or the output format is JSON.
+func (o *Outliner) Outline(path string, asJSON bool) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if err := fileops.CheckSize(path, info.Size()); err != nil {
+		return nil, err
+	}
+
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	// Files with a BOM or in UTF-16 or Latin-1 are parsed as UTF-8, the
+	// same text view shows.
+	content, err := fileops.DetectFormat(raw).Decode(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	symbols, err := Parse(path, []byte(content))
+	if err != nil {
+		return nil, err
+	}
+	if symbols == nil {
+		symbols = []Symbol{}
+	}
+
+	res := &Result{Path: path, Symbols: symbols, asJSON: asJSON}
+	return res, output.Print(o.out, res)
+}
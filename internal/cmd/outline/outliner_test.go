@@ -0,0 +1,98 @@
+package outline
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outlineSource = `class Greeter:
+    def greet(self, name):
+        return "hi " + name
+
+def main():
+    Greeter().greet("bob")
+`
+
+func TestOutliner_Outline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greet.py")
+	require.NoError(t, os.WriteFile(path, []byte(outlineSource), 0o644))
+
+	var buf bytes.Buffer
+	_, err := NewOutliner(&buf).Outline(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, "class Greeter: [1-3]\n  def greet(self, name): [2-3]\ndef main(): [5-6]\n", buf.String())
+}
+
+func TestOutliner_Outline_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greet.py")
+	require.NoError(t, os.WriteFile(path, []byte(outlineSource), 0o644))
+
+	var buf bytes.Buffer
+	_, err := NewOutliner(&buf).Outline(path, true)
+	require.NoError(t, err)
+
+	var got struct {
+		Path    string   `json:"path"`
+		Symbols []Symbol `json:"symbols"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, path, got.Path)
+	assert.Equal(t, []Symbol{
+		{
+			Name: "Greeter", Kind: "class", Signature: "class Greeter:", StartLine: 1, EndLine: 3,
+			Children: []Symbol{
+				{Name: "greet", Kind: "method", Signature: "def greet(self, name):", StartLine: 2, EndLine: 3},
+			},
+		},
+		{Name: "main", Kind: "function", Signature: "def main():", StartLine: 5, EndLine: 6},
+	}, got.Symbols)
+}
+
+func TestOutliner_Outline_Encodings(t *testing.T) {
+	tmpDir := t.TempDir()
+	utf16 := []byte{0xff, 0xfe}
+	for _, r := range outlineSource {
+		utf16 = append(utf16, byte(r), 0)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "utf-8 bom", content: append([]byte("\xef\xbb\xbf"), outlineSource...)},
+		{name: "utf-16le", content: utf16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, "greet.py")
+			require.NoError(t, os.WriteFile(path, tt.content, 0o644))
+
+			var buf bytes.Buffer
+			_, err := NewOutliner(&buf).Outline(path, false)
+			require.NoError(t, err)
+			assert.Equal(t, "class Greeter: [1-3]\n  def greet(self, name): [2-3]\ndef main(): [5-6]\n", buf.String())
+		})
+	}
+}
+
+func TestOutliner_Outline_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	empty := filepath.Join(tmpDir, "empty.go")
+	require.NoError(t, os.WriteFile(empty, []byte("package empty\n"), 0o644))
+
+	var buf bytes.Buffer
+	_, err := NewOutliner(&buf).Outline(empty, false)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No declarations found")
+
+	_, err = NewOutliner(&buf).Outline(filepath.Join(tmpDir, "missing.go"), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read")
+}
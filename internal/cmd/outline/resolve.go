@@ -0,0 +1,63 @@
+package outline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolve finds the declaration in a source file named by selector, a name
+// optionally qualified by the names of its enclosing declarations, such as
+// "McpServer.handleBatch". "::" can be used in place of ".". A selector
+// matching several declarations is an error that lists them, unless exactly
+// one of them matches the selector in full.
+func Resolve(filename string, content []byte, selector string) (Symbol, error) {
+	symbols, err := Parse(filename, content)
+	if err != nil {
+		return Symbol{}, err
+	}
+
+	selector = normalizeName(selector)
+	var matches, exact []Symbol
+	var names []string
+	walk(symbols, "", func(name string, symbol Symbol) {
+		if name == selector || strings.HasSuffix(name, "."+selector) {
+			matches = append(matches, symbol)
+			names = append(names, name)
+			if name == selector {
+				exact = append(exact, symbol)
+			}
+		}
+	})
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(exact) == 1:
+		return exact[0], nil
+	case len(matches) == 0:
+		return Symbol{}, fmt.Errorf("symbol %q not found in %s", selector, filename)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "symbol %q is ambiguous in %s, candidates:", selector, filename)
+	for i, match := range matches {
+		fmt.Fprintf(&b, "\n  %s (%s) [%d-%d]", names[i], match.Kind, match.StartLine, match.EndLine)
+	}
+	return Symbol{}, fmt.Errorf("%s", b.String())
+}
+
+// walk calls fn with every symbol and its qualified name, parents first.
+func walk(symbols []Symbol, prefix string, fn func(string, Symbol)) {
+	for _, symbol := range symbols {
+		name := normalizeName(symbol.Name)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fn(name, symbol)
+		walk(symbol.Children, name, fn)
+	}
+}
+
+func normalizeName(name string) string {
+	return strings.ReplaceAll(name, "::", ".")
+}
@@ -0,0 +1,153 @@
+package outline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		content   string
+		selector  string
+		wantStart int
+		wantEnd   int
+		wantDoc   int
+		wantErr   []string
+	}{
+		{
+			name:     "go method with doc comment",
+			filename: "server.go",
+			content: `package main
+
+x := 1 // not a doc comment
+// handleBatch runs
+// every operation.
+func (m *McpServer) handleBatch() {
+}
+`,
+			selector:  "McpServer.handleBatch",
+			wantStart: 6,
+			wantEnd:   7,
+			wantDoc:   4,
+		},
+		{
+			name:     "unqualified name",
+			filename: "server.go",
+			content: `package main
+
+func (m *McpServer) handleBatch() {}
+`,
+			selector:  "handleBatch",
+			wantStart: 3,
+			wantEnd:   3,
+		},
+		{
+			name:     "comment separated by a blank line is not doc",
+			filename: "main.go",
+			content: `package main
+
+// unrelated
+
+func main() {}
+`,
+			selector:  "main",
+			wantStart: 5,
+			wantEnd:   5,
+		},
+		{
+			name:     "rust attributes and doc comments",
+			filename: "lib.rs",
+			content: `/// A point.
+#[derive(Debug)]
+struct Point { x: i32 }
+`,
+			selector:  "Point",
+			wantStart: 3,
+			wantEnd:   3,
+			wantDoc:   1,
+		},
+		{
+			name:     "nested python method",
+			filename: "app.py",
+			content: `class A:
+    # Greets.
+    def greet(self):
+        pass
+
+class B:
+    def greet(self):
+        pass
+`,
+			selector:  "A.greet",
+			wantStart: 3,
+			wantEnd:   4,
+			wantDoc:   2,
+		},
+		{
+			name:     "cpp qualified with colons",
+			filename: "main.cpp",
+			content: `namespace ns {
+class Foo {
+    void bar() {}
+};
+}
+`,
+			selector:  "ns::Foo::bar",
+			wantStart: 3,
+			wantEnd:   3,
+		},
+		{
+			name:     "exact match wins over suffix matches",
+			filename: "app.ts",
+			content: `function area() {}
+class Box {
+  area() {}
+}
+`,
+			selector:  "area",
+			wantStart: 1,
+			wantEnd:   1,
+		},
+		{
+			name:     "ambiguous selector lists candidates",
+			filename: "lib.rs",
+			content: `impl Shape for Point {
+    fn area(&self) -> f64 { 0.0 }
+}
+impl Shape for Circle {
+    fn area(&self) -> f64 { 1.0 }
+}
+`,
+			selector: "area",
+			wantErr:  []string{"ambiguous", "Point.area (method) [2-2]", "Circle.area (method) [5-5]"},
+		},
+		{
+			name:     "not found",
+			filename: "main.go",
+			content:  "package main\n",
+			selector: "main",
+			wantErr:  []string{`symbol "main" not found`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, err := Resolve(tt.filename, []byte(tt.content), tt.selector)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStart, symbol.StartLine)
+			assert.Equal(t, tt.wantEnd, symbol.EndLine)
+			assert.Equal(t, tt.wantDoc, symbol.DocLine)
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package outline
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/RRethy/eddie/internal/cmd/search"
+)
+
+// maxSignatureLength caps the length of a signature in runes.
+const maxSignatureLength = 200
+
+// Symbol is a declaration in a source file. Lines are 1-based and inclusive
+// and cover the whole declaration, including decorators and export or
+// template wrappers. DocLine is the first line of the comments and attributes
+// right above the declaration, or 0 if there are none.
+type Symbol struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Signature string   `json:"signature"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	DocLine   int      `json:"doc_line,omitempty"`
+	Children  []Symbol `json:"children,omitempty"`
+}
+
+// declarationKinds maps the tree-sitter node kinds of every grammar in
+// search.LanguageFromFile that declare something to the kind of symbol they
+// declare. Node kinds shared by several grammars mean the same thing in each.
+var declarationKinds = map[string]string{
+	// Go
+	"function_declaration": "function",
+	"method_declaration":   "method",
+	"type_spec":            "type",
+	"type_alias":           "type",
+
+	// JavaScript and TypeScript
+	"generator_function_declaration": "function",
+	"class_declaration":              "class",
+	"abstract_class_declaration":     "class",
+	"method_definition":              "method",
+	"interface_declaration":          "interface",
+	"type_alias_declaration":         "type",
+	"enum_declaration":               "enum",
+	"variable_declarator":            "function",
+
+	// Python
+	"function_definition": "function",
+	"class_definition":    "class",
+
+	// Rust
+	"function_item":           "function",
+	"function_signature_item": "function",
+	"struct_item":             "struct",
+	"enum_item":               "enum",
+	"union_item":              "union",
+	"trait_item":              "trait",
+	"impl_item":               "impl",
+	"mod_item":                "module",
+	"type_item":               "type",
+
+	// Java
+	"record_declaration":          "class",
+	"constructor_declaration":     "method",
+	"annotation_type_declaration": "interface",
+
+	// C and C++
+	"struct_specifier":     "struct",
+	"union_specifier":      "union",
+	"enum_specifier":       "enum",
+	"class_specifier":      "class",
+	"type_definition":      "type",
+	"namespace_definition": "namespace",
+}
+
+// containerKinds are the symbol kinds whose functions are methods.
+var containerKinds = map[string]bool{
+	"class":     true,
+	"struct":    true,
+	"interface": true,
+	"trait":     true,
+	"impl":      true,
+}
+
+// Parse returns the declarations of a source file, nested the way they are
+// in the file. The language is chosen by the extension of filename.
+func Parse(filename string, content []byte) ([]Symbol, error) {
+	lang := search.LanguageFromFile(filename)
+	if lang == nil {
+		return nil, fmt.Errorf("unsupported language: %s", filename)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+
+	err := parser.SetLanguage(lang)
+	if err != nil {
+		return nil, fmt.Errorf("set language for %s: %w", filename, err)
+	}
+
+	tree := parser.Parse(content, nil)
+	if tree == nil {
+		return nil, fmt.Errorf("failed to parse %s", filename)
+	}
+	defer tree.Close()
+
+	cursor := tree.Walk()
+	defer cursor.Close()
+
+	return collect(tree.RootNode(), content, "", cursor), nil
+}
+
+func collect(node *tree_sitter.Node, content []byte, parentKind string, cursor *tree_sitter.TreeCursor) []Symbol {
+	var symbols []Symbol
+	for _, child := range node.NamedChildren(cursor) {
+		symbol, ok := declaration(&child, content, parentKind)
+		if !ok {
+			symbols = append(symbols, collect(&child, content, parentKind, cursor)...)
+			continue
+		}
+		symbol.Children = collect(&child, content, symbol.Kind, cursor)
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func declaration(node *tree_sitter.Node, content []byte, parentKind string) (Symbol, bool) {
+	kind, ok := declarationKinds[node.Kind()]
+	if !ok {
+		return Symbol{}, false
+	}
+
+	body := node.ChildByFieldName("body")
+	switch node.Kind() {
+	case "variable_declarator":
+		// Only functions assigned to variables are declarations.
+		value := node.ChildByFieldName("value")
+		if value == nil {
+			return Symbol{}, false
+		}
+		switch value.Kind() {
+		case "arrow_function", "function_expression", "function", "generator_function":
+			body = value.ChildByFieldName("body")
+		default:
+			return Symbol{}, false
+		}
+	case "struct_specifier", "union_specifier", "enum_specifier", "class_specifier":
+		// Without a body these only refer to a type declared elsewhere.
+		if body == nil {
+			return Symbol{}, false
+		}
+	}
+
+	name := symbolName(node, content)
+	if name == "" {
+		return Symbol{}, false
+	}
+
+	if kind == "function" && containerKinds[parentKind] {
+		kind = "method"
+	}
+
+	span := spanNode(node)
+	signatureStart := span
+	if span.Kind() == "decorated_definition" {
+		signatureStart = node
+	}
+
+	return Symbol{
+		Name:      name,
+		Kind:      kind,
+		Signature: signature(content, signatureStart.StartByte(), node.EndByte(), body),
+		StartLine: int(span.StartPosition().Row) + 1,
+		EndLine:   int(span.EndPosition().Row) + 1,
+		DocLine:   docLine(span, content),
+	}, true
+}
+
+// docLine returns the first line of the block of comments and attributes
+// that ends on the line right above node, or 0 if there is none. Comments
+// trailing code on their line are not part of the block.
+func docLine(node *tree_sitter.Node, content []byte) int {
+	first := 0
+	row := node.StartPosition().Row
+	for prev := previousNode(node); prev != nil; prev = previousNode(prev) {
+		if !strings.Contains(prev.Kind(), "comment") && prev.Kind() != "attribute_item" {
+			break
+		}
+
+		// Some grammars include the newline ending a line comment.
+		end := prev.EndPosition()
+		if end.Column == 0 && end.Row > 0 {
+			end.Row--
+		}
+		if end.Row+1 != row || !startsLine(prev, content) {
+			break
+		}
+		row = prev.StartPosition().Row
+		first = int(row) + 1
+	}
+	return first
+}
+
+// previousNode returns the named node before node, looking past the start of
+// blocks that begin with node, where grammars such as Python's leave the
+// comments above the first statement.
+func previousNode(node *tree_sitter.Node) *tree_sitter.Node {
+	for {
+		if prev := node.PrevNamedSibling(); prev != nil {
+			return prev
+		}
+		parent := node.Parent()
+		if parent == nil || parent.StartByte() != node.StartByte() {
+			return nil
+		}
+		node = parent
+	}
+}
+
+func startsLine(node *tree_sitter.Node, content []byte) bool {
+	start := node.StartByte()
+	lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+	return len(bytes.TrimSpace(content[lineStart:start])) == 0
+}
+
+// spanNode returns the node covering the whole declaration of node, which
+// can be wrapped in decorators, export statements, templates or a
+// declaration of a single variable or type.
+func spanNode(node *tree_sitter.Node) *tree_sitter.Node {
+	span := node
+	for parent := span.Parent(); parent != nil; parent = span.Parent() {
+		switch parent.Kind() {
+		case "decorated_definition", "template_declaration":
+		case "export_statement", "lexical_declaration", "variable_declaration", "type_declaration":
+			if parent.NamedChildCount() != 1 {
+				return span
+			}
+		default:
+			return span
+		}
+		span = parent
+	}
+	return span
+}
+
+func symbolName(node *tree_sitter.Node, content []byte) string {
+	switch node.Kind() {
+	case "impl_item":
+		if typ := node.ChildByFieldName("type"); typ != nil {
+			return stripTypeArguments(typ.Utf8Text(content))
+		}
+		return ""
+	case "method_declaration":
+		name := node.ChildByFieldName("name")
+		if name == nil {
+			return ""
+		}
+		if receiver := receiverType(node, content); receiver != "" {
+			return receiver + "." + name.Utf8Text(content)
+		}
+		return name.Utf8Text(content)
+	}
+
+	if name := node.ChildByFieldName("name"); name != nil {
+		return name.Utf8Text(content)
+	}
+	if declarator := node.ChildByFieldName("declarator"); declarator != nil {
+		return declaratorName(declarator, content)
+	}
+	return ""
+}
+
+// declaratorName finds the name in a C or C++ declarator, which can be
+// nested in pointer, reference and function declarators.
+func declaratorName(declarator *tree_sitter.Node, content []byte) string {
+	for declarator != nil {
+		switch declarator.Kind() {
+		case "identifier", "field_identifier", "type_identifier", "qualified_identifier",
+			"destructor_name", "operator_name", "primitive_type":
+			return declarator.Utf8Text(content)
+		}
+		next := declarator.ChildByFieldName("declarator")
+		if next == nil && declarator.NamedChildCount() > 0 {
+			next = declarator.NamedChild(0)
+		}
+		declarator = next
+	}
+	return ""
+}
+
+// receiverType returns the type of a Go method receiver without pointers or
+// type parameters.
+func receiverType(node *tree_sitter.Node, content []byte) string {
+	receiver := node.ChildByFieldName("receiver")
+	if receiver == nil || receiver.NamedChildCount() == 0 {
+		return ""
+	}
+	typ := receiver.NamedChild(0).ChildByFieldName("type")
+	if typ == nil {
+		return ""
+	}
+	return stripTypeArguments(strings.TrimLeft(typ.Utf8Text(content), "*"))
+}
+
+func stripTypeArguments(name string) string {
+	if i := strings.IndexAny(name, "[<"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// signature returns the text of the declaration between start and end up to
+// its body, or its first line if it has no body, on one line.
+func signature(content []byte, start, end uint, body *tree_sitter.Node) string {
+	var text string
+	if body != nil && body.StartByte() > start {
+		text = string(content[start:body.StartByte()])
+	} else {
+		text = string(content[start:end])
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+		}
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.TrimSpace(strings.TrimSuffix(text, "{"))
+
+	runes := []rune(text)
+	if len(runes) > maxSignatureLength {
+		text = string(runes[:maxSignatureLength]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,209 @@
+package outline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flatten lists symbols depth first as "kind name start-end", indented by
+// depth.
+func flatten(symbols []Symbol, indent string) []string {
+	var out []string
+	for _, s := range symbols {
+		out = append(out, fmt.Sprintf("%s%s %s %d-%d", indent, s.Kind, s.Name, s.StartLine, s.EndLine))
+		out = append(out, flatten(s.Children, indent+"  ")...)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     []string
+	}{
+		{
+			name:     "go",
+			filename: "main.go",
+			content: `package main
+
+// Server serves.
+type Server struct {
+	addr string
+}
+
+type (
+	A int
+	B string
+)
+
+func (s *Server) Start() error {
+	return nil
+}
+
+func (l List[T]) Len() int { return 0 }
+
+func main() {}
+`,
+			want: []string{
+				"type Server 4-6",
+				"type A 9-9",
+				"type B 10-10",
+				"method Server.Start 13-15",
+				"method List.Len 17-17",
+				"function main 19-19",
+			},
+		},
+		{
+			name:     "python",
+			filename: "app.py",
+			content: `@route("/")
+def index():
+    pass
+
+class Foo(Base):
+    def method(self):
+        def inner():
+            pass
+`,
+			want: []string{
+				"function index 1-3",
+				"class Foo 5-8",
+				"  method method 6-8",
+				"    function inner 7-8",
+			},
+		},
+		{
+			name:     "typescript",
+			filename: "app.ts",
+			content: `export function hello(name: string): string {
+  return name;
+}
+export const arrow = (x: number) => x * 2;
+const value = 3;
+interface Shape { area(): number; }
+export class Box extends Base {
+  area(): number {
+    return 1;
+  }
+}
+`,
+			want: []string{
+				"function hello 1-3",
+				"function arrow 4-4",
+				"interface Shape 6-6",
+				"class Box 7-11",
+				"  method area 8-10",
+			},
+		},
+		{
+			name:     "rust",
+			filename: "lib.rs",
+			content: `struct Point { x: i32 }
+trait Shape {
+    fn area(&self) -> f64;
+}
+impl<T> Shape for Wrapper<T> {
+    fn area(&self) -> f64 {
+        0.0
+    }
+}
+`,
+			want: []string{
+				"struct Point 1-1",
+				"trait Shape 2-4",
+				"  method area 3-3",
+				"impl Wrapper 5-9",
+				"  method area 6-8",
+			},
+		},
+		{
+			name:     "java",
+			filename: "A.java",
+			content: `public class A {
+    public A() {}
+    public int getX() {
+        return 0;
+    }
+}
+`,
+			want: []string{
+				"class A 1-6",
+				"  method A 2-2",
+				"  method getX 3-5",
+			},
+		},
+		{
+			name:     "c",
+			filename: "main.c",
+			content: `struct point { int x; };
+typedef struct { int y; } pt;
+struct point *make(int n) {
+    return 0;
+}
+`,
+			want: []string{
+				"struct point 1-1",
+				"type pt 2-2",
+				"function make 3-5",
+			},
+		},
+		{
+			name:     "cpp",
+			filename: "main.cpp",
+			content: `namespace ns {
+class Foo {
+    void bar() {}
+};
+template <typename T>
+T id(T v) { return v; }
+}
+`,
+			want: []string{
+				"namespace ns 1-7",
+				"  class Foo 2-4",
+				"    method bar 3-3",
+				"  function id 5-6",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols, err := Parse(tt.filename, []byte(tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, flatten(symbols, ""))
+		})
+	}
+}
+
+func TestParse_Signature(t *testing.T) {
+	content := `package main
+
+func Long(
+	a int,
+	b string,
+) (int, error) {
+	return 0, nil
+}
+
+type Server struct {
+	addr string
+}
+`
+	symbols, err := Parse("main.go", []byte(content))
+	require.NoError(t, err)
+	require.Len(t, symbols, 2)
+	assert.Equal(t, "func Long( a int, b string, ) (int, error)", symbols[0].Signature)
+	assert.Equal(t, "type Server struct", symbols[1].Signature)
+}
+
+func TestParse_UnsupportedLanguage(t *testing.T) {
+	_, err := Parse("notes.txt", []byte("hello"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported language")
+}
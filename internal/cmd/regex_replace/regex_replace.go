@@ -0,0 +1,12 @@
+package regex_replace
+
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func RegexReplace(path, pattern, replacement string, multiline, ignoreCase bool, maxReplacements int, showChanges, showResult bool, pre fileops.Precondition) error {
+	_, err := NewReplacer(os.Stdout).RegexReplace(path, pattern, replacement, multiline, ignoreCase, maxReplacements, showChanges, showResult, pre)
+	return err
+}
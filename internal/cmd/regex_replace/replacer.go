@@ -0,0 +1,132 @@
+package regex_replace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+type Replacer struct {
+	fileOps *fileops.FileOps
+	out     io.Writer
+}
+
+func NewReplacer(w io.Writer) *Replacer {
+	return &Replacer{
+		fileOps: &fileops.FileOps{},
+		out:     w,
+	}
+}
+
+// RegexReplace replaces matches of the RE2 pattern with replacement, which may
+// reference capture groups as $1 or ${name}. A maxReplacements of zero or less
+// replaces every match.
+func (r *Replacer) RegexReplace(path, pattern, replacement string, multiline, ignoreCase bool, maxReplacements int, showChanges, showResult bool, pre fileops.Precondition) (*output.Edit, error) {
+	re, err := r.compile(pattern, multiline, ignoreCase)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, info, err := r.fileOps.ReadFileContentForOperation(path, "replace regex matches in")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pre.Check(path, raw, info); err != nil {
+		return nil, err
+	}
+
+	original, format, err := fileops.DecodeText(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	modified, count := r.replace(re, original, replacement, maxReplacements)
+
+	res := &output.Edit{Command: "regex_replace", Path: path, Count: count}
+	if count == 0 {
+		res.Summary = fmt.Sprintf("No matches of %q found in %s", pattern, path)
+		return res, output.Print(r.out, res)
+	}
+
+	if original == modified {
+		res.Summary = fmt.Sprintf("Matched %d time(s) but content of %s is unchanged", count, path)
+		return res, output.Print(r.out, res)
+	}
+
+	encoded, err := format.EncodeText(modified)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	err = r.fileOps.WriteFileContent(path, encoded, info.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
+	err = undoEditor.RecordEdit(path, "regex_replace", raw, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("record edit: %w", err)
+	}
+
+	if showChanges {
+		diff := display.Diff(path, original, modified)
+		res.Diff = &diff
+	}
+	if showResult {
+		res.Content = &modified
+	}
+	res.Summary = fmt.Sprintf("Replaced %d match(es) of %q with %q in %s", count, pattern, replacement, path)
+	return res, output.Print(r.out, res)
+}
+
+func (r *Replacer) compile(pattern string, multiline, ignoreCase bool) (*regexp.Regexp, error) {
+	var flags string
+	if multiline {
+		flags += "m"
+	}
+	if ignoreCase {
+		flags += "i"
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile pattern: %w", err)
+	}
+	return re, nil
+}
+
+func (r *Replacer) replace(re *regexp.Regexp, content, replacement string, maxReplacements int) (string, int) {
+	n := -1
+	if maxReplacements > 0 {
+		n = maxReplacements
+	}
+
+	matches := re.FindAllStringSubmatchIndex(content, n)
+	if len(matches) == 0 {
+		return content, 0
+	}
+
+	var b strings.Builder
+	b.Grow(len(content))
+	last := 0
+	for _, m := range matches {
+		b.WriteString(content[last:m[0]])
+		b.Write(re.ExpandString(nil, replacement, content, m))
+		last = m[1]
+	}
+	b.WriteString(content[last:])
+
+	return b.String(), len(matches)
+}
@@ -0,0 +1,57 @@
+package regex_replace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func BenchmarkReplacer_RegexReplace(b *testing.B) {
+	tmpDir := b.TempDir()
+
+	sizes := []struct {
+		name  string
+		lines int
+	}{
+		{"small", 100},
+		{"medium", 10000},
+		{"large", 100000},
+	}
+
+	for _, size := range sizes {
+		b.Run(size.name, func(b *testing.B) {
+			content := strings.Repeat("result := fooV1(a, b)\n", size.lines)
+			r := &Replacer{}
+
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				testFile := filepath.Join(tmpDir, fmt.Sprintf("bench_%s_%d.txt", size.name, n))
+				if err := os.WriteFile(testFile, []byte(content), 0o644); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				_, err := r.RegexReplace(testFile, `fooV1\((.*)\)`, "fooV2($1)", false, false, 0, false, false, fileops.Precondition{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReplacer_replace(b *testing.B) {
+	content := strings.Repeat("result := fooV1(a, b)\n", 10000)
+	re := regexp.MustCompile(`fooV1\((.*)\)`)
+	r := &Replacer{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _ = r.replace(re, content, "fooV2($1)", 0)
+	}
+}
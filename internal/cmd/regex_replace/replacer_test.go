@@ -0,0 +1,128 @@
+package regex_replace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func TestReplacer_RegexReplace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name            string
+		content         string
+		pattern         string
+		replacement     string
+		wantContent     string
+		maxReplacements int
+		multiline       bool
+		ignoreCase      bool
+	}{
+		{
+			name:        "numbered capture group",
+			content:     "fooV1(a, b)\nfooV1(c)\n",
+			pattern:     `fooV1\(`,
+			replacement: "fooV2(",
+			wantContent: "fooV2(a, b)\nfooV2(c)\n",
+		},
+		{
+			name:        "keep arguments",
+			content:     "x := fooV1(a, b)\n",
+			pattern:     `fooV1\((.*)\)`,
+			replacement: "fooV2($1)",
+			wantContent: "x := fooV2(a, b)\n",
+		},
+		{
+			name:        "named capture group",
+			content:     "key=value\n",
+			pattern:     `(?P<k>\w+)=(?P<v>\w+)`,
+			replacement: "${v}=${k}",
+			wantContent: "value=key\n",
+		},
+		{
+			name:        "multiline anchors",
+			content:     "todo one\ntodo two\n",
+			pattern:     `^todo`,
+			replacement: "TODO",
+			multiline:   true,
+			wantContent: "TODO one\nTODO two\n",
+		},
+		{
+			name:        "anchors without multiline",
+			content:     "todo one\ntodo two\n",
+			pattern:     `^todo`,
+			replacement: "TODO",
+			wantContent: "TODO one\ntodo two\n",
+		},
+		{
+			name:        "ignore case",
+			content:     "Hello HELLO hello",
+			pattern:     `hello`,
+			replacement: "hi",
+			ignoreCase:  true,
+			wantContent: "hi hi hi",
+		},
+		{
+			name:            "max replacements",
+			content:         "a a a a",
+			pattern:         `a`,
+			replacement:     "b",
+			maxReplacements: 2,
+			wantContent:     "b b a a",
+		},
+		{
+			name:        "no matches",
+			content:     "hello",
+			pattern:     `xyz`,
+			replacement: "abc",
+			wantContent: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, "test_"+tt.name+".txt")
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			r := &Replacer{}
+			_, err := r.RegexReplace(testFile, tt.pattern, tt.replacement, tt.multiline, tt.ignoreCase, tt.maxReplacements, false, false, fileops.Precondition{})
+			require.NoError(t, err)
+
+			result, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(result))
+		})
+	}
+}
+
+func TestReplacer_RegexReplace_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("content"), 0o644))
+
+	tests := []struct {
+		name    string
+		path    string
+		pattern string
+		wantErr string
+	}{
+		{"invalid pattern", testFile, `(unclosed`, "compile pattern"},
+		{"nonexistent file", "/nonexistent/file.txt", `a`, "stat"},
+		{"directory instead of file", tmpDir, `a`, "cannot replace regex matches in directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Replacer{}
+			_, err := r.RegexReplace(tt.path, tt.pattern, "b", false, false, 0, false, false, fileops.Precondition{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package replace_lines
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RRethy/eddie/internal/cmd/undo_edit"
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+type LineReplacer struct {
+	fileOps *fileops.FileOps
+	out     io.Writer
+}
+
+func NewLineReplacer(w io.Writer) *LineReplacer {
+	return &LineReplacer{
+		fileOps: &fileops.FileOps{},
+		out:     w,
+	}
+}
+
+// ReplaceLines replaces lines startLine through endLine (1-based, inclusive)
+// with the lines of newStr. An endLine of -1 means the last line of the file.
+// An empty newStr removes the lines; use "\n" to leave a single blank line.
+func (r *LineReplacer) ReplaceLines(path string, startLine, endLine int, newStr string, showChanges, showResult bool, pre fileops.Precondition) (*output.Edit, error) {
+	raw, info, err := r.fileOps.ReadFileContentForOperation(path, "replace lines in")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pre.Check(path, raw, info); err != nil {
+		return nil, err
+	}
+
+	original, format, err := fileops.DecodeText(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	newStr = format.NormalizeText(newStr)
+
+	modified, hunk, err := r.replaceLines(original, startLine, endLine, newStr)
+	if err != nil {
+		return nil, fmt.Errorf("replace lines: %w", err)
+	}
+
+	encoded, err := format.EncodeText(modified)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	err = r.fileOps.WriteFileContent(path, encoded, info.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
+	err = undoEditor.RecordEdit(path, "replace_lines", raw, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("record edit: %w", err)
+	}
+
+	res := &output.Edit{Command: "replace_lines", Path: path, Count: hunk.OldCount}
+	if showChanges {
+		diff := display.Diff(path, original, modified)
+		res.Diff = &diff
+	}
+	if showResult {
+		res.Content = &modified
+	}
+	res.Summary = fmt.Sprintf("Replaced lines %d-%d (%d line(s)) with %d line(s) in %s",
+		hunk.Start, hunk.Start+hunk.OldCount-1, hunk.OldCount, hunk.NewCount, path)
+	return res, output.Print(r.out, res)
+}
+
+func (r *LineReplacer) replaceLines(content string, startLine, endLine int, newStr string) (string, fileops.LineHunk, error) {
+	lines, hasTrailingNewline := fileops.SplitLines(content)
+
+	if startLine < 1 {
+		return "", fileops.LineHunk{}, fmt.Errorf("start line must be >= 1, got %d", startLine)
+	}
+	if endLine == -1 {
+		endLine = len(lines)
+	}
+	if endLine < startLine {
+		return "", fileops.LineHunk{}, fmt.Errorf("start line %d is greater than end line %d", startLine, endLine)
+	}
+	if endLine > len(lines) {
+		return "", fileops.LineHunk{}, fmt.Errorf("line range %d-%d exceeds file length (%d lines)", startLine, endLine, len(lines))
+	}
+
+	newLines, _ := fileops.SplitLines(newStr)
+
+	result := make([]string, 0, len(lines)-(endLine-startLine+1)+len(newLines))
+	result = append(result, lines[:startLine-1]...)
+	result = append(result, newLines...)
+	result = append(result, lines[endLine:]...)
+
+	hunk := fileops.LineHunk{
+		Start:    startLine,
+		OldCount: endLine - startLine + 1,
+		NewCount: len(newLines),
+	}
+	return fileops.JoinLines(result, hasTrailingNewline), hunk, nil
+}
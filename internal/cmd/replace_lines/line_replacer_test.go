@@ -0,0 +1,139 @@
+package replace_lines
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func TestLineReplacer_replaceLines(t *testing.T) {
+	r := &LineReplacer{}
+
+	tests := []struct {
+		name      string
+		content   string
+		newStr    string
+		want      string
+		wantErr   string
+		startLine int
+		endLine   int
+	}{
+		{
+			name:      "replace with more lines",
+			content:   "a\nb\nc\nd\n",
+			startLine: 2,
+			endLine:   3,
+			newStr:    "X\nY\nZ",
+			want:      "a\nX\nY\nZ\nd\n",
+		},
+		{
+			name:      "replace with fewer lines",
+			content:   "a\nb\nc\nd\n",
+			startLine: 1,
+			endLine:   3,
+			newStr:    "X",
+			want:      "X\nd\n",
+		},
+		{
+			name:      "trailing newline in new string is not an extra line",
+			content:   "a\nb\n",
+			startLine: 2,
+			endLine:   2,
+			newStr:    "X\n",
+			want:      "a\nX\n",
+		},
+		{
+			name:      "empty new string removes lines",
+			content:   "a\nb\nc\n",
+			startLine: 2,
+			endLine:   2,
+			newStr:    "",
+			want:      "a\nc\n",
+		},
+		{
+			name:      "newline leaves blank line",
+			content:   "a\nb\nc\n",
+			startLine: 2,
+			endLine:   2,
+			newStr:    "\n",
+			want:      "a\n\nc\n",
+		},
+		{
+			name:      "end of file",
+			content:   "a\nb\nc\n",
+			startLine: 2,
+			endLine:   -1,
+			newStr:    "X",
+			want:      "a\nX\n",
+		},
+		{
+			name:      "no trailing newline preserved",
+			content:   "a\nb",
+			startLine: 2,
+			endLine:   2,
+			newStr:    "X",
+			want:      "a\nX",
+		},
+		{
+			name:      "range past end",
+			content:   "a\nb\n",
+			startLine: 2,
+			endLine:   3,
+			newStr:    "X",
+			wantErr:   "exceeds file length",
+		},
+		{
+			name:      "start after end",
+			content:   "a\nb\n",
+			startLine: 2,
+			endLine:   1,
+			newStr:    "X",
+			wantErr:   "greater than end line",
+		},
+		{
+			name:      "zero start",
+			content:   "a\nb\n",
+			startLine: 0,
+			endLine:   1,
+			newStr:    "X",
+			wantErr:   "must be >= 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := r.replaceLines(tt.content, tt.startLine, tt.endLine, tt.newStr)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLineReplacer_ReplaceLines(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("a\nb\nc\n"), 0o644))
+
+	r := &LineReplacer{}
+	_, err := r.ReplaceLines(testFile, 2, 2, "X\nY", false, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nX\nY\nc\n", string(content))
+
+	_, err = r.ReplaceLines(tmpDir, 1, 1, "X", false, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot replace lines in directory")
+}
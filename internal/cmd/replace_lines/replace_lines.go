@@ -0,0 +1,12 @@
+package replace_lines
+
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func ReplaceLines(path string, startLine, endLine int, newStr string, showChanges, showResult bool, pre fileops.Precondition) error {
+	_, err := NewLineReplacer(os.Stdout).ReplaceLines(path, startLine, endLine, newStr, showChanges, showResult, pre)
+	return err
+}
@@ -0,0 +1,315 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+// GrepOptions are the options of a plain-text search. Pattern is a Go
+// regular expression, or a literal string if Literal is set. Before and
+// After are the number of lines of context printed around each match.
+// Include and Exclude are globs in gitignore syntax: if there are include
+// globs only files that match one are searched, and files and directories
+// that match an exclude glob are skipped. FilesWithMatches prints only the
+// files that match and Count the number of matching lines in each.
+type GrepOptions struct {
+	Pattern          string
+	Literal          bool
+	IgnoreCase       bool
+	WordRegexp       bool
+	Before           int
+	After            int
+	Include          []string
+	Exclude          []string
+	FilesWithMatches bool
+	Count            bool
+}
+
+// GrepLine is a line printed by Grep: a line that matches, with the column
+// of its first match, or a line of context around one.
+type GrepLine struct {
+	Number  int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Text    string `json:"text"`
+	Context bool   `json:"context,omitempty"`
+}
+
+// GrepFile is a file that matches, with the number of lines that match and
+// the lines printed for it.
+type GrepFile struct {
+	Path  string     `json:"path"`
+	Count int        `json:"count"`
+	Lines []GrepLine `json:"lines,omitempty"`
+
+	// warning is set instead if the file could not be read.
+	warning string
+}
+
+// GrepResult is the result of Grep, in the order the files were walked.
+// Warnings are the files and directories that could not be read.
+type GrepResult struct {
+	Pattern  string     `json:"pattern"`
+	Files    []GrepFile `json:"files"`
+	Warnings []string   `json:"warnings,omitempty"`
+
+	filesWithMatches bool
+	count            bool
+	context          bool
+}
+
+func (r *GrepResult) String() string {
+	var b strings.Builder
+	for i, file := range r.Files {
+		switch {
+		case r.filesWithMatches:
+			b.WriteString(file.Path + "\n")
+		case r.count:
+			fmt.Fprintf(&b, "%s:%d\n", file.Path, file.Count)
+		default:
+			if i > 0 && r.context {
+				b.WriteString("--\n")
+			}
+			writeGrepLines(&b, file, r.context)
+		}
+	}
+	for _, warning := range r.Warnings {
+		b.WriteString("Warning: " + warning + "\n")
+	}
+	return b.String()
+}
+
+// writeGrepLines writes the lines of file the way grep does, matches as
+// path:line:column:text and context as path-line-text. With context, "--"
+// separates lines that are not next to each other.
+func writeGrepLines(b *strings.Builder, file GrepFile, context bool) {
+	for i, line := range file.Lines {
+		if context && i > 0 && line.Number != file.Lines[i-1].Number+1 {
+			b.WriteString("--\n")
+		}
+		if line.Context {
+			fmt.Fprintf(b, "%s-%d-%s\n", file.Path, line.Number, line.Text)
+		} else {
+			fmt.Fprintf(b, "%s:%d:%d:%s\n", file.Path, line.Number, line.Column, line.Text)
+		}
+	}
+}
+
+// Grep prints the lines that match opts.Pattern in path, or in every text
+// file under path that walk does not ignore if it is a directory. Like
+// Search, files are searched in parallel but printed in walk order. Binary
+// files and files over the size limit are skipped in a directory and
+// refused if path is one. Files and directories that cannot be read are
+// skipped with a warning in a directory, and fail the search if path is one.
+func (s *Searcher) Grep(path string, opts GrepOptions, walk ignore.Options) (*GrepResult, error) {
+	re, err := grepRegexp(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Before < 0 || opts.After < 0 {
+		return nil, fmt.Errorf("context must be >= 0")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	var files, warnings []string
+	if info.IsDir() {
+		files, warnings, err = grepFiles(path, opts, walk)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files = []string{path}
+	}
+
+	g := &grepper{re: re, opts: opts, skip: info.IsDir()}
+	results, err := parallel(s.workers, files, func() fileWorker[string, *GrepFile] {
+		return g
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GrepResult{
+		Pattern:          opts.Pattern,
+		Files:            []GrepFile{},
+		Warnings:         warnings,
+		filesWithMatches: opts.FilesWithMatches,
+		count:            opts.Count,
+		context:          opts.Before > 0 || opts.After > 0,
+	}
+	for _, file := range results {
+		switch {
+		case file == nil:
+		case file.warning != "":
+			res.Warnings = append(res.Warnings, file.warning)
+		default:
+			res.Files = append(res.Files, *file)
+		}
+	}
+	return res, output.Print(s.out, res)
+}
+
+// grepRegexp compiles the pattern of opts.
+func grepRegexp(opts GrepOptions) (*regexp.Regexp, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("pattern must not be empty")
+	}
+
+	expr := opts.Pattern
+	if opts.Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.WordRegexp {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", opts.Pattern, err)
+	}
+	return re, nil
+}
+
+// grepFiles returns the files under dir that walk does not ignore and that
+// the include and exclude globs of opts select, in walk order, and warnings
+// for the entries below dir that could not be read.
+func grepFiles(dir string, opts GrepOptions, walk ignore.Options) ([]string, []string, error) {
+	include := ignore.NewGlobs(dir, opts.Include)
+	exclude := ignore.NewGlobs(dir, opts.Exclude)
+
+	var files, warnings []string
+	err := ignore.Walk(dir, walk, func(path string, d fs.DirEntry, err error) error {
+		if err != nil && path == dir {
+			return err
+		}
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if exclude.Match(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if !include.Empty() && !include.Match(path, false) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, warnings, err
+}
+
+// grepper searches files for the lines that match re. It has no state of its
+// own, so every worker shares it.
+type grepper struct {
+	re   *regexp.Regexp
+	opts GrepOptions
+	// skip is set when searching a directory, to leave out binary and
+	// oversized files, and to warn about unreadable ones, instead of failing.
+	skip bool
+}
+
+func (g *grepper) close() {}
+
+// search returns the lines of file that match, or nil if none do.
+func (g *grepper) search(file string) (*GrepFile, error) {
+	lines, err := g.readLines(file)
+	switch {
+	case err == nil:
+	case !g.skip:
+		return nil, err
+	case errors.Is(err, fileops.ErrBinary) || errors.Is(err, fileops.ErrTooLarge):
+		return nil, nil
+	default:
+		// The file may be unreadable or removed since the walk.
+		return &GrepFile{Path: file, warning: err.Error()}, nil
+	}
+
+	res := &GrepFile{Path: file}
+	printed := 0 // lines before this one have been printed
+	after := 0   // lines of context still to print after the last match
+	for i, line := range lines {
+		loc := g.re.FindStringIndex(line)
+		if loc == nil {
+			if after > 0 && !g.summary() {
+				res.Lines = append(res.Lines, GrepLine{Number: i + 1, Text: line, Context: true})
+				printed = i + 1
+				after--
+			}
+			continue
+		}
+
+		res.Count++
+		if g.opts.FilesWithMatches {
+			return res, nil
+		}
+		if g.summary() {
+			continue
+		}
+		for j := max(printed, i-g.opts.Before); j < i; j++ {
+			res.Lines = append(res.Lines, GrepLine{Number: j + 1, Text: lines[j], Context: true})
+		}
+		res.Lines = append(res.Lines, GrepLine{Number: i + 1, Column: loc[0] + 1, Text: line})
+		printed = i + 1
+		after = g.opts.After
+	}
+
+	if res.Count == 0 {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// summary reports whether only the files or counts are printed, not lines.
+func (g *grepper) summary() bool {
+	return g.opts.FilesWithMatches || g.opts.Count
+}
+
+// readLines returns the lines of file decoded to UTF-8, failing if it is a
+// binary file or over the size limit.
+func (g *grepper) readLines(file string) ([]string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", file, err)
+	}
+	if err := fileops.CheckSize(file, info.Size()); err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	if !fileops.AllowBinary && fileops.LooksBinary(raw[:min(len(raw), fileops.SniffLen)]) {
+		return nil, fmt.Errorf("%w: refusing to search %s (see --allow-binary)", fileops.ErrBinary, file)
+	}
+
+	text, err := fileops.DetectFormat(raw).Decode(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", file, err)
+	}
+	return splitLines(text), nil
+}
@@ -0,0 +1,213 @@
+package search
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestSearcher_Grep(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "notes.txt")
+	writeFiles(t, tmpDir, map[string]string{
+		"notes.txt": "one\nTODO: two\nthree\nfour\nfive\nsix todo\nTODOs\nx.y\n",
+	})
+
+	tests := []struct {
+		name     string
+		opts     GrepOptions
+		expected string
+	}{
+		{
+			name:     "regex",
+			opts:     GrepOptions{Pattern: "TODO:?"},
+			expected: file + ":2:1:TODO: two\n" + file + ":7:1:TODOs\n",
+		},
+		{
+			name:     "ignore case",
+			opts:     GrepOptions{Pattern: "todo", IgnoreCase: true, WordRegexp: true},
+			expected: file + ":2:1:TODO: two\n" + file + ":6:5:six todo\n",
+		},
+		{
+			name:     "literal",
+			opts:     GrepOptions{Pattern: "x.y", Literal: true},
+			expected: file + ":8:1:x.y\n",
+		},
+		{
+			name: "context",
+			opts: GrepOptions{Pattern: "two|six", Before: 1, After: 1},
+			expected: file + "-1-one\n" + file + ":2:7:TODO: two\n" + file + "-3-three\n" +
+				"--\n" +
+				file + "-5-five\n" + file + ":6:1:six todo\n" + file + "-7-TODOs\n",
+		},
+		{
+			name:     "overlapping context",
+			opts:     GrepOptions{Pattern: "three|five", After: 2},
+			expected: file + ":3:1:three\n" + file + "-4-four\n" + file + ":5:1:five\n" + file + "-6-six todo\n" + file + "-7-TODOs\n",
+		},
+		{
+			name:     "count",
+			opts:     GrepOptions{Pattern: "o", Count: true},
+			expected: file + ":4\n",
+		},
+		{
+			name:     "files with matches",
+			opts:     GrepOptions{Pattern: "o", FilesWithMatches: true},
+			expected: file + "\n",
+		},
+		{
+			name:     "no match",
+			opts:     GrepOptions{Pattern: "missing"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := NewSearcher(&out)
+			res, err := s.Grep(file, tt.opts, ignore.Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, out.String())
+			assert.Equal(t, tt.expected, res.String())
+		})
+	}
+}
+
+func TestSearcher_Grep_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"a.txt": "a\n"})
+	s := &Searcher{}
+
+	_, err := s.Grep(tmpDir, GrepOptions{}, ignore.Options{})
+	assert.Error(t, err)
+
+	_, err = s.Grep(tmpDir, GrepOptions{Pattern: "("}, ignore.Options{})
+	assert.ErrorContains(t, err, "invalid pattern")
+
+	_, err = s.Grep(tmpDir, GrepOptions{Pattern: "a", After: -1}, ignore.Options{})
+	assert.Error(t, err)
+
+	_, err = s.Grep(filepath.Join(tmpDir, "missing"), GrepOptions{Pattern: "a"}, ignore.Options{})
+	assert.Error(t, err)
+}
+
+func TestSearcher_GrepDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		".gitignore":        "gen/\n",
+		"a.go":              "// TODO a\n",
+		"b.md":              "TODO b\n",
+		"src/c.go":          "// TODO c\n",
+		"src/c_test.go":     "// TODO test\n",
+		"gen/d.go":          "// TODO gen\n",
+		"vendor/e.go":       "// TODO vendor\n",
+		"image.png":         "TODO\x00\x01\x02",
+		"node_modules/f.js": "// TODO js\n",
+	})
+
+	paths := func(res *GrepResult) []string {
+		var paths []string
+		for _, file := range res.Files {
+			rel, err := filepath.Rel(tmpDir, file.Path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return paths
+	}
+
+	s := &Searcher{}
+	res, err := s.Grep(tmpDir, GrepOptions{Pattern: "TODO"}, ignore.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "b.md", "src/c.go", "src/c_test.go", "vendor/e.go"}, paths(res))
+
+	res, err = s.Grep(tmpDir, GrepOptions{Pattern: "TODO", Include: []string{"*.go"}, Exclude: []string{"vendor/", "*_test.go"}}, ignore.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "src/c.go"}, paths(res))
+
+	res, err = s.Grep(tmpDir, GrepOptions{Pattern: "TODO", Include: []string{"/*.go"}}, ignore.Options{NoIgnore: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go"}, paths(res))
+
+	res, err = s.Grep(tmpDir, GrepOptions{Pattern: "TODO", Include: []string{"*.go", "*.js"}}, ignore.Options{NoIgnore: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "gen/d.go", "node_modules/f.js", "src/c.go", "src/c_test.go", "vendor/e.go"}, paths(res))
+}
+
+func TestSearcher_Grep_Binary(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "image.png")
+	writeFiles(t, tmpDir, map[string]string{"image.png": "TODO\x00\x01\x02"})
+
+	s := &Searcher{}
+	_, err := s.Grep(file, GrepOptions{Pattern: "TODO"}, ignore.Options{})
+	assert.ErrorIs(t, err, fileops.ErrBinary)
+
+	res, err := s.Grep(tmpDir, GrepOptions{Pattern: "TODO"}, ignore.Options{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Files)
+}
+
+func TestSearcher_Grep_Unreadable(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"a.txt": "TODO a\n", "b.txt": "TODO b\n"})
+	b := filepath.Join(tmpDir, "b.txt")
+
+	// A file removed after the walk found it is skipped with a warning.
+	g := &grepper{re: regexp.MustCompile("TODO"), skip: true}
+	file, err := g.search(filepath.Join(tmpDir, "removed.txt"))
+	require.NoError(t, err)
+	require.NotNil(t, file)
+	assert.Contains(t, file.warning, "removed.txt")
+
+	g.skip = false
+	_, err = g.search(filepath.Join(tmpDir, "removed.txt"))
+	assert.Error(t, err)
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without read permission")
+	}
+	require.NoError(t, os.Chmod(b, 0o000))
+	defer os.Chmod(b, 0o644)
+
+	s := &Searcher{}
+	res, err := s.Grep(tmpDir, GrepOptions{Pattern: "TODO"}, ignore.Options{})
+	require.NoError(t, err)
+	require.Len(t, res.Files, 1)
+	assert.Equal(t, filepath.Join(tmpDir, "a.txt"), res.Files[0].Path)
+	require.Len(t, res.Warnings, 1)
+	assert.Contains(t, res.String(), "Warning: ")
+
+	_, err = s.Grep(b, GrepOptions{Pattern: "TODO"}, ignore.Options{})
+	assert.Error(t, err)
+}
+
+func TestSearcher_Grep_CRLF(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "win.txt")
+	writeFiles(t, tmpDir, map[string]string{"win.txt": "alpha\r\nbeta\r\n"})
+
+	s := &Searcher{}
+	res, err := s.Grep(file, GrepOptions{Pattern: "beta$"}, ignore.Options{})
+	require.NoError(t, err)
+	require.Len(t, res.Files, 1)
+	assert.Equal(t, []GrepLine{{Number: 2, Column: 1, Text: "beta"}}, res.Files[0].Lines)
+}
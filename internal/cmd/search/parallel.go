@@ -0,0 +1,60 @@
+package search
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// fileWorker searches files one at a time, keeping whatever state it can
+// reuse from one file to the next until it is closed.
+type fileWorker[F, R any] interface {
+	search(file F) (R, error)
+	close()
+}
+
+// parallel searches files with a bounded pool of workers, GOMAXPROCS of them
+// if workers is zero, and returns their results in the order of files. If
+// several files fail, the error of the first one is returned, and no more
+// files are handed out once one has failed.
+func parallel[F, R any](workers int, files []F, newWorker func() fileWorker[F, R]) ([]R, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(files))
+
+	results := make([]R, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := newWorker()
+			defer w.close()
+			for i := range jobs {
+				results[i], errs[i] = w.search(files[i])
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range files {
+		if failed.Load() {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
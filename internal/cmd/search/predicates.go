@@ -0,0 +1,84 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// checkPredicates returns an error if query uses a predicate search cannot
+// evaluate. The text predicates (#eq?, #match?, #any-of? and their not- and
+// any- forms) are evaluated and #set!, #is? and #is-not? only annotate a
+// pattern, but any other predicate would be silently ignored by the cursor
+// and match every node.
+func checkPredicates(query *tree_sitter.Query) error {
+	for i := range query.PatternCount() {
+		if predicates := query.GeneralPredicates(i); len(predicates) > 0 {
+			return fmt.Errorf("unsupported predicate #%s", predicates[0].Operator)
+		}
+	}
+	return nil
+}
+
+// satisfies reports whether match passes the text predicates of its pattern.
+// The cursor already filters matches with them, but it keeps the matches of
+// #any-eq? and #any-match? where no node of a quantified capture passes, so
+// every predicate is checked again here.
+func satisfies(query *tree_sitter.Query, match *tree_sitter.QueryMatch, content []byte) bool {
+	for _, predicate := range query.TextPredicates[match.PatternIndex] {
+		if !satisfiesPredicate(predicate, match, content) {
+			return false
+		}
+	}
+	return true
+}
+
+// satisfiesPredicate reports whether the nodes of match pass predicate. The
+// plain and not- forms must hold for every node of the capture and the any-
+// forms for at least one. A capture without nodes, such as an optional one
+// that is missing, passes.
+func satisfiesPredicate(predicate tree_sitter.TextPredicateCapture, match *tree_sitter.QueryMatch, content []byte) bool {
+	text := func(node tree_sitter.Node) []byte {
+		return content[node.StartByte():node.EndByte()]
+	}
+
+	nodes := match.NodesForCaptureIndex(predicate.CaptureId)
+	var others []tree_sitter.Node
+	if predicate.Type == tree_sitter.TextPredicateTypeEqCapture {
+		// Two captures are compared node by node.
+		others = match.NodesForCaptureIndex(predicate.Value.(uint))
+		if predicate.MatchAllNodes && len(nodes) != len(others) {
+			return false
+		}
+		nodes = nodes[:min(len(nodes), len(others))]
+	}
+	if len(nodes) == 0 {
+		return true
+	}
+
+	for i, node := range nodes {
+		var holds bool
+		switch predicate.Type {
+		case tree_sitter.TextPredicateTypeEqCapture:
+			holds = bytes.Equal(text(node), text(others[i]))
+		case tree_sitter.TextPredicateTypeEqString:
+			holds = string(text(node)) == predicate.Value.(string)
+		case tree_sitter.TextPredicateTypeMatchString:
+			holds = predicate.Value.(*regexp.Regexp).Match(text(node))
+		case tree_sitter.TextPredicateTypeAnyString:
+			holds = slices.Contains(predicate.Value.([]string), string(text(node)))
+		}
+
+		passes := holds == predicate.Positive
+		if passes && !predicate.MatchAllNodes {
+			return true
+		}
+		if !passes && predicate.MatchAllNodes {
+			return false
+		}
+	}
+	return predicate.MatchAllNodes
+}
@@ -0,0 +1,130 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+func TestSearcher_Search_Predicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.go")
+	content := `package main
+
+func TestFoo() {}
+func TestBar() {}
+func helper() {}
+
+// one
+// two
+func main() {
+	x := x
+	y := z
+	helper()
+}
+
+// TODO three
+// four
+func other() {}
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "match",
+			query: `((identifier) @id (#match? @id "^Test"))`,
+			want:  []string{"TestFoo", "TestBar"},
+		},
+		{
+			name:  "not match",
+			query: `((function_declaration name: (identifier) @id) (#not-match? @id "^Test"))`,
+			want:  []string{"helper", "main", "other"},
+		},
+		{
+			name:  "eq string",
+			query: `((identifier) @id (#eq? @id "helper"))`,
+			want:  []string{"helper", "helper"},
+		},
+		{
+			name:  "not eq string",
+			query: `((function_declaration name: (identifier) @id) (#not-eq? @id "main"))`,
+			want:  []string{"TestFoo", "TestBar", "helper", "other"},
+		},
+		{
+			name:  "eq capture",
+			query: `(short_var_declaration left: (expression_list (identifier) @a) right: (expression_list (identifier) @b) (#eq? @a @b))`,
+			want:  []string{"x", "x"},
+		},
+		{
+			name:  "not eq capture",
+			query: `(short_var_declaration left: (expression_list (identifier) @a) right: (expression_list (identifier) @b) (#not-eq? @a @b))`,
+			want:  []string{"y", "z"},
+		},
+		{
+			name:  "any of",
+			query: `((function_declaration name: (identifier) @id) (#any-of? @id "main" "helper"))`,
+			want:  []string{"helper", "main"},
+		},
+		{
+			name:  "not any of",
+			query: `((function_declaration name: (identifier) @id) (#not-any-of? @id "main" "helper"))`,
+			want:  []string{"TestFoo", "TestBar", "other"},
+		},
+		{
+			name:  "match all nodes of a quantified capture",
+			query: `((comment)+ @c (#match? @c "^// (one|two)$"))`,
+			want:  []string{"// one", "// two"},
+		},
+		{
+			name:  "any match",
+			query: `((comment)+ @c (#any-match? @c "TODO"))`,
+			want:  []string{"// TODO three", "// four"},
+		},
+		{
+			name:  "any eq",
+			query: `((comment)+ @c (#any-eq? @c "// two"))`,
+			want:  []string{"// one", "// two"},
+		},
+		{
+			name:  "any not eq",
+			query: `((comment)+ @c (#any-not-eq? @c "// four"))`,
+			want:  []string{"// one", "// two", "// TODO three", "// four"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Searcher{}
+			res, err := s.Search(file, tt.query, SearchOptions{ShowText: true}, ignore.Options{})
+			require.NoError(t, err)
+
+			var got []string
+			for _, match := range res.Matches {
+				got = append(got, match.Text)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSearcher_Search_UnsupportedPredicate(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nvar x = 1\n"), 0o644))
+
+	s := &Searcher{}
+	_, err := s.Search(file, `((identifier) @id (#lua-match? @id "^x"))`, SearchOptions{}, ignore.Options{})
+	assert.ErrorContains(t, err, "unsupported predicate #lua-match?")
+
+	_, err = s.Search(file, `((identifier) @id (#set! "priority" "100"))`, SearchOptions{}, ignore.Options{})
+	assert.NoError(t, err)
+}
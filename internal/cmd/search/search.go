@@ -1,5 +1,17 @@
 package search
 
-func Search(path, query string) error {
-	return (&Searcher{}).Search(path, query)
+import (
+	"os"
+
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+func Search(path, query string, opts SearchOptions, walk ignore.Options) error {
+	_, err := NewSearcher(os.Stdout).Search(path, query, opts, walk)
+	return err
+}
+
+func Grep(path string, opts GrepOptions, walk ignore.Options) error {
+	_, err := NewSearcher(os.Stdout).Grep(path, opts, walk)
+	return err
 }
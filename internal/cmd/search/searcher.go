@@ -1,10 +1,19 @@
 package search
 
 import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
+	"unsafe"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
@@ -15,133 +24,564 @@ import (
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 	tree_sitter_rust "github.com/tree-sitter/tree-sitter-rust/bindings/go"
 	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+
+	"github.com/RRethy/eddie/internal/ignore"
+	"github.com/RRethy/eddie/internal/output"
 )
 
-type Searcher struct{}
+type Searcher struct {
+	out io.Writer
+
+	// workers is the number of files searched at once, GOMAXPROCS if zero.
+	workers int
+}
+
+func NewSearcher(out io.Writer) *Searcher {
+	return &Searcher{out: out}
+}
 
+// SearchOptions controls what Search prints for each captured node. Captures
+// keeps only the captures with these names. ShowText prints the whole text of
+// the node instead of its first line, ShowSpan its end and byte range, and
+// Before and After the lines of context around it. JSON prints the result as
+// JSON whatever the output format.
+type SearchOptions struct {
+	Captures []string
+	ShowText bool
+	ShowSpan bool
+	Before   int
+	After    int
+	JSON     bool
+}
+
+// Match is a node captured by a search query. Line and Column are where the
+// node starts, and EndLine and EndColumn the last line it covers and the
+// column just past it, all 1-based. StartByte and EndByte are its byte range
+// in the file. Content is its first line without surrounding whitespace and
+// Text, if asked for, the whole node. Before and After are the lines of
+// context before the node and after its last printed line. Pattern is the
+// index of the query pattern that captured it.
 type Match struct {
-	File    string
-	Content string
-	Capture string
-	Line    int
-	Column  int
-}
-
-var languageMap = map[string]func() *tree_sitter.Language{
-	".go":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_go.Language()) },
-	".js":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_javascript.Language()) },
-	".mjs": func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_javascript.Language()) },
-	".jsx": func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_javascript.Language()) },
-	".ts": func() *tree_sitter.Language {
-		return tree_sitter.NewLanguage(tree_sitter_typescript.LanguageTypescript())
-	},
-	".tsx":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_typescript.LanguageTSX()) },
-	".py":   func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_python.Language()) },
-	".pyi":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_python.Language()) },
-	".rs":   func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_rust.Language()) },
-	".java": func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_java.Language()) },
-	".c":    func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_c.Language()) },
-	".h":    func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_c.Language()) },
-	".cc":   func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_cpp.Language()) },
-	".cpp":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_cpp.Language()) },
-	".cxx":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_cpp.Language()) },
-	".hpp":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_cpp.Language()) },
-	".hxx":  func() *tree_sitter.Language { return tree_sitter.NewLanguage(tree_sitter_cpp.Language()) },
-}
-
-func getLanguageFromFile(filename string) *tree_sitter.Language {
-	ext := strings.ToLower(filepath.Ext(filename))
-	if langFunc, exists := languageMap[ext]; exists {
-		return langFunc()
+	File      string   `json:"file"`
+	Content   string   `json:"content"`
+	Capture   string   `json:"capture"`
+	Pattern   int      `json:"pattern"`
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	EndLine   int      `json:"end_line"`
+	EndColumn int      `json:"end_column"`
+	StartByte int      `json:"start_byte"`
+	EndByte   int      `json:"end_byte"`
+	Text      string   `json:"text,omitempty"`
+	Before    []string `json:"before,omitempty"`
+	After     []string `json:"after,omitempty"`
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%s:%d:%d: @%s: %s", m.File, m.Line, m.Column, m.Capture, m.Content)
+}
+
+// spanString is like String with the end and byte range of the node.
+func (m Match) spanString() string {
+	return fmt.Sprintf("%s:%d:%d-%d:%d (bytes %d-%d): @%s: %s",
+		m.File, m.Line, m.Column, m.EndLine, m.EndColumn, m.StartByte, m.EndByte, m.Capture, m.Content)
+}
+
+// Result is the result of Search. Patterns are the patterns of the query, by
+// index. Matches are grouped by pattern and then in the order the files were
+// searched.
+type Result struct {
+	Query    string   `json:"query"`
+	Patterns []string `json:"patterns,omitempty"`
+	Matches  []Match  `json:"matches"`
+
+	opts SearchOptions
+}
+
+func (r *Result) String() string {
+	if r.opts.JSON {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("Error: %v\n", err)
+		}
+		return string(data) + "\n"
+	}
+
+	block := r.opts.ShowText || r.opts.Before > 0 || r.opts.After > 0
+	grouped := len(r.Patterns) > 1
+	var b strings.Builder
+	for i, match := range r.Matches {
+		newGroup := grouped && (i == 0 || match.Pattern != r.Matches[i-1].Pattern)
+		if newGroup {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			first, _, _ := strings.Cut(r.Patterns[match.Pattern], "\n")
+			fmt.Fprintf(&b, "pattern %d: %s\n", match.Pattern, first)
+		} else if block && i > 0 {
+			b.WriteString("--\n")
+		}
+		if r.opts.ShowSpan {
+			b.WriteString(match.spanString() + "\n")
+		} else {
+			b.WriteString(match.String() + "\n")
+		}
+		if block {
+			r.writeLines(&b, match)
+		}
+	}
+	return b.String()
+}
+
+// writeLines writes the lines of match like grep, the node as line:text and
+// its context as line-text.
+func (r *Result) writeLines(b *strings.Builder, match Match) {
+	for i, line := range match.Before {
+		fmt.Fprintf(b, "%d-%s\n", match.Line-len(match.Before)+i, line)
+	}
+	last := match.Line
+	if r.opts.ShowText {
+		for i, line := range splitLines(match.Text) {
+			fmt.Fprintf(b, "%d:%s\n", match.Line+i, line)
+		}
+		last = match.EndLine
+	} else {
+		fmt.Fprintf(b, "%d:%s\n", match.Line, match.Content)
+	}
+	for i, line := range match.After {
+		fmt.Fprintf(b, "%d-%s\n", last+1+i, line)
 	}
-	return nil
 }
 
-func (s *Searcher) Search(path, queryStr string) error {
+// grammars are the tree-sitter grammars search supports, by name.
+var grammars = map[string]func() unsafe.Pointer{
+	"go":         tree_sitter_go.Language,
+	"javascript": tree_sitter_javascript.Language,
+	"typescript": tree_sitter_typescript.LanguageTypescript,
+	"tsx":        tree_sitter_typescript.LanguageTSX,
+	"python":     tree_sitter_python.Language,
+	"rust":       tree_sitter_rust.Language,
+	"java":       tree_sitter_java.Language,
+	"c":          tree_sitter_c.Language,
+	"cpp":        tree_sitter_cpp.Language,
+}
+
+// extensions maps file extensions to the name of their grammar.
+var extensions = map[string]string{
+	".go":   "go",
+	".js":   "javascript",
+	".mjs":  "javascript",
+	".jsx":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "tsx",
+	".py":   "python",
+	".pyi":  "python",
+	".rs":   "rust",
+	".java": "java",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cxx":  "cpp",
+	".hpp":  "cpp",
+	".hxx":  "cpp",
+}
+
+// grammarFromFile returns the name of the grammar for filename based on its
+// extension, or "" if the language is not supported.
+func grammarFromFile(filename string) string {
+	return extensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// LanguageFromFile returns the tree-sitter grammar for filename based on its
+// extension, or nil if the language is not supported.
+func LanguageFromFile(filename string) *tree_sitter.Language {
+	name := grammarFromFile(filename)
+	if name == "" {
+		return nil
+	}
+	return tree_sitter.NewLanguage(grammars[name]())
+}
+
+// Search prints the nodes captured by queryStr in path, or in every
+// supported file under path that walk does not ignore if it is a directory.
+// Files are searched in parallel, but the matches of each pattern of the
+// query are in the order the files were walked.
+func (s *Searcher) Search(path, queryStr string, opts SearchOptions, walk ignore.Options) (*Result, error) {
+	if opts.Before < 0 || opts.After < 0 {
+		return nil, fmt.Errorf("context must be >= 0")
+	}
+
 	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("stat %s: %w", path, err)
+		return nil, fmt.Errorf("stat %s: %w", path, err)
 	}
 
+	queries := newQueries(queryStr)
+	defer queries.close()
+
+	var files []sourceFile
 	if info.IsDir() {
-		return s.searchDir(path, queryStr)
+		files, err = collectFiles(path, queries, walk)
+		if err != nil {
+			return nil, err
+		}
+	} else if name := grammarFromFile(path); name != "" {
+		files = []sourceFile{{path: path, query: queries.get(name)}}
 	}
-	return s.searchFile(path, queryStr)
+
+	err = queries.checkCaptures(opts.Captures)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.searchFiles(files, opts)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Compare(a.Pattern, b.Pattern)
+	})
+	res := &Result{Query: queryStr, Patterns: queries.patterns(), Matches: matches, opts: opts}
+	return res, output.Print(s.out, res)
 }
 
-func (s *Searcher) searchDir(dir, queryStr string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// ReadQuery returns the query in the file at path, such as a .scm file with
+// many patterns.
+func ReadQuery(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read query %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// sourceFile is a file to search and the query compiled for its grammar.
+type sourceFile struct {
+	path  string
+	query *compiledQuery
+}
+
+// collectFiles returns the supported files under dir that opts does not
+// ignore, in walk order.
+func collectFiles(dir string, queries *queries, opts ignore.Options) ([]sourceFile, error) {
+	var files []sourceFile
+	err := ignore.Walk(dir, opts, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if d.IsDir() {
 			return nil
 		}
 
-		if getLanguageFromFile(path) == nil {
+		name := grammarFromFile(path)
+		if name == "" {
 			return nil
 		}
+		files = append(files, sourceFile{path: path, query: queries.get(name)})
+		return nil
+	})
+	return files, err
+}
 
-		return s.searchFile(path, queryStr)
+// searchFiles searches files with a bounded pool of workers and returns
+// their matches in the order of files.
+func (s *Searcher) searchFiles(files []sourceFile, opts SearchOptions) ([]Match, error) {
+	results, err := parallel(s.workers, files, func() fileWorker[sourceFile, []Match] {
+		return newWorker(opts)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []Match{}
+	for _, found := range results {
+		matches = append(matches, found...)
+	}
+	return matches, nil
 }
 
-func (s *Searcher) searchFile(filename, queryStr string) error {
-	lang := getLanguageFromFile(filename)
-	if lang == nil {
-		return nil
+// compiledQuery is a search query compiled for one grammar. A compiled query
+// is only read after it is made, so every worker shares it.
+type compiledQuery struct {
+	lang     *tree_sitter.Language
+	query    *tree_sitter.Query
+	captures []string
+	err      error
+}
+
+// queries compiles a search query once for each grammar it is run against.
+type queries struct {
+	source  string
+	grammar map[string]*compiledQuery
+}
+
+func newQueries(source string) *queries {
+	return &queries{source: source, grammar: map[string]*compiledQuery{}}
+}
+
+// get returns the query compiled for the grammar called name. A query that
+// does not compile for the grammar is returned with err set, so only the
+// files of that grammar fail.
+func (q *queries) get(name string) *compiledQuery {
+	if compiled, ok := q.grammar[name]; ok {
+		return compiled
 	}
 
-	parser := tree_sitter.NewParser()
-	defer parser.Close()
+	compiled := &compiledQuery{lang: tree_sitter.NewLanguage(grammars[name]())}
+	query, queryErr := tree_sitter.NewQuery(compiled.lang, q.source)
+	if queryErr != nil {
+		compiled.err = errors.New(queryErr.Message)
+	} else if err := checkPredicates(query); err != nil {
+		query.Close()
+		compiled.err = err
+	} else {
+		compiled.query = query
+		compiled.captures = query.CaptureNames()
+	}
+	q.grammar[name] = compiled
+	return compiled
+}
+
+// patterns returns the source of each pattern of the query, by index, or nil
+// if it did not compile for any grammar. Patterns are in the order of the
+// source, so they have the same index in every grammar.
+func (q *queries) patterns() []string {
+	for _, compiled := range q.grammar {
+		if compiled.query == nil {
+			continue
+		}
+		patterns := make([]string, compiled.query.PatternCount())
+		for i := range patterns {
+			start := compiled.query.StartByteForPattern(uint(i))
+			end := compiled.query.EndByteForPattern(uint(i))
+			patterns[i] = trimPattern(q.source[start:end])
+		}
+		return patterns
+	}
+	return nil
+}
+
+// trimPattern trims the blank lines and comments that follow a pattern up to
+// the start of the next one.
+func trimPattern(pattern string) string {
+	lines := strings.Split(strings.TrimSpace(pattern), "\n")
+	for len(lines) > 1 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last != "" && !strings.HasPrefix(last, ";") {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// checkCaptures returns an error if a name in names is not a capture of the
+// query in every grammar it compiled for. If no file needed a grammar, the
+// names are checked against the captures written in the source instead, so
+// a misspelled name fails even when nothing is searched.
+func (q *queries) checkCaptures(names []string) error {
+	var known [][]string
+	for _, compiled := range q.grammar {
+		if compiled.query != nil {
+			known = append(known, compiled.captures)
+		}
+	}
+	if len(q.grammar) == 0 {
+		known = append(known, captureNames(q.source))
+	}
+
+	for _, name := range names {
+		for _, captures := range known {
+			if !slices.Contains(captures, name) {
+				return fmt.Errorf("query has no capture @%s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// captureNames returns the names of the captures in source, skipping strings
+// and comments. Names are read as tree-sitter reads identifiers.
+func captureNames(source string) []string {
+	isNameChar := func(c byte) bool {
+		return c >= utf8.RuneSelf || c == '_' || c == '-' || c == '.' || c == '?' || c == '!' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+	}
 
-	err := parser.SetLanguage(lang)
+	var names []string
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case ';':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case '"':
+			for i++; i < len(source) && source[i] != '"'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+		case '@':
+			start := i + 1
+			for i+1 < len(source) && isNameChar(source[i+1]) {
+				i++
+			}
+			names = append(names, source[start:i+1])
+		}
+	}
+	return names
+}
+
+func (q *queries) close() {
+	for _, compiled := range q.grammar {
+		if compiled.query != nil {
+			compiled.query.Close()
+		}
+	}
+}
+
+// worker searches files one at a time. It keeps a parser for each grammar it
+// has seen and one query cursor, so they are made once per worker rather
+// than once per file.
+type worker struct {
+	opts    SearchOptions
+	parsers map[*compiledQuery]*tree_sitter.Parser
+	cursor  *tree_sitter.QueryCursor
+}
+
+func newWorker(opts SearchOptions) *worker {
+	return &worker{
+		opts:    opts,
+		parsers: map[*compiledQuery]*tree_sitter.Parser{},
+		cursor:  tree_sitter.NewQueryCursor(),
+	}
+}
+
+func (w *worker) close() {
+	for _, parser := range w.parsers {
+		parser.Close()
+	}
+	w.cursor.Close()
+}
+
+func (w *worker) parser(file sourceFile) (*tree_sitter.Parser, error) {
+	if parser, ok := w.parsers[file.query]; ok {
+		return parser, nil
+	}
+	parser := tree_sitter.NewParser()
+	err := parser.SetLanguage(file.query.lang)
 	if err != nil {
-		return fmt.Errorf("set language for %s: %w", filename, err)
+		parser.Close()
+		return nil, fmt.Errorf("set language for %s: %w", file.path, err)
 	}
+	w.parsers[file.query] = parser
+	return parser, nil
+}
 
-	query, queryErr := tree_sitter.NewQuery(lang, queryStr)
-	if queryErr != nil {
-		return fmt.Errorf("invalid query for %s: %s", filename, queryErr.Message)
+// search returns the matches of the query in file.
+func (w *worker) search(file sourceFile) ([]Match, error) {
+	if file.query.err != nil {
+		return nil, fmt.Errorf("invalid query for %s: %s", file.path, file.query.err)
+	}
+
+	parser, err := w.parser(file)
+	if err != nil {
+		return nil, err
 	}
-	defer query.Close()
 
-	content, err := os.ReadFile(filename)
+	content, err := os.ReadFile(file.path)
 	if err != nil {
-		return fmt.Errorf("read %s: %w", filename, err)
+		return nil, fmt.Errorf("read %s: %w", file.path, err)
 	}
 
 	tree := parser.Parse(content, nil)
 	if tree == nil {
-		return fmt.Errorf("failed to parse %s", filename)
+		return nil, fmt.Errorf("failed to parse %s", file.path)
 	}
 	defer tree.Close()
 
-	cursor := tree_sitter.NewQueryCursor()
-	defer cursor.Close()
+	var lines []string
+	if w.opts.Before > 0 || w.opts.After > 0 {
+		lines = splitLines(string(content))
+	}
 
-	matches := cursor.Matches(query, tree.RootNode(), content)
+	var found []Match
+	matches := w.cursor.Matches(file.query.query, tree.RootNode(), content)
 	for match := matches.Next(); match != nil; match = matches.Next() {
+		if !satisfies(file.query.query, match, content) {
+			continue
+		}
 		for _, capture := range match.Captures {
-			node := capture.Node
-			startPos := node.StartPosition()
-
-			lines := strings.Split(string(content), "\n")
-			var lineContent string
-			if startPos.Row < uint(len(lines)) {
-				lineContent = strings.TrimSpace(lines[startPos.Row])
+			name := file.query.captures[capture.Index]
+			if len(w.opts.Captures) > 0 && !slices.Contains(w.opts.Captures, name) {
+				continue
 			}
+			m := w.match(file.path, content, lines, &capture.Node, name)
+			m.Pattern = int(match.PatternIndex)
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
 
-			captureName := query.CaptureNames()[capture.Index]
+// match returns the Match of node, captured as capture in the file at path.
+// lines are the lines of content, needed only for context.
+func (w *worker) match(path string, content []byte, lines []string, node *tree_sitter.Node, capture string) Match {
+	start, end := node.StartPosition(), node.EndPosition()
+	m := Match{
+		File:      path,
+		Content:   lineAt(content, node.StartByte()),
+		Capture:   capture,
+		Line:      int(start.Row) + 1,
+		Column:    int(start.Column) + 1,
+		EndLine:   int(end.Row) + 1,
+		EndColumn: int(end.Column) + 1,
+		StartByte: int(node.StartByte()),
+		EndByte:   int(node.EndByte()),
+	}
+	if end.Column == 0 && end.Row > start.Row {
+		// The node ends with a newline, so its last line is the one before.
+		nl := int(node.EndByte()) - 1
+		m.EndLine--
+		m.EndColumn = nl - (bytes.LastIndexByte(content[:nl], '\n') + 1) + 1
+	}
 
-			fmt.Printf("%s:%d:%d: @%s: %s\n",
-				filename,
-				startPos.Row+1,
-				startPos.Column+1,
-				captureName,
-				lineContent)
+	if w.opts.ShowText {
+		m.Text = string(content[m.StartByte:m.EndByte])
+	}
+	if lines != nil {
+		m.Before = lines[max(0, m.Line-1-w.opts.Before) : m.Line-1]
+		last := m.Line
+		if w.opts.ShowText {
+			last = m.EndLine
 		}
+		m.After = lines[min(last, len(lines)):min(last+w.opts.After, len(lines))]
 	}
+	return m
+}
 
-	return nil
+// lineAt returns the line of content that contains offset, without
+// surrounding whitespace.
+func lineAt(content []byte, offset uint) string {
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	end := bytes.IndexByte(content[offset:], '\n')
+	if end < 0 {
+		end = len(content)
+	} else {
+		end += int(offset)
+	}
+	return strings.TrimSpace(string(content[start:end]))
+}
+
+// splitLines splits text into lines without their line endings.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
 }
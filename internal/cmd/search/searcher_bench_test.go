@@ -0,0 +1,95 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RRethy/eddie/internal/ignore"
+)
+
+const benchQuery = "(function_declaration name: (identifier) @func)"
+
+func goSource(functions int) string {
+	var b strings.Builder
+	b.WriteString("package main\n")
+	for i := 0; i < functions; i++ {
+		fmt.Fprintf(&b, "\nfunc f%d(x int) int {\n\treturn x + %d\n}\n", i, i)
+	}
+	return b.String()
+}
+
+func BenchmarkSearcher_SearchDir(b *testing.B) {
+	sizes := []struct {
+		name  string
+		files int
+	}{
+		{"small", 10},
+		{"medium", 100},
+		{"large", 1000},
+	}
+
+	for _, size := range sizes {
+		testDir := b.TempDir()
+		for i := 0; i < size.files; i++ {
+			fileName := filepath.Join(testDir, fmt.Sprintf("file%d.go", i))
+			err := os.WriteFile(fileName, []byte(goSource(20)), 0o644)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		for _, workers := range []struct {
+			name  string
+			count int
+		}{
+			{"serial", 1},
+			{"parallel", 0},
+		} {
+			b.Run(size.name+"/"+workers.name, func(b *testing.B) {
+				s := &Searcher{workers: workers.count}
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					_, err := s.Search(testDir, benchQuery, SearchOptions{}, ignore.Options{})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkSearcher_SearchFile(b *testing.B) {
+	sizes := []struct {
+		name      string
+		functions int
+	}{
+		{"small", 10},
+		{"medium", 1000},
+		{"large", 10000},
+	}
+
+	for _, size := range sizes {
+		b.Run(size.name, func(b *testing.B) {
+			fileName := filepath.Join(b.TempDir(), "main.go")
+			err := os.WriteFile(fileName, []byte(goSource(size.functions)), 0o644)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			s := &Searcher{}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err := s.Search(fileName, benchQuery, SearchOptions{}, ignore.Options{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
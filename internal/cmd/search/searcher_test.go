@@ -1,12 +1,17 @@
 package search
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/ignore"
 )
 
 func TestSearcher_Search_Go(t *testing.T) {
@@ -54,8 +59,9 @@ var x = 1`,
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
-			assert.NoError(t, err)
+			res, err := s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, len(res.Matches) > 0)
 		})
 	}
 }
@@ -105,7 +111,7 @@ func TestSearcher_Search_JavaScript(t *testing.T) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -165,7 +171,7 @@ function Component({ name }: Props) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -217,7 +223,7 @@ func TestSearcher_Search_Python(t *testing.T) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -261,7 +267,7 @@ func TestSearcher_Search_Rust(t *testing.T) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -308,7 +314,7 @@ func TestSearcher_Search_Java(t *testing.T) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -360,7 +366,7 @@ void greet(void);
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -415,7 +421,7 @@ public:
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			if tt.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -460,7 +466,7 @@ func TestSearcher_Search_UnsupportedFiles(t *testing.T) {
 			require.NoError(t, err)
 
 			s := &Searcher{}
-			err = s.Search(testFile, tt.query)
+			_, err = s.Search(testFile, tt.query, SearchOptions{}, ignore.Options{})
 			assert.NoError(t, err)
 		})
 	}
@@ -489,8 +495,12 @@ func hello() {
 	s := &Searcher{}
 
 	t.Run("function declaration search (Go and JS)", func(t *testing.T) {
-		err = s.Search(tmpDir, "(function_declaration name: (identifier) @func)")
-		assert.NoError(t, err)
+		res, err := s.Search(tmpDir, "(function_declaration name: (identifier) @func)", SearchOptions{}, ignore.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, []Match{
+			{File: goFile, Content: "func hello() {", Capture: "func", Line: 2, Column: 6, EndLine: 2, EndColumn: 11, StartByte: 18, EndByte: 23},
+			{File: jsFile, Content: "function greet() {", Capture: "func", Line: 1, Column: 10, EndLine: 1, EndColumn: 15, StartByte: 9, EndByte: 14},
+		}, res.Matches)
 	})
 }
 
@@ -505,7 +515,7 @@ func TestSearcher_SearchDir_LanguageSpecific(t *testing.T) {
 	s := &Searcher{}
 
 	t.Run("python function search", func(t *testing.T) {
-		err = s.Search(tmpDir, "(function_definition name: (identifier) @func)")
+		_, err = s.Search(tmpDir, "(function_definition name: (identifier) @func)", SearchOptions{}, ignore.Options{})
 		assert.NoError(t, err)
 	})
 }
@@ -538,11 +548,11 @@ func helper() {
 	require.NoError(t, err)
 
 	s := &Searcher{}
-	err = s.Search(tmpDir, "(function_declaration name: (identifier) @func)")
+	_, err = s.Search(tmpDir, "(function_declaration name: (identifier) @func)", SearchOptions{}, ignore.Options{})
 	assert.NoError(t, err)
 }
 
-func TestGetLanguageFromFile(t *testing.T) {
+func TestLanguageFromFile(t *testing.T) {
 	tests := []struct {
 		name     string
 		filename string
@@ -569,7 +579,7 @@ func TestGetLanguageFromFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lang := getLanguageFromFile(tt.filename)
+			lang := LanguageFromFile(tt.filename)
 			if tt.hasLang {
 				assert.NotNil(t, lang, "expected language for %s", tt.filename)
 			} else {
@@ -578,3 +588,239 @@ func TestGetLanguageFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSearcher_SearchDir_Order(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var expected []Match
+	for i := range 50 {
+		file := filepath.Join(tmpDir, fmt.Sprintf("file%02d.go", i))
+		content := fmt.Sprintf("package main\n\nfunc f%d() {}\n\nfunc g%d() {}\n", i, i)
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+		for j, name := range []string{fmt.Sprintf("f%d", i), fmt.Sprintf("g%d", i)} {
+			line := 3 + 2*j
+			start := strings.Index(content, name+"()")
+			expected = append(expected, Match{
+				File:      file,
+				Content:   "func " + name + "() {}",
+				Capture:   "func",
+				Line:      line,
+				Column:    6,
+				EndLine:   line,
+				EndColumn: 6 + len(name),
+				StartByte: start,
+				EndByte:   start + len(name),
+			})
+		}
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			s := &Searcher{workers: workers}
+			res, err := s.Search(tmpDir, "(function_declaration name: (identifier) @func)", SearchOptions{}, ignore.Options{})
+			require.NoError(t, err)
+			assert.Equal(t, expected, res.Matches)
+		})
+	}
+}
+
+func TestSearcher_Search_InvalidQuery(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	goFile := filepath.Join(tmpDir, "a.go")
+	require.NoError(t, os.WriteFile(goFile, []byte("package main\n\nfunc a() {}\n"), 0o644))
+	pyFile := filepath.Join(tmpDir, "b.py")
+	require.NoError(t, os.WriteFile(pyFile, []byte("def b():\n    pass\n"), 0o644))
+
+	s := &Searcher{}
+	_, err := s.Search(tmpDir, "(function_declaration name: (identifier) @func)", SearchOptions{}, ignore.Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid query for "+pyFile)
+}
+
+func TestLineAt(t *testing.T) {
+	content := []byte("first\n\tsecond line  \nthird")
+
+	assert.Equal(t, "first", lineAt(content, 0))
+	assert.Equal(t, "first", lineAt(content, 4))
+	assert.Equal(t, "second line", lineAt(content, 8))
+	assert.Equal(t, "third", lineAt(content, uint(len(content)-1)))
+}
+
+func TestSearcher_SearchDir_Ignore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("gen/\n"), 0o644))
+	for _, dir := range []string{"src", "gen", "node_modules"} {
+		require.NoError(t, os.Mkdir(filepath.Join(tmpDir, dir), 0o755))
+		content := "package " + dir + "\n\nfunc " + dir + "Func() {}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, dir, "a.go"), []byte(content), 0o644))
+	}
+
+	s := &Searcher{}
+	query := "(function_declaration name: (identifier) @func)"
+
+	res, err := s.Search(tmpDir, query, SearchOptions{}, ignore.Options{})
+	require.NoError(t, err)
+	require.Len(t, res.Matches, 1)
+	assert.Equal(t, filepath.Join(tmpDir, "src", "a.go"), res.Matches[0].File)
+
+	res, err = s.Search(tmpDir, query, SearchOptions{}, ignore.Options{NoIgnore: true})
+	require.NoError(t, err)
+	assert.Len(t, res.Matches, 3)
+}
+
+func TestSearcher_Search_Options(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.go")
+	content := "package main\n\n// hello says hello.\nfunc hello() {\n\tprintln(\"hello\")\n}\n\nvar x = 1\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	query := "(function_declaration name: (identifier) @name) @func"
+	start := strings.Index(content, "func hello")
+	end := strings.Index(content, "}\n") + 1
+
+	t.Run("spans", func(t *testing.T) {
+		var out bytes.Buffer
+		res, err := NewSearcher(&out).Search(file, query, SearchOptions{ShowSpan: true}, ignore.Options{})
+		require.NoError(t, err)
+		require.Len(t, res.Matches, 2)
+		assert.Equal(t, Match{
+			File:      file,
+			Content:   "func hello() {",
+			Capture:   "func",
+			Line:      4,
+			Column:    1,
+			EndLine:   6,
+			EndColumn: 2,
+			StartByte: start,
+			EndByte:   end,
+		}, res.Matches[0])
+		assert.Equal(t, fmt.Sprintf("%s:4:1-6:2 (bytes %d-%d): @func: func hello() {\n", file, start, end),
+			strings.SplitAfter(out.String(), "\n")[0])
+	})
+
+	t.Run("captures", func(t *testing.T) {
+		res, err := (&Searcher{}).Search(file, query, SearchOptions{Captures: []string{"name"}}, ignore.Options{})
+		require.NoError(t, err)
+		require.Len(t, res.Matches, 1)
+		assert.Equal(t, "name", res.Matches[0].Capture)
+
+		_, err = (&Searcher{}).Search(file, query, SearchOptions{Captures: []string{"missing"}}, ignore.Options{})
+		assert.ErrorContains(t, err, "query has no capture @missing")
+
+		// Names are checked even when no file is searched.
+		empty := t.TempDir()
+		notes := filepath.Join(empty, "notes.txt")
+		require.NoError(t, os.WriteFile(notes, []byte("hello\n"), 0o644))
+		for _, path := range []string{empty, notes} {
+			_, err = (&Searcher{}).Search(path, query, SearchOptions{Captures: []string{"missing"}}, ignore.Options{})
+			assert.ErrorContains(t, err, "query has no capture @missing")
+
+			res, err = (&Searcher{}).Search(path, query, SearchOptions{Captures: []string{"name"}}, ignore.Options{})
+			require.NoError(t, err)
+			assert.Empty(t, res.Matches)
+		}
+	})
+
+	t.Run("text and context", func(t *testing.T) {
+		var out bytes.Buffer
+		opts := SearchOptions{Captures: []string{"func"}, ShowText: true, Before: 1, After: 2}
+		res, err := NewSearcher(&out).Search(file, query, opts, ignore.Options{})
+		require.NoError(t, err)
+		require.Len(t, res.Matches, 1)
+		match := res.Matches[0]
+		assert.Equal(t, "func hello() {\n\tprintln(\"hello\")\n}", match.Text)
+		assert.Equal(t, []string{"// hello says hello."}, match.Before)
+		assert.Equal(t, []string{"", "var x = 1"}, match.After)
+		assert.Equal(t, file+":4:1: @func: func hello() {\n"+
+			"3-// hello says hello.\n"+
+			"4:func hello() {\n"+
+			"5:\tprintln(\"hello\")\n"+
+			"6:}\n"+
+			"7-\n"+
+			"8-var x = 1\n", out.String())
+	})
+
+	t.Run("context without text", func(t *testing.T) {
+		opts := SearchOptions{Captures: []string{"name"}, After: 1}
+		res, err := (&Searcher{}).Search(file, query, opts, ignore.Options{})
+		require.NoError(t, err)
+		require.Len(t, res.Matches, 1)
+		assert.Empty(t, res.Matches[0].Before)
+		assert.Equal(t, []string{"\tprintln(\"hello\")"}, res.Matches[0].After)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := NewSearcher(&out).Search(file, query, SearchOptions{Captures: []string{"name"}, JSON: true}, ignore.Options{})
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), `"end_line": 4`)
+		assert.Contains(t, out.String(), `"start_byte": `)
+	})
+}
+
+func TestSearcher_Search_NodeEndingWithNewline(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.py")
+	content := "def f():\n    return 1\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+	res, err := (&Searcher{}).Search(file, "(module) @module", SearchOptions{}, ignore.Options{})
+	require.NoError(t, err)
+	require.Len(t, res.Matches, 1)
+	assert.Equal(t, 2, res.Matches[0].EndLine)
+	assert.Equal(t, 13, res.Matches[0].EndColumn)
+	assert.Equal(t, len(content), res.Matches[0].EndByte)
+}
+
+func TestSearcher_Search_QueryFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "main.go")
+	content := "package main\n\nfunc main() {\n\thelper()\n}\n\nfunc helper() {}\n"
+	require.NoError(t, os.WriteFile(goFile, []byte(content), 0o644))
+
+	queryFile := filepath.Join(tmpDir, "queries.scm")
+	queryContent := `; calls
+(call_expression
+  function: (identifier) @call)
+
+; functions
+(function_declaration name: (identifier) @func)
+`
+	require.NoError(t, os.WriteFile(queryFile, []byte(queryContent), 0o644))
+
+	query, err := ReadQuery(queryFile)
+	require.NoError(t, err)
+	_, err = ReadQuery(filepath.Join(tmpDir, "missing.scm"))
+	assert.Error(t, err)
+
+	var out bytes.Buffer
+	res, err := NewSearcher(&out).Search(goFile, query, SearchOptions{}, ignore.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"(call_expression\n  function: (identifier) @call)",
+		"(function_declaration name: (identifier) @func)",
+	}, res.Patterns)
+
+	var got []string
+	for _, match := range res.Matches {
+		got = append(got, fmt.Sprintf("%d %s %d", match.Pattern, match.Capture, match.Line))
+	}
+	assert.Equal(t, []string{"0 call 4", "1 func 3", "1 func 7"}, got)
+
+	assert.Equal(t, "pattern 0: (call_expression\n"+
+		goFile+":4:2: @call: helper()\n"+
+		"\n"+
+		"pattern 1: (function_declaration name: (identifier) @func)\n"+
+		goFile+":3:6: @func: func main() {\n"+
+		goFile+":7:6: @func: func helper() {}\n", out.String())
+}
+
+func TestCaptureNames(t *testing.T) {
+	source := `; @commented
+((call_expression function: (identifier) @fn.name) @call
+  (#eq? @fn.name "@quoted \" @still-quoted"))
+(identifier)@id`
+	assert.Equal(t, []string{"fn.name", "call", "fn.name", "id"}, captureNames(source))
+}
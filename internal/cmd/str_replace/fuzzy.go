@@ -0,0 +1,220 @@
+package str_replace
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/RRethy/eddie/internal/diff"
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+// maxCandidates caps the number of snippets suggested when a fuzzy match
+// fails.
+const maxCandidates = 3
+
+// fuzzyMatch is a match of old_str found while ignoring differences in
+// whitespace. Start and End are byte offsets in the content.
+type fuzzyMatch struct {
+	Start int
+	End   int
+}
+
+// fuzzyPattern returns a regexp matching s with any run of whitespace between
+// its words, or nil if s is only whitespace.
+func fuzzyPattern(s string) *regexp.Regexp {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return nil
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(strings.Join(words, `\s+`))
+}
+
+// fuzzyReplace replaces the only match of oldStr in content found while
+// ignoring whitespace, with newStr re-indented to the indentation of the
+// matched lines. It fails, suggesting the closest snippets of content, if
+// there is no such match or more than one.
+func (r *Replacer) fuzzyReplace(path, content, oldStr, newStr string) (string, fuzzyMatch, error) {
+	pattern := fuzzyPattern(oldStr)
+	if pattern == nil {
+		return "", fuzzyMatch{}, fmt.Errorf("old_str must contain more than whitespace to match fuzzily")
+	}
+
+	locs := pattern.FindAllStringIndex(content, -1)
+	switch len(locs) {
+	case 0:
+		msg := fmt.Sprintf("no occurrences of %q found in %s, even ignoring whitespace", oldStr, path)
+		if candidates := nearestSnippets(content, oldStr); candidates != "" {
+			msg += "; the closest snippets are:\n" + candidates
+		}
+		return "", fuzzyMatch{}, fmt.Errorf("%s", msg)
+	case 1:
+	default:
+		var snippets []string
+		for i, loc := range locs {
+			if i == maxCandidates {
+				snippets = append(snippets, fmt.Sprintf("... and %d more", len(locs)-i))
+				break
+			}
+			start, end := lineOf(content, loc[0]), lineOf(content, loc[1]-1)
+			snippets = append(snippets, snippet(content, start, end, ""))
+		}
+		return "", fuzzyMatch{}, fmt.Errorf("found %d occurrences of %q in %s ignoring whitespace; add surrounding context to old_str to narrow the match:\n%s",
+			len(locs), oldStr, path, strings.Join(snippets, "\n"))
+	}
+
+	match := fuzzyMatch{Start: locs[0][0], End: locs[0][1]}
+	replacement := reindent(oldStr, newStr, content, match)
+	return content[:match.Start] + replacement + content[match.End:], match, nil
+}
+
+// reindent adapts newStr to replace the text at match, which old_str matched
+// with different whitespace. Whitespace that old_str has around its words is
+// dropped from newStr, since the match leaves it out, and the indentation of
+// every line after the first is moved from old_str's indentation to the
+// indentation of the matched lines, converting indentation levels between
+// tabs and spaces.
+func reindent(oldStr, newStr, content string, match fuzzyMatch) string {
+	oldLead := oldStr[:len(oldStr)-len(strings.TrimLeft(oldStr, " \t\r\n"))]
+	if i := strings.LastIndexByte(oldLead, '\n'); i >= 0 {
+		newStr = strings.TrimPrefix(newStr, oldLead[:i+1])
+	}
+	newStr = strings.TrimSuffix(newStr, oldStr[len(strings.TrimRight(oldStr, " \t\r\n")):])
+
+	oldLines := strings.Split(strings.Trim(oldStr, "\r\n"), "\n")
+	oldBase := fileops.Indentation(oldLines[0])
+	oldUnit := indentUnit(oldLines, oldBase)
+
+	lineStart := strings.LastIndexByte(content[:match.Start], '\n') + 1
+	matchedLines := strings.Split(content[lineStart:match.End], "\n")
+	base := fileops.Indentation(matchedLines[0])
+	unit := indentUnit(matchedLines, base)
+	if unit == "" {
+		unit = oldUnit
+		if strings.Contains(base, "\t") {
+			unit = "\t"
+		}
+	}
+
+	convert := func(indent string) string {
+		rest, ok := strings.CutPrefix(indent, oldBase)
+		if !ok || oldUnit == "" || unit == oldUnit {
+			return rest
+		}
+		levels := strings.Count(rest, oldUnit)
+		return strings.Repeat(unit, levels) + strings.ReplaceAll(rest, oldUnit, "")
+	}
+
+	lines := strings.Split(newStr, "\n")
+	for i, line := range lines {
+		text := strings.TrimLeft(line, " \t")
+		switch {
+		case i == 0:
+			// The indentation before the match stays in the file.
+			lines[i] = convert(fileops.Indentation(line)) + text
+		case strings.TrimSpace(line) == "":
+			lines[i] = strings.TrimLeft(line, " \t")
+		default:
+			lines[i] = base + convert(fileops.Indentation(line)) + text
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentUnit returns the smallest indentation added to base by any of lines,
+// which is taken to be one level of indentation.
+func indentUnit(lines []string, base string) string {
+	unit := ""
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		extra, ok := strings.CutPrefix(fileops.Indentation(line), base)
+		if ok && extra != "" && (unit == "" || len(extra) < len(unit)) {
+			unit = extra
+		}
+	}
+	return unit
+}
+
+// nearestSnippets returns the blocks of content with as many lines as oldStr
+// that share the most words with it, formatted with line numbers.
+func nearestSnippets(content, oldStr string) string {
+	lines := strings.Split(content, "\n")
+	n := strings.Count(strings.Trim(oldStr, "\r\n"), "\n") + 1
+	if n > len(lines) {
+		n = len(lines)
+	}
+	want := strings.Fields(oldStr)
+
+	type candidate struct {
+		start int
+		score float64
+	}
+	var candidates []candidate
+	for start := 0; start+n <= len(lines); start++ {
+		words := strings.Fields(strings.Join(lines[start:start+n], "\n"))
+		if len(words) == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{start, similarity(want, words)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var snippets []string
+	var taken []int
+	for _, c := range candidates {
+		if len(snippets) == maxCandidates || c.score == 0 {
+			break
+		}
+		overlaps := false
+		for _, start := range taken {
+			if c.start < start+n && start < c.start+n {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		taken = append(taken, c.start)
+		snippets = append(snippets, snippet(content, c.start+1, c.start+n, fmt.Sprintf(" (%.0f%% similar)", c.score*100)))
+	}
+	return strings.Join(snippets, "\n")
+}
+
+// similarity returns the share of words a and b have in common, in order,
+// from 0 to 1.
+func similarity(a, b []string) float64 {
+	common := len(a)
+	for _, h := range diff.Lines(a, b) {
+		common -= h.OldEnd - h.OldStart
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// snippet formats lines start to end of content, 1-based and inclusive.
+func snippet(content string, start, end int, note string) string {
+	lines := strings.Split(content, "\n")
+	var b strings.Builder
+	if start == end {
+		fmt.Fprintf(&b, "line %d%s:", start, note)
+	} else {
+		fmt.Fprintf(&b, "lines %d-%d%s:", start, end, note)
+	}
+	for i := start; i <= end && i <= len(lines); i++ {
+		fmt.Fprintf(&b, "\n%6d\t%s", i, lines[i-1])
+	}
+	return b.String()
+}
+
+// lineOf returns the 1-based line of content containing byte offset.
+func lineOf(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
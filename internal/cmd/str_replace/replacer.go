@@ -4,58 +4,158 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/RRethy/eddie/internal/cmd/undo_edit"
 	"github.com/RRethy/eddie/internal/display"
 	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type Replacer struct {
 	fileOps *fileops.FileOps
-	display *display.Display
+	out     io.Writer
 }
 
 func NewReplacer(w io.Writer) *Replacer {
 	return &Replacer{
 		fileOps: &fileops.FileOps{},
-		display: display.New(w),
+		out:     w,
 	}
 }
 
-func (r *Replacer) StrReplace(path, oldStr, newStr string, showChanges, showResult bool) error {
-	original, info, err := r.fileOps.ReadFileContentForOperation(path, "replace strings in")
+// ExpectedCount returns the expectedCount of StrReplace for the unique and
+// expected count options, of which at most one can be set.
+func ExpectedCount(unique bool, expectedCount int) (int, error) {
+	if unique && expectedCount != 0 {
+		return 0, fmt.Errorf("cannot use both unique and an expected count; unique expects a count of 1")
+	}
+	if unique {
+		return 1, nil
+	}
+	return expectedCount, nil
+}
+
+// StrReplace replaces occurrences of oldStr with newStr in the file at path.
+// If expectedCount is greater than zero the edit is rejected unless oldStr
+// occurs exactly expectedCount times. If fuzzy is set and oldStr does not
+// occur, its only occurrence ignoring whitespace is replaced instead, with
+// newStr re-indented to match. The result is printed and returned.
+func (r *Replacer) StrReplace(path, oldStr, newStr string, expectedCount int, fuzzy, showChanges, showResult bool, pre fileops.Precondition) (*output.Edit, error) {
+	raw, info, err := r.fileOps.ReadFileContentForOperation(path, "replace strings in")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	modified := strings.ReplaceAll(original, oldStr, newStr)
+	if err := pre.Check(path, raw, info); err != nil {
+		return nil, err
+	}
 
-	if original == modified {
-		fmt.Printf("No occurrences of %q found in %s\n", oldStr, path)
-		return nil
+	original, format, err := fileops.DecodeText(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
 	}
+	oldStr, newStr = format.NormalizeText(oldStr), format.NormalizeText(newStr)
 
-	if showChanges {
-		r.display.ShowDiff(path, original, modified)
+	var match *fuzzyMatch
+	var modified string
+	if fuzzy && !strings.Contains(original, oldStr) && expectedCount <= 1 {
+		var m fuzzyMatch
+		modified, m, err = r.fuzzyReplace(path, original, oldStr, newStr)
+		if err != nil {
+			return nil, err
+		}
+		match = &m
+	} else {
+		if expectedCount > 0 {
+			if err := r.checkMatchCount(path, original, oldStr, expectedCount); err != nil {
+				return nil, err
+			}
+		}
+		modified = strings.ReplaceAll(original, oldStr, newStr)
 	}
 
-	err = r.fileOps.WriteFileContent(path, modified, info.Mode())
+	res := &output.Edit{Command: "str_replace", Path: path}
+	if original == modified {
+		res.Summary = fmt.Sprintf("No occurrences of %q found in %s", oldStr, path)
+		return res, output.Print(r.out, res)
+	}
+
+	encoded, err := format.EncodeText(modified)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("encode %s: %w", path, err)
 	}
 
-	if showResult {
-		r.display.ShowResult(path, modified)
+	err = r.fileOps.WriteFileContent(path, encoded, info.Mode())
+	if err != nil {
+		return nil, err
 	}
 
 	undoEditor := undo_edit.NewUndoEditor(os.Stdout)
-	err = undoEditor.RecordEdit(path, "str_replace", oldStr, newStr, -1)
+	err = undoEditor.RecordEdit(path, "str_replace", raw, encoded)
 	if err != nil {
-		return fmt.Errorf("record edit: %w", err)
+		return nil, fmt.Errorf("record edit: %w", err)
+	}
+
+	if showChanges {
+		diff := display.Diff(path, original, modified)
+		res.Diff = &diff
+	}
+	if showResult {
+		res.Content = &modified
 	}
 
-	count := strings.Count(original, oldStr)
-	fmt.Printf("Replaced %d occurrence(s) of %q with %q in %s\n", count, oldStr, newStr, path)
-	return nil
+	if match != nil {
+		start, end := lineOf(original, match.Start), lineOf(original, match.End-1)
+		res.Count = 1
+		res.Summary = fmt.Sprintf("Fuzzy matched old_str at lines %d-%d of %s ignoring whitespace; new_str was re-indented to match\n", start, end, path) +
+			fmt.Sprintf("Replaced 1 occurrence(s) of %q with %q in %s", oldStr, newStr, path)
+		return res, output.Print(r.out, res)
+	}
+
+	res.Count = strings.Count(original, oldStr)
+	res.Summary = fmt.Sprintf("Replaced %d occurrence(s) of %q with %q in %s", res.Count, oldStr, newStr, path)
+	return res, output.Print(r.out, res)
+}
+
+func (r *Replacer) checkMatchCount(path, content, oldStr string, expectedCount int) error {
+	if oldStr == "" {
+		return fmt.Errorf("old_str must not be empty when an expected count is set")
+	}
+
+	lines := r.matchLines(content, oldStr)
+	if len(lines) == expectedCount {
+		return nil
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("expected %d occurrence(s) of %q in %s, found none", expectedCount, oldStr, path)
+	}
+
+	lineStrs := make([]string, len(lines))
+	for i, line := range lines {
+		lineStrs[i] = strconv.Itoa(line)
+	}
+	return fmt.Errorf("expected %d occurrence(s) of %q in %s, found %d at lines %s; add surrounding context to old_str to narrow the match",
+		expectedCount, oldStr, path, len(lines), strings.Join(lineStrs, ", "))
+}
+
+// matchLines returns the 1-based starting line of every non-overlapping
+// occurrence of substr in content, in the same order strings.ReplaceAll
+// visits them.
+func (r *Replacer) matchLines(content, substr string) []int {
+	var lines []int
+	line := 1
+	offset := 0
+	for {
+		idx := strings.Index(content[offset:], substr)
+		if idx < 0 {
+			return lines
+		}
+		line += strings.Count(content[offset:offset+idx], "\n")
+		lines = append(lines, line)
+		line += strings.Count(substr, "\n")
+		offset += idx + len(substr)
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func BenchmarkReplacer_StrReplace(b *testing.B) {
@@ -37,7 +39,7 @@ func BenchmarkReplacer_StrReplace(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = r.StrReplace(testFile, "hello", "hi", false, false)
+				_, err = r.StrReplace(testFile, "hello", "hi", 0, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -78,7 +80,7 @@ func BenchmarkReplacer_StrReplacePatterns(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = r.StrReplace(testFile, pattern.oldStr, pattern.newStr, false, false)
+				_, err = r.StrReplace(testFile, pattern.oldStr, pattern.newStr, 0, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -125,7 +127,7 @@ func BenchmarkReplacer_StrReplaceFrequency(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = r.StrReplace(testFile, "target", "replacement", false, false)
+				_, err = r.StrReplace(testFile, "target", "replacement", 0, false, false, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func TestReplacer_StrReplace(t *testing.T) {
@@ -65,7 +67,7 @@ func TestReplacer_StrReplace(t *testing.T) {
 			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
 
 			r := &Replacer{}
-			err := r.StrReplace(testFile, tt.oldStr, tt.newStr, false, false)
+			res, err := r.StrReplace(testFile, tt.oldStr, tt.newStr, 0, false, false, false, fileops.Precondition{})
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -73,6 +75,7 @@ func TestReplacer_StrReplace(t *testing.T) {
 			}
 
 			require.NoError(t, err)
+			assert.Contains(t, res.Summary, tt.expectOutput)
 
 			result, err := os.ReadFile(testFile)
 			require.NoError(t, err)
@@ -109,9 +112,300 @@ func TestReplacer_StrReplace_Errors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup()
-			err := r.StrReplace(path, "old", "new", false, false)
+			_, err := r.StrReplace(path, "old", "new", 0, false, false, false, fileops.Precondition{})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
 }
+
+func TestReplacer_StrReplace_ExpectedCount(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		content       string
+		oldStr        string
+		wantContent   string
+		wantErr       string
+		expectedCount int
+	}{
+		{
+			name:          "unique match",
+			content:       "foo()\nbar()\n",
+			oldStr:        "foo()",
+			expectedCount: 1,
+			wantContent:   "baz()\nbar()\n",
+		},
+		{
+			name:          "ambiguous match lists lines",
+			content:       "foo()\nbar()\nfoo()\n\nfoo()\n",
+			oldStr:        "foo()",
+			expectedCount: 1,
+			wantContent:   "foo()\nbar()\nfoo()\n\nfoo()\n",
+			wantErr:       "found 3 at lines 1, 3, 5",
+		},
+		{
+			name:          "explicit count",
+			content:       "foo()\nfoo()\n",
+			oldStr:        "foo()",
+			expectedCount: 2,
+			wantContent:   "baz()\nbaz()\n",
+		},
+		{
+			name:          "no match",
+			content:       "bar()\n",
+			oldStr:        "foo()",
+			expectedCount: 1,
+			wantContent:   "bar()\n",
+			wantErr:       "found none",
+		},
+		{
+			name:          "multiline old string",
+			content:       "a\nfoo()\nb\nfoo()\n",
+			oldStr:        "foo()\nb",
+			expectedCount: 1,
+			wantContent:   "a\nbaz()\nfoo()\n",
+		},
+		{
+			name:          "empty old string",
+			content:       "foo()\n",
+			oldStr:        "",
+			expectedCount: 1,
+			wantContent:   "foo()\n",
+			wantErr:       "must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, "test_"+tt.name+".txt")
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			r := &Replacer{}
+			_, err := r.StrReplace(testFile, tt.oldStr, "baz()", tt.expectedCount, false, false, false, fileops.Precondition{})
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			result, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(result))
+		})
+	}
+}
+
+func TestExpectedCount(t *testing.T) {
+	tests := []struct {
+		name          string
+		unique        bool
+		expectedCount int
+		want          int
+		wantErr       bool
+	}{
+		{name: "neither"},
+		{name: "unique", unique: true, want: 1},
+		{name: "expected count", expectedCount: 3, want: 3},
+		{name: "both", unique: true, expectedCount: 3, wantErr: true},
+		{name: "both agreeing", unique: true, expectedCount: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpectedCount(tt.unique, tt.expectedCount)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "cannot use both unique and an expected count")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReplacer_StrReplace_Precondition(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("hello world\n"), 0o644))
+	hash := fileops.HashContent("hello world\n")
+
+	r := &Replacer{}
+	_, err := r.StrReplace(testFile, "world", "there", 0, false, false, false, fileops.Precondition{SHA256: hash})
+	require.NoError(t, err)
+
+	_, err = r.StrReplace(testFile, "there", "again", 0, false, false, false, fileops.Precondition{SHA256: hash})
+	require.ErrorIs(t, err, fileops.ErrConflict)
+
+	result, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "hello there\n", string(result))
+}
+
+func TestReplacer_StrReplace_Fuzzy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		oldStr      string
+		newStr      string
+		wantContent string
+		wantErr     []string
+	}{
+		{
+			name:        "spaces for tabs",
+			content:     "func f() {\n\tif x {\n\t\treturn 1\n\t}\n}\n",
+			oldStr:      "    if x {\n        return 1\n    }",
+			newStr:      "    if x {\n        y()\n        return 2\n    }",
+			wantContent: "func f() {\n\tif x {\n\t\ty()\n\t\treturn 2\n\t}\n}\n",
+		},
+		{
+			name:        "tabs for spaces",
+			content:     "def f():\n    if x:\n        return 1\n",
+			oldStr:      "\tif x:\n\t\treturn 1\n",
+			newStr:      "\tif x:\n\t\tlog()\n\t\treturn 2\n",
+			wantContent: "def f():\n    if x:\n        log()\n        return 2\n",
+		},
+		{
+			name:        "trailing whitespace and spacing",
+			content:     "a = 1  \nb  =  2\n",
+			oldStr:      "a = 1\nb = 2",
+			newStr:      "a = 10\nb = 20",
+			wantContent: "a = 10\nb = 20\n",
+		},
+		{
+			name:        "deeper indentation in file",
+			content:     "class A:\n    def f(self):\n        pass\n",
+			oldStr:      "def f(self):\n    pass",
+			newStr:      "def f(self):\n    return 1\n\ndef g(self):\n    pass",
+			wantContent: "class A:\n    def f(self):\n        return 1\n\n    def g(self):\n        pass\n",
+		},
+		{
+			name:        "ambiguous",
+			content:     "if x {\n  a()\n}\nif x {\n\ta()\n}\n",
+			oldStr:      "if x {\n    a()\n}",
+			newStr:      "b()",
+			wantContent: "if x {\n  a()\n}\nif x {\n\ta()\n}\n",
+			wantErr:     []string{"found 2 occurrences", "lines 1-3:", "lines 4-6:"},
+		},
+		{
+			name:        "no match suggests nearest",
+			content:     "one\nfunc handle(req Request) error {\n\treturn nil\n}\ntwo\n",
+			oldStr:      "func handle(req *Request) error {\n    return nil\n}",
+			newStr:      "x",
+			wantContent: "one\nfunc handle(req Request) error {\n\treturn nil\n}\ntwo\n",
+			wantErr:     []string{"even ignoring whitespace", "lines 2-4 (", "     3\t\treturn nil"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, "test_"+tt.name+".txt")
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			r := &Replacer{}
+			_, err := r.StrReplace(testFile, tt.oldStr, tt.newStr, 0, true, false, false, fileops.Precondition{})
+
+			if len(tt.wantErr) > 0 {
+				require.Error(t, err)
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+
+			result, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(result))
+		})
+	}
+}
+
+func TestReplacer_StrReplace_Format(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		oldStr      string
+		newStr      string
+		wantContent string
+		wantErr     string
+	}{
+		{
+			name:        "crlf",
+			content:     "a\r\nb\r\nc\r\n",
+			oldStr:      "a\nb\n",
+			newStr:      "x\ny\nz\n",
+			wantContent: "x\r\ny\r\nz\r\nc\r\n",
+		},
+		{
+			name:        "crlf in old_str",
+			content:     "a\r\nb\r\n",
+			oldStr:      "a\r\nb",
+			newStr:      "c",
+			wantContent: "c\r\n",
+		},
+		{
+			name:        "mixed line endings kept",
+			content:     "a\r\nb\n",
+			oldStr:      "b\n",
+			newStr:      "c\nd\n",
+			wantContent: "a\r\nc\nd\n",
+		},
+		{
+			name:        "utf-8 bom",
+			content:     "\xef\xbb\xbfhello\n",
+			oldStr:      "hello",
+			newStr:      "héllo",
+			wantContent: "\xef\xbb\xbfh\xc3\xa9llo\n",
+		},
+		{
+			name:        "utf-16le",
+			content:     "\xff\xfeh\x00i\x00\r\x00\n\x00",
+			oldStr:      "hi\n",
+			newStr:      "é\n",
+			wantContent: "\xff\xfe\xe9\x00\r\x00\n\x00",
+		},
+		{
+			name:        "latin-1",
+			content:     "caf\xe9\n",
+			oldStr:      "café",
+			newStr:      "thé",
+			wantContent: "th\xe9\n",
+		},
+		{
+			name:    "latin-1 cannot encode",
+			content: "caf\xe9\n",
+			oldStr:  "café",
+			newStr:  "☕",
+			wantErr: "cannot be encoded in ISO-8859-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, tt.name+".txt")
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			r := &Replacer{}
+			_, err := r.StrReplace(testFile, tt.oldStr, tt.newStr, 0, false, false, false, fileops.Precondition{})
+			result, readErr := os.ReadFile(testFile)
+			require.NoError(t, readErr)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, tt.content, string(result))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(result))
+		})
+	}
+}
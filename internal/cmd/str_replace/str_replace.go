@@ -1,7 +1,12 @@
 package str_replace
 
-import "os"
+import (
+	"os"
 
-func StrReplace(path, oldStr, newStr string, showChanges, showResult bool) error {
-	return NewReplacer(os.Stdout).StrReplace(path, oldStr, newStr, showChanges, showResult)
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func StrReplace(path, oldStr, newStr string, expectedCount int, fuzzy, showChanges, showResult bool, pre fileops.Precondition) error {
+	_, err := NewReplacer(os.Stdout).StrReplace(path, oldStr, newStr, expectedCount, fuzzy, showChanges, showResult, pre)
+	return err
 }
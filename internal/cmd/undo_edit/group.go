@@ -0,0 +1,167 @@
+package undo_edit
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// FileChange is the change of one file recorded by RecordGroup. Before and
+// After are the whole content of the file, and Created and Deleted are set if
+// it did not exist before or after the change. CreatedDirs are the parent
+// directories made for a created file.
+type FileChange struct {
+	Path        string
+	Before      string
+	After       string
+	Created     bool
+	Deleted     bool
+	CreatedDirs []string
+}
+
+// RecordGroup records changes to several files made by one edit of type
+// editType. The edits share a group, so undoing or redoing the edit in any of
+// the files does the same in the others. It must be called after the changes
+// have been written.
+func (u *UndoEditor) RecordGroup(editType string, changes []FileChange) error {
+	group := fmt.Sprintf("%s-%d", editType, time.Now().UnixNano())
+	for _, change := range changes {
+		edit := EditRecord{
+			EditType: editType,
+			Group:    group,
+		}
+
+		if change.Created {
+			edit.Event = EventCreated
+			for _, dir := range change.CreatedDirs {
+				absDir, err := filepath.Abs(dir)
+				if err != nil {
+					return fmt.Errorf("get absolute path: %w", err)
+				}
+				edit.CreatedDirs = append(edit.CreatedDirs, absDir)
+			}
+		} else {
+			hash, err := u.storeSnapshot(change.Before)
+			if err != nil {
+				return fmt.Errorf("store snapshot: %w", err)
+			}
+			edit.Before = hash
+		}
+
+		if change.Deleted {
+			edit.Event = EventDeleted
+		} else {
+			hash, err := u.storeSnapshot(change.After)
+			if err != nil {
+				return fmt.Errorf("store snapshot: %w", err)
+			}
+			edit.After = hash
+		}
+
+		err := u.appendEdit(change.Path, edit, change.Before)
+		if err != nil {
+			return fmt.Errorf("record edit of %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// linkedEdit is a file whose last count edits, or next count undone edits,
+// were made together with edits being undone or redone in another file.
+type linkedEdit struct {
+	path  string
+	count int
+}
+
+// linkedEdits returns the other files that must move along with the next
+// count edits of path that undo or redo would move, because those edits
+// belong to the same groups. It fails if a linked file cannot move just those
+// edits, or, unless force is set, if it was changed outside of eddie.
+func (u *UndoEditor) linkedEdits(path string, count int, undo, force bool) ([]linkedEdit, error) {
+	editPath, err := u.getEditFilePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("get edit file path: %w", err)
+	}
+
+	history, err := u.readEditHistory(editPath)
+	if err != nil || history.Version < historyVersion {
+		// travel reports missing and migrates old histories, which have no
+		// groups.
+		return nil, nil
+	}
+
+	from, to := history.Current-count, history.Current
+	if !undo {
+		from, to = history.Current, history.Current+count
+	}
+	if from < 0 || to > len(history.Edits) {
+		return nil, nil
+	}
+
+	groups := make(map[string]bool)
+	for _, edit := range history.Edits[from:to] {
+		if edit.Group != "" {
+			groups[edit.Group] = true
+		}
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	histories, _, err := u.ListHistories(string(filepath.Separator))
+	if err != nil {
+		return nil, fmt.Errorf("list edit histories: %w", err)
+	}
+
+	var linked []linkedEdit
+	for _, other := range histories {
+		if other.FilePath == history.FilePath {
+			continue
+		}
+
+		var indexes []int
+		for i, edit := range other.Edits {
+			if groups[edit.Group] && (i < other.Current) == undo {
+				indexes = append(indexes, i)
+			}
+		}
+		if len(indexes) == 0 {
+			continue
+		}
+
+		first := other.Current
+		if undo {
+			first = other.Current - len(indexes)
+		}
+		if indexes[0] != first || indexes[len(indexes)-1] != first+len(indexes)-1 {
+			if undo {
+				return nil, fmt.Errorf("cannot undo %s: %s was changed by the same edit and has been edited since; undo those edits first", path, other.FilePath)
+			}
+			return nil, fmt.Errorf("cannot redo %s: %s was changed by the same edit and has other edits to redo first", path, other.FilePath)
+		}
+
+		if !force {
+			err = u.CheckUnchanged(other.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("%w; rerun with --force to merge the changes", err)
+			}
+		}
+		linked = append(linked, linkedEdit{path: other.FilePath, count: len(indexes)})
+	}
+	return linked, nil
+}
+
+// saveLinked saves path and the files linked to it before undo or redo moves
+// them, so the move can be rolled back if a linked file fails. Nothing is
+// saved if there are no linked files.
+func (u *UndoEditor) saveLinked(path string, linked []linkedEdit) (savedStates, error) {
+	if len(linked) == 0 {
+		return nil, nil
+	}
+
+	paths := []string{path}
+	for _, link := range linked {
+		paths = append(paths, link.path)
+	}
+	return u.saveStates(paths)
+}
@@ -0,0 +1,117 @@
+package undo_edit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func TestUndoEditor_RecordGroup(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	c := filepath.Join(tmpDir, "c.txt")
+	require.NoError(t, os.WriteFile(a, []byte("a2\n"), 0o644))
+	require.NoError(t, os.WriteFile(c, []byte("c2\n"), 0o644))
+	require.NoError(t, u.RecordGroup("apply_patch", []FileChange{
+		{Path: a, Before: "a1\n", After: "a2\n"},
+		{Path: b, Before: "b1\n", Deleted: true},
+		{Path: c, After: "c2\n", Created: true},
+	}))
+
+	readFile := func(path string) string {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	_, err := u.UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.Equal(t, "a1\n", readFile(a))
+	assert.Equal(t, "b1\n", readFile(b))
+	assert.NoFileExists(t, c)
+
+	_, err = u.RedoEdit(b, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.Equal(t, "a2\n", readFile(a))
+	assert.NoFileExists(t, b)
+	assert.Equal(t, "c2\n", readFile(c))
+
+	// A later edit of one file keeps the group from being undone from another.
+	require.NoError(t, os.WriteFile(c, []byte("c3\n"), 0o644))
+	require.NoError(t, u.RecordEdit(c, "str_replace", "c2\n", "c3\n"))
+	_, err = u.UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has been edited since")
+	assert.Equal(t, "a2\n", readFile(a))
+
+	// A change made outside of eddie to a linked file stops the undo too.
+	_, err = u.UndoEdit(c, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(c, []byte("changed\n"), 0o644))
+	_, err = u.UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modified since last tracked edit")
+	assert.Equal(t, "a2\n", readFile(a))
+}
+
+func TestUndoEditor_RecordGroup_RollsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	u := &UndoEditor{}
+
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	c := filepath.Join(tmpDir, "c.txt")
+	require.NoError(t, os.WriteFile(a, []byte("a2\n"), 0o644))
+	require.NoError(t, os.WriteFile(c, []byte("c2\n"), 0o644))
+	require.NoError(t, u.RecordGroup("apply_patch", []FileChange{
+		{Path: a, Before: "a1\n", After: "a2\n"},
+		{Path: b, Before: "b1\n", Deleted: true},
+		{Path: c, After: "c2\n", Created: true},
+	}))
+
+	// Recreating b fails after a has been undone.
+	snapshotDir, err := u.getSnapshotDir()
+	require.NoError(t, err)
+	snapshot := filepath.Join(snapshotDir, hashContent("b1\n"))
+	require.NoError(t, os.Remove(snapshot))
+
+	_, err = u.UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undo linked edit of "+b)
+
+	content, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "a2\n", string(content))
+	assert.NoFileExists(t, b)
+	assert.FileExists(t, c)
+
+	// With the snapshot back, the group undoes from where it was.
+	_, err = u.storeSnapshot("b1\n")
+	require.NoError(t, err)
+	_, err = u.UndoEdit(a, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "a1\n", string(content))
+	assert.FileExists(t, b)
+	assert.NoFileExists(t, c)
+}
@@ -0,0 +1,270 @@
+package undo_edit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+// summaryLines caps how many removed and added lines ShowHistory prints for
+// each edit.
+const summaryLines = 3
+
+func (u *UndoEditor) RedoEdit(path string, showChanges, showResult bool, count int, force bool, pre fileops.Precondition) (*output.Edits, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be greater than 0")
+	}
+
+	linked, err := u.linkedEdits(path, count, false, force)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := u.saveLinked(path, linked)
+	if err != nil {
+		return nil, err
+	}
+
+	edit, err := u.travel(path, showChanges, showResult, force, pre, func(history *EditHistory) (int, error) {
+		undone := len(history.Edits) - history.Current
+		if undone == 0 {
+			return 0, fmt.Errorf("no undone edits to redo for %s", path)
+		}
+		if count > undone {
+			return 0, fmt.Errorf("cannot redo %d edits, only %d edits available", count, undone)
+		}
+		return history.Current + count, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &output.Edits{Edits: []*output.Edit{moved(edit, "redo_edit", "Redid", count)}}
+
+	for _, link := range linked {
+		edit, err = u.travel(link.path, showChanges, showResult, force, fileops.Precondition{}, func(history *EditHistory) (int, error) {
+			return history.Current + link.count, nil
+		})
+		if err != nil {
+			return nil, saved.rollback(fmt.Errorf("redo linked edit of %s: %w", link.path, err))
+		}
+		res.Edits = append(res.Edits, moved(edit, "redo_edit", "Redid", link.count))
+	}
+	return res, output.Print(u.out, res)
+}
+
+// GotoEdit restores path to its state right after edit index of its history,
+// where 0 is the content before the first recorded edit. Edits after index
+// stay in the history and can be redone. Unlike UndoEdit and RedoEdit, only
+// path is moved, even if its edits were made together with other files.
+func (u *UndoEditor) GotoEdit(path string, index int, showChanges, showResult, force bool, pre fileops.Precondition) (*output.Edit, error) {
+	edit, err := u.travel(path, showChanges, showResult, force, pre, func(history *EditHistory) (int, error) {
+		if len(history.Edits) == 0 {
+			return 0, fmt.Errorf("no edit records found for %s", path)
+		}
+		if index < 0 || index > len(history.Edits) {
+			return 0, fmt.Errorf("edit index %d is out of range (0-%d)", index, len(history.Edits))
+		}
+		return index, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	edit.Command = "history"
+	edit.Summary = fmt.Sprintf("Moved %s to edit %d", path, index)
+	return edit, output.Print(u.out, edit)
+}
+
+// History is the timeline of edits to a file as ShowHistory lists it.
+// Missing is set if the file did not exist before its first edit, and
+// Current is the index of the edit the file is at. Warnings are problems
+// found while reading an older history.
+type History struct {
+	Path    string         `json:"path"`
+	Current int            `json:"current"`
+	Missing bool           `json:"missing,omitempty"`
+	Edits   []HistoryEntry `json:"edits"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// HistoryEntry is one edit in a History, with a short diff of what it
+// changed, or Error if the diff could not be made.
+type HistoryEntry struct {
+	Index     int       `json:"index"`
+	Timestamp time.Time `json:"timestamp"`
+	EditType  string    `json:"edit_type"`
+	Event     string    `json:"event,omitempty"`
+	Undone    bool      `json:"undone,omitempty"`
+	Summary   []string  `json:"summary,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (h *History) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "History of %s (%d edits, at %d):\n", h.Path, len(h.Edits), h.Current)
+	original := "original"
+	if h.Missing {
+		original = "original (file did not exist)"
+	}
+	fmt.Fprintf(&b, "%s 0  %s\n", h.marker(0), original)
+	for _, edit := range h.Edits {
+		status := ""
+		if edit.Undone {
+			status = " (undone)"
+		}
+		event := ""
+		if edit.Event != "" {
+			event = " [" + edit.Event + "]"
+		}
+		fmt.Fprintf(&b, "%s %d  %s  %s%s%s\n", h.marker(edit.Index), edit.Index,
+			edit.Timestamp.Format(time.RFC3339), edit.EditType, event, status)
+
+		if edit.Error != "" {
+			fmt.Fprintf(&b, "      %s\n", edit.Error)
+			continue
+		}
+		for _, line := range edit.Summary {
+			fmt.Fprintf(&b, "      %s\n", line)
+		}
+	}
+	for _, warning := range h.Warnings {
+		b.WriteString("Warning: " + warning + "\n")
+	}
+	return b.String()
+}
+
+func (h *History) marker(index int) string {
+	if index == h.Current {
+		return "*"
+	}
+	return " "
+}
+
+// ShowHistory lists every recorded edit of path with its index, timestamp,
+// type and a short diff. The entry marked with * is the current state.
+func (u *UndoEditor) ShowHistory(path string) (*History, error) {
+	editPath, err := u.getEditFilePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("get edit file path: %w", err)
+	}
+
+	history, err := u.readEditHistory(editPath)
+	if err != nil {
+		return nil, fmt.Errorf("read edit history %s: %w", editPath, err)
+	}
+
+	var warnings []string
+	if history.Version < historyVersion {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		// A file changed since its last legacy edit is only migrated for
+		// display, so undo can still refuse to adopt the changes.
+		persist := true
+		if info, err := os.Stat(path); err == nil {
+			if err := checkLegacyModTime(path, history, info.ModTime()); err != nil {
+				warnings = append(warnings, err.Error()+"; the history is migrated by an undo with --force")
+				persist = false
+			}
+		}
+
+		dropped, err := u.migrateHistory(history, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("migrate edit history: %w", err)
+		}
+		warnings = append(warnings, dropped...)
+		if persist {
+			err = u.writeEditHistory(editPath, history)
+			if err != nil {
+				return nil, fmt.Errorf("write updated edit history: %w", err)
+			}
+		}
+	}
+
+	if len(history.Edits) == 0 {
+		return nil, fmt.Errorf("no edit records found for %s", path)
+	}
+
+	res := &History{
+		Path:    path,
+		Current: history.Current,
+		Missing: history.Edits[0].Before == "",
+		Edits:   make([]HistoryEntry, len(history.Edits)),
+
+		Warnings: warnings,
+	}
+	for i, edit := range history.Edits {
+		entry := HistoryEntry{
+			Index:     i + 1,
+			Timestamp: edit.Timestamp,
+			EditType:  edit.EditType,
+			Event:     edit.Event,
+			Undone:    i+1 > history.Current,
+		}
+		entry.Summary, err = u.summarizeEdit(&edit)
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		res.Edits[i] = entry
+	}
+	return res, output.Print(u.out, res)
+}
+
+// summarizeEdit returns a short diff of an edit: the changed block between the
+// lines both snapshots share at the start and end, truncated to summaryLines
+// removed and added lines. A missing file counts as empty.
+func (u *UndoEditor) summarizeEdit(edit *EditRecord) ([]string, error) {
+	var before, after string
+	var err error
+	if edit.Before != "" {
+		before, err = u.loadSnapshot(edit.Before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if edit.After != "" {
+		after, err = u.loadSnapshot(edit.After)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	beforeLines, _ := fileops.SplitLines(before)
+	afterLines, _ := fileops.SplitLines(after)
+
+	prefix := 0
+	for prefix < len(beforeLines) && prefix < len(afterLines) && beforeLines[prefix] == afterLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(beforeLines)-prefix && suffix < len(afterLines)-prefix &&
+		beforeLines[len(beforeLines)-1-suffix] == afterLines[len(afterLines)-1-suffix] {
+		suffix++
+	}
+
+	removed := beforeLines[prefix : len(beforeLines)-suffix]
+	added := afterLines[prefix : len(afterLines)-suffix]
+
+	summary := []string{fmt.Sprintf("@@ line %d: -%d +%d @@", prefix+1, len(removed), len(added))}
+	summary = append(summary, u.summarizeLines("-", removed)...)
+	summary = append(summary, u.summarizeLines("+", added)...)
+	return summary, nil
+}
+
+func (u *UndoEditor) summarizeLines(prefix string, lines []string) []string {
+	summary := make([]string, 0, summaryLines+1)
+	for i, line := range lines {
+		if i == summaryLines {
+			summary = append(summary, fmt.Sprintf("%s... %d more", prefix, len(lines)-summaryLines))
+			break
+		}
+		summary = append(summary, prefix+strings.TrimRight(line, "\r"))
+	}
+	return summary
+}
@@ -0,0 +1,247 @@
+package undo_edit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func recordVersions(t *testing.T, u *UndoEditor, path string, versions ...string) {
+	require.NoError(t, os.WriteFile(path, []byte(versions[0]), 0o644))
+	for i := 1; i < len(versions); i++ {
+		require.NoError(t, os.WriteFile(path, []byte(versions[i]), 0o644))
+		require.NoError(t, u.RecordEdit(path, "str_replace", versions[i-1], versions[i]))
+	}
+}
+
+func TestUndoEditor_RedoEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	recordVersions(t, u, testFile, "v1\n", "v2\n", "v3\n")
+
+	_, err := u.UndoEdit(testFile, false, false, 2, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "v1\n", string(content))
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "v2\n", string(content))
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "v3\n", string(content))
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no undone edits to redo")
+}
+
+func TestUndoEditor_RedoEdit_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	recordVersions(t, u, testFile, "v1\n", "v2\n", "v3\n")
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		count   int
+		wantErr string
+	}{
+		{
+			name:    "zero count",
+			count:   0,
+			wantErr: "count must be greater than 0",
+		},
+		{
+			name:    "more than undone",
+			count:   2,
+			wantErr: "cannot redo 2 edits, only 1 edits available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.RedoEdit(testFile, false, false, tt.count, false, fileops.Precondition{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestUndoEditor_RecordEdit_DiscardsRedo(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	recordVersions(t, u, testFile, "v1\n", "v2\n", "v3\n")
+	_, err := u.UndoEdit(testFile, false, false, 2, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(testFile, []byte("other\n"), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "insert", "v1\n", "other\n"))
+
+	editPath, err := u.getEditFilePath(testFile)
+	require.NoError(t, err)
+	history, err := u.readEditHistory(editPath)
+	require.NoError(t, err)
+	assert.Len(t, history.Edits, 1)
+	assert.Equal(t, 1, history.Current)
+	assert.Equal(t, "insert", history.Edits[0].EditType)
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no undone edits to redo")
+}
+
+func TestUndoEditor_GotoEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	recordVersions(t, u, testFile, "v1\n", "v2\n", "v3\n", "v4\n")
+
+	tests := []struct {
+		name        string
+		index       int
+		wantContent string
+		wantErr     string
+	}{
+		{name: "original", index: 0, wantContent: "v1\n"},
+		{name: "forward", index: 2, wantContent: "v3\n"},
+		{name: "latest", index: 3, wantContent: "v4\n"},
+		{name: "backward", index: 1, wantContent: "v2\n"},
+		{name: "out of range", index: 4, wantContent: "v2\n", wantErr: "edit index 4 is out of range (0-3)"},
+		{name: "negative", index: -1, wantContent: "v2\n", wantErr: "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.GotoEdit(testFile, tt.index, false, false, false, fileops.Precondition{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			content, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(content))
+		})
+	}
+}
+
+func TestUndoEditor_ShowHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	recordVersions(t, u, testFile,
+		"a\nb\nc\n",
+		"a\nB\nc\n",
+		"a\nB\n1\n2\n3\n4\n5\nc\n",
+	)
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	res, err := u.ShowHistory(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Current)
+	assert.True(t, res.Edits[1].Undone)
+	output := res.String()
+
+	assert.Contains(t, output, "(2 edits, at 1)")
+	assert.Contains(t, output, "  0  original\n")
+	assert.Regexp(t, `\* 1  \S+  str_replace\n`, output)
+	assert.Regexp(t, `  2  \S+  str_replace \(undone\)\n`, output)
+	assert.Contains(t, output, "@@ line 2: -1 +1 @@\n      -b\n      +B\n")
+	assert.Contains(t, output, "@@ line 3: -0 +5 @@\n      +1\n      +2\n      +3\n      +... 2 more\n")
+}
+
+func TestUndoEditor_ShowHistory_NoHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("content\n"), 0o644))
+
+	_, err := u.ShowHistory(testFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read edit history")
+}
@@ -0,0 +1,118 @@
+package undo_edit
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// historyVersion is the current EditHistory format. Version 0 histories
+// stored the replaced strings of each edit and were undone by reapplying them
+// in reverse; version 1 histories store a snapshot of the whole file before
+// and after every edit; version 2 histories keep undone edits for redo.
+const historyVersion = 2
+
+// migrateHistory upgrades history to historyVersion. latest is the file
+// content after the most recent edit. It returns warnings about edits that
+// could not be migrated and were dropped.
+func (u *UndoEditor) migrateHistory(history *EditHistory, latest string) ([]string, error) {
+	var warnings []string
+	if history.Version < 1 {
+		var err error
+		warnings, err = u.migrateToSnapshots(history, latest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if history.Version < 2 {
+		history.Current = len(history.Edits)
+		for i := range history.Edits {
+			history.Edits[i].FileModTime = nil
+		}
+	}
+
+	history.Version = historyVersion
+	return warnings, nil
+}
+
+// checkLegacyModTime returns a *ModifiedError if history, not yet migrated,
+// recorded a modification time after its last edit other than modTime, the
+// modification time of path. The content of path becomes the state after
+// that edit when the history is migrated, so the check keeps changes made
+// outside of eddie from being adopted as part of it.
+func checkLegacyModTime(path string, history *EditHistory, modTime time.Time) error {
+	if history.Version >= 2 || len(history.Edits) == 0 {
+		return nil
+	}
+	recorded := history.Edits[len(history.Edits)-1].FileModTime
+	if recorded == nil || recorded.Equal(modTime) {
+		return nil
+	}
+	return &ModifiedError{Path: path}
+}
+
+// upgradeHistory migrates a history read from editPath and saves it. A
+// history whose file changed since its last legacy edit is left alone, since
+// only undo with --force may treat the changes as the result of that edit.
+func (u *UndoEditor) upgradeHistory(editPath string, history *EditHistory) ([]string, error) {
+	content, err := os.ReadFile(history.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if info, err := os.Stat(history.FilePath); err == nil {
+		err = checkLegacyModTime(history.FilePath, history, info.ModTime())
+		if err != nil {
+			return nil, fmt.Errorf("%w; undo_edit with --force migrates its history", err)
+		}
+	}
+
+	warnings, err := u.migrateHistory(history, string(content))
+	if err != nil {
+		return nil, fmt.Errorf("migrate edit history: %w", err)
+	}
+	err = u.writeEditHistory(editPath, history)
+	if err != nil {
+		return nil, fmt.Errorf("write updated edit history: %w", err)
+	}
+	return warnings, nil
+}
+
+// migrateToSnapshots rebuilds the snapshots of a version 0 history by
+// reversing edits newest first. If an edit cannot be reversed, it and every
+// older edit are dropped because their content can no longer be
+// reconstructed, and a warning says so.
+func (u *UndoEditor) migrateToSnapshots(history *EditHistory, latest string) ([]string, error) {
+	var warnings []string
+	current := latest
+	first := 0
+	for i := len(history.Edits) - 1; i >= 0; i-- {
+		record := &history.Edits[i]
+
+		previous, err := u.reverseLegacyEdit(current, record)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Dropped %d edit(s) of %s from before snapshot history: %v", i+1, history.FilePath, err))
+			first = i + 1
+			break
+		}
+
+		after, err := u.storeSnapshot(current)
+		if err != nil {
+			return nil, fmt.Errorf("store snapshot: %w", err)
+		}
+		before, err := u.storeSnapshot(previous)
+		if err != nil {
+			return nil, fmt.Errorf("store snapshot: %w", err)
+		}
+
+		record.Before = before
+		record.After = after
+		record.OldContent = ""
+		record.NewContent = ""
+		record.Position = 0
+		current = previous
+	}
+
+	history.Edits = history.Edits[first:]
+	return warnings, nil
+}
@@ -0,0 +1,225 @@
+package undo_edit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/output"
+)
+
+// ListHistories returns the edit history of every tracked file whose path is
+// root or inside it. Histories in an older format are migrated and saved.
+// Histories that cannot be read or migrated are skipped with a warning, and
+// those written before paths were stored as absolute are skipped silently.
+func (u *UndoEditor) ListHistories(root string) ([]*EditHistory, []string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get absolute path: %w", err)
+	}
+
+	editDir, err := u.getEditDir()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get edit directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(editDir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read edit directory %s: %w", editDir, err)
+	}
+
+	var histories []*EditHistory
+	var warnings []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		editPath := filepath.Join(editDir, entry.Name())
+		history, err := u.readEditHistory(editPath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Skipped edit history %s: %v", editPath, err))
+			continue
+		}
+		if !filepath.IsAbs(history.FilePath) || !isWithin(absRoot, history.FilePath) {
+			continue
+		}
+		if history.Version < historyVersion {
+			dropped, err := u.upgradeHistory(editPath, history)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("Skipped edit history of %s: %v", history.FilePath, err))
+				continue
+			}
+			warnings = append(warnings, dropped...)
+		}
+		if len(history.Edits) == 0 {
+			continue
+		}
+
+		histories = append(histories, history)
+	}
+
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].FilePath < histories[j].FilePath
+	})
+	return histories, warnings, nil
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// CheckUnchanged returns an error if path was changed by something other than
+// eddie since its last tracked edit.
+func (u *UndoEditor) CheckUnchanged(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read file: %w", err)
+	}
+	exists := err == nil
+
+	editPath, err := u.getEditFilePath(path)
+	if err != nil {
+		return fmt.Errorf("get edit file path: %w", err)
+	}
+
+	history, err := u.readEditHistory(editPath)
+	if err != nil {
+		return fmt.Errorf("read edit history %s: %w", editPath, err)
+	}
+
+	return u.checkUnchanged(path, history, string(content), exists)
+}
+
+// RestoreState sets path to the snapshot hash, or deletes it along with the
+// directories made when it was created if hash is empty. The change is
+// recorded as a new edit of type editType so it can be undone like any other.
+// The change is returned, with its diff if showChanges is set, and nil if
+// path already is at hash.
+func (u *UndoEditor) RestoreState(path, hash, editType string, showChanges bool) (*output.Edit, error) {
+	err := u.CheckUnchanged(path)
+	if err != nil {
+		return nil, err
+	}
+
+	editPath, err := u.getEditFilePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("get edit file path: %w", err)
+	}
+
+	history, err := u.readEditHistory(editPath)
+	if err != nil {
+		return nil, fmt.Errorf("read edit history %s: %w", editPath, err)
+	}
+
+	current := history.State()
+	if current == hash {
+		return nil, nil
+	}
+
+	beforeContent := ""
+	mode := os.FileMode(0o644)
+	if current != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", path, err)
+		}
+		mode = info.Mode()
+
+		beforeContent, err = u.loadSnapshot(current)
+		if err != nil {
+			return nil, fmt.Errorf("load snapshot: %w", err)
+		}
+	}
+
+	var createdDirs []string
+	for i := history.Current - 1; i >= 0; i-- {
+		if history.Edits[i].Event == EventCreated {
+			createdDirs = history.Edits[i].CreatedDirs
+			break
+		}
+	}
+
+	restored, err := u.writeState(path, hash, mode, current != "", createdDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := EditRecord{
+		EditType: editType,
+		Before:   current,
+		After:    hash,
+	}
+	switch {
+	case current == "":
+		edit.Event = EventCreated
+	case hash == "":
+		edit.Event = EventDeleted
+	}
+
+	err = u.appendEdit(path, edit, beforeContent)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &output.Edit{Command: editType, Path: path, Created: current == "", Deleted: hash == ""}
+	if showChanges {
+		unified := display.Diff(path, beforeContent, restored)
+		if res.Created {
+			unified = display.NewFileDiff(path, restored)
+		}
+		res.Diff = &unified
+	}
+	return res, nil
+}
+
+// RestoreStates sets each path in paths to its snapshot in targets, as
+// RestoreState does, but all or nothing: every file is checked before any is
+// written, and the files already restored are rolled back if one fails.
+func (u *UndoEditor) RestoreStates(paths []string, targets map[string]string, editType string, showChanges bool) ([]*output.Edit, error) {
+	for _, path := range paths {
+		err := u.CheckUnchanged(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err := u.saveStates(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []*output.Edit
+	for _, path := range paths {
+		edit, err := u.RestoreState(path, targets[path], editType, showChanges)
+		if err != nil {
+			return nil, saved.rollback(fmt.Errorf("restore %s: %w", path, err))
+		}
+		if edit != nil {
+			edits = append(edits, edit)
+		}
+	}
+	return edits, nil
+}
+
+// StateBefore returns the snapshot of the file before its first edit recorded
+// after t, and false if no edit was recorded after t.
+func (h *EditHistory) StateBefore(t time.Time) (string, bool) {
+	for _, edit := range h.Edits {
+		if edit.Timestamp.After(t) {
+			return edit.Before, true
+		}
+	}
+	return "", false
+}
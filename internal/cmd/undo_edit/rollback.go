@@ -0,0 +1,93 @@
+package undo_edit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+// savedState is a file and its edit history as they were before a change of
+// several files, so the change can be rolled back if a later file fails.
+// History is nil if the file had no edit history.
+type savedState struct {
+	path     string
+	exists   bool
+	content  []byte
+	mode     os.FileMode
+	editPath string
+	history  []byte
+}
+
+type savedStates []savedState
+
+// saveStates saves paths and their edit histories before they are changed.
+func (u *UndoEditor) saveStates(paths []string) (savedStates, error) {
+	var saved savedStates
+	for _, path := range paths {
+		state := savedState{path: path, mode: 0o644}
+
+		info, err := os.Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("stat %s: %w", path, err)
+		}
+		if err == nil {
+			state.exists = true
+			state.mode = info.Mode()
+			state.content, err = os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read file: %w", err)
+			}
+		}
+
+		state.editPath, err = u.getEditFilePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("get edit file path: %w", err)
+		}
+		state.history, err = os.ReadFile(state.editPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read edit history %s: %w", state.editPath, err)
+		}
+
+		saved = append(saved, state)
+	}
+	return saved, nil
+}
+
+// rollback puts every saved file and edit history back, last first, and
+// returns err, the failure that stopped the change, along with any error
+// from rolling back.
+func (s savedStates) rollback(err error) error {
+	fileOps := &fileops.FileOps{}
+	var errs []error
+	for i := len(s) - 1; i >= 0; i-- {
+		state := s[i]
+
+		if state.exists {
+			err := os.MkdirAll(filepath.Dir(state.path), 0o755)
+			if err == nil {
+				err = fileOps.WriteFileContent(state.path, string(state.content), state.mode)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("roll back %s: %w", state.path, err))
+			}
+		} else if err := os.Remove(state.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("roll back %s: %w", state.path, err))
+		}
+
+		if state.history != nil {
+			err := fileops.WriteFileAtomic(state.editPath, state.history, 0o644)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("roll back edit history %s: %w", state.editPath, err))
+			}
+		} else if err := os.Remove(state.editPath); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("roll back edit history %s: %w", state.editPath, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w; rolling back failed: %w", err, errors.Join(errs...))
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package undo_edit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+// Snapshots are stored once per distinct file content, named by the SHA-256
+// of the content, so consecutive edits share the snapshot between them.
+
+func (u *UndoEditor) getSnapshotDir() (string, error) {
+	editDir, err := u.getEditDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(editDir), "snapshots"), nil
+}
+
+func hashContent(content string) string {
+	return fileops.HashContent(content)
+}
+
+func (u *UndoEditor) storeSnapshot(content string) (string, error) {
+	hash := hashContent(content)
+
+	snapshotDir, err := u.getSnapshotDir()
+	if err != nil {
+		return "", fmt.Errorf("get snapshot directory: %w", err)
+	}
+
+	snapshotPath := filepath.Join(snapshotDir, hash)
+	if _, err := os.Stat(snapshotPath); err == nil {
+		return hash, nil
+	}
+
+	err = os.MkdirAll(snapshotDir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("create snapshot directory %s: %w", snapshotDir, err)
+	}
+
+	tmp, err := os.CreateTemp(snapshotDir, hash+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), snapshotPath); err != nil {
+		return "", fmt.Errorf("rename snapshot: %w", err)
+	}
+
+	return hash, nil
+}
+
+func (u *UndoEditor) loadSnapshot(hash string) (string, error) {
+	snapshotDir, err := u.getSnapshotDir()
+	if err != nil {
+		return "", fmt.Errorf("get snapshot directory: %w", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(snapshotDir, hash))
+	if err != nil {
+		return "", fmt.Errorf("read snapshot %s: %w", hash, err)
+	}
+
+	if hashContent(string(content)) != hash {
+		return "", fmt.Errorf("snapshot %s is corrupt", hash)
+	}
+
+	return string(content), nil
+}
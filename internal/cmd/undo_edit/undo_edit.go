@@ -1,7 +1,27 @@
 package undo_edit
 
-import "os"
+import (
+	"os"
 
-func UndoEdit(path string, showChanges, showResult bool, count int) error {
-	return NewUndoEditor(os.Stdout).UndoEdit(path, showChanges, showResult, count)
+	"github.com/RRethy/eddie/internal/fileops"
+)
+
+func UndoEdit(path string, showChanges, showResult bool, count int, force bool, pre fileops.Precondition) error {
+	_, err := NewUndoEditor(os.Stdout).UndoEdit(path, showChanges, showResult, count, force, pre)
+	return err
+}
+
+func RedoEdit(path string, showChanges, showResult bool, count int, force bool, pre fileops.Precondition) error {
+	_, err := NewUndoEditor(os.Stdout).RedoEdit(path, showChanges, showResult, count, force, pre)
+	return err
+}
+
+func GotoEdit(path string, index int, showChanges, showResult, force bool, pre fileops.Precondition) error {
+	_, err := NewUndoEditor(os.Stdout).GotoEdit(path, index, showChanges, showResult, force, pre)
+	return err
+}
+
+func ShowHistory(path string) error {
+	_, err := NewUndoEditor(os.Stdout).ShowHistory(path)
+	return err
 }
@@ -3,138 +3,465 @@ package undo_edit
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/RRethy/eddie/internal/diff"
 	"github.com/RRethy/eddie/internal/display"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 type UndoEditor struct {
-	display *display.Display
+	fileOps *fileops.FileOps
+	out     io.Writer
 }
 
 func NewUndoEditor(w io.Writer) *UndoEditor {
 	return &UndoEditor{
-		display: display.New(w),
+		fileOps: &fileops.FileOps{},
+		out:     w,
 	}
 }
 
+// Lifecycle events of a file, as opposed to edits of its content.
+const (
+	EventCreated = "created"
+	EventDeleted = "deleted"
+	EventRenamed = "renamed"
+)
+
+// EditRecord describes one edit. Before and After name the snapshots of the
+// whole file around the edit, so undoing restores byte-identical content. An
+// empty Before or After means the file did not exist on that side of the
+// edit, which is the case for lifecycle events.
 type EditRecord struct {
-	Timestamp   time.Time `json:"timestamp"`
-	FileModTime time.Time `json:"file_mod_time"`
-	EditType    string    `json:"edit_type"`
-	OldContent  string    `json:"old_content"`
-	NewContent  string    `json:"new_content"`
-	Position    int       `json:"position"`
+	Timestamp time.Time `json:"timestamp"`
+	EditType  string    `json:"edit_type"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+
+	// Event is set for edits that created, deleted or renamed the file.
+	// CreatedDirs lists the parent directories a created event made,
+	// outermost first, and is removed again when it is undone. For renamed
+	// events, From and To are the old and new paths of the file.
+	Event       string   `json:"event,omitempty"`
+	CreatedDirs []string `json:"created_dirs,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+
+	// Group is shared by the edits of several files made by one operation,
+	// which undo and redo move together.
+	Group string `json:"group,omitempty"`
+
+	// Fields written by older histories, cleared by migrateHistory.
+	OldContent  string     `json:"old_content,omitempty"`
+	NewContent  string     `json:"new_content,omitempty"`
+	Position    int        `json:"position,omitempty"`
+	FileModTime *time.Time `json:"file_mod_time,omitempty"`
 }
 
+// EditHistory is the timeline of edits to one file. The first Current edits
+// are applied; the rest have been undone and can be redone until a new edit
+// is recorded.
 type EditHistory struct {
+	Version  int          `json:"version"`
 	FilePath string       `json:"file_path"`
+	Current  int          `json:"current"`
 	Edits    []EditRecord `json:"edits"`
+
+	// Merged is the snapshot of the file after a forced undo or redo merged
+	// changes made outside of eddie into the state at Current. Later moves
+	// through the timeline keep merging those changes until the next edit.
+	Merged string `json:"merged,omitempty"`
 }
 
-func (u *UndoEditor) UndoEdit(path string, showChanges, showResult bool, count int) error {
+func (u *UndoEditor) UndoEdit(path string, showChanges, showResult bool, count int, force bool, pre fileops.Precondition) (*output.Edits, error) {
 	if count <= 0 {
-		return fmt.Errorf("count must be greater than 0")
+		return nil, fmt.Errorf("count must be greater than 0")
+	}
+
+	linked, err := u.linkedEdits(path, count, true, force)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := u.saveLinked(path, linked)
+	if err != nil {
+		return nil, err
+	}
+
+	edit, err := u.travel(path, showChanges, showResult, force, pre, func(history *EditHistory) (int, error) {
+		if len(history.Edits) == 0 {
+			return 0, fmt.Errorf("no edit records found for %s", path)
+		}
+		if count > history.Current {
+			return 0, fmt.Errorf("cannot undo %d edits, only %d edits available", count, history.Current)
+		}
+		return history.Current - count, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	res := &output.Edits{Edits: []*output.Edit{moved(edit, "undo_edit", "Undid", count)}}
+
+	for _, link := range linked {
+		edit, err = u.travel(link.path, showChanges, showResult, force, fileops.Precondition{}, func(history *EditHistory) (int, error) {
+			return history.Current - link.count, nil
+		})
+		if err != nil {
+			return nil, saved.rollback(fmt.Errorf("undo linked edit of %s: %w", link.path, err))
+		}
+		res.Edits = append(res.Edits, moved(edit, "undo_edit", "Undid", link.count))
+	}
+	return res, output.Print(u.out, res)
+}
+
+// moved fills in edit, the result of moving its file count edits through its
+// timeline with command.
+func moved(edit *output.Edit, command, verb string, count int) *output.Edit {
+	edit.Command = command
+	edit.Count = count
+	if count == 1 {
+		edit.Summary = fmt.Sprintf("%s 1 edit in %s", verb, edit.Path)
+	} else {
+		edit.Summary = fmt.Sprintf("%s %d edits in %s", verb, count, edit.Path)
+	}
+	return edit
+}
+
+// travel moves path to the point in its timeline chosen by target, which is
+// given the history and returns the number of edits that should be applied.
+// If the file was changed outside of eddie, travel fails unless force is set,
+// in which case the move is merged with those changes. The file must also
+// satisfy pre. The move is returned as an edit without a command or summary.
+func (u *UndoEditor) travel(path string, showChanges, showResult, force bool, pre fileops.Precondition, target func(*EditHistory) (int, error)) (*output.Edit, error) {
 	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %s", path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
 	}
+	exists := err == nil
 
 	editPath, err := u.getEditFilePath(path)
 	if err != nil {
-		return fmt.Errorf("get edit file path: %w", err)
+		return nil, fmt.Errorf("get edit file path: %w", err)
 	}
 
 	editHistory, err := u.readEditHistory(editPath)
 	if err != nil {
-		return fmt.Errorf("read edit history %s: %w", editPath, err)
+		if !exists {
+			return nil, fmt.Errorf("file does not exist: %s", path)
+		}
+		return nil, fmt.Errorf("read edit history %s: %w", editPath, err)
 	}
 
-	if len(editHistory.Edits) == 0 {
-		return fmt.Errorf("no edit records found for %s", path)
+	beforeContent := ""
+	mode := os.FileMode(0o644)
+	if exists {
+		current, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		beforeContent = string(current)
+		mode = info.Mode()
 	}
 
-	if count > len(editHistory.Edits) {
-		return fmt.Errorf("cannot undo %d edits, only %d edits available", count, len(editHistory.Edits))
+	if exists {
+		err = pre.Check(path, beforeContent, info)
+	} else if pre != (fileops.Precondition{}) {
+		err = fmt.Errorf("%w: %s does not exist", fileops.ErrConflict, path)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	var beforeContent string
-	if showChanges {
-		content, err := os.ReadFile(path)
+	var warnings []string
+	if editHistory.Version < historyVersion {
+		if exists {
+			err = checkLegacyModTime(path, editHistory, info.ModTime())
+			if err != nil && !force {
+				return nil, fmt.Errorf("%w; rerun with --force to treat the current content as the result of the last edit", err)
+			}
+		}
+		warnings, err = u.migrateHistory(editHistory, beforeContent)
 		if err != nil {
-			return fmt.Errorf("read file before undo: %w", err)
+			return nil, fmt.Errorf("migrate edit history: %w", err)
 		}
-		beforeContent = string(content)
 	}
 
-	for i := 0; i < count; i++ {
-		lastEditIndex := len(editHistory.Edits) - 1
-		editRecord := editHistory.Edits[lastEditIndex]
+	index, err := target(editHistory)
+	if err != nil {
+		return nil, err
+	}
 
-		if i == 0 {
-			if !info.ModTime().Equal(editRecord.FileModTime) {
-				return fmt.Errorf("file has been modified since last tracked edit (expected: %v, actual: %v)",
-					editRecord.FileModTime.Format(time.RFC3339), info.ModTime().Format(time.RFC3339))
-			}
+	err = u.checkUnchanged(path, editHistory, beforeContent, exists)
+	if errors.Is(err, errModified) {
+		if !force {
+			return nil, fmt.Errorf("%w; rerun with --force to merge the changes", err)
 		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		err = u.applyReverseEdit(path, &editRecord)
+	var restored string
+	targetHash := editHistory.StateAt(index)
+	if exists && hashContent(beforeContent) != editHistory.StateAt(editHistory.Current) {
+		restored, err = u.mergeState(path, editHistory, index, beforeContent, mode)
 		if err != nil {
-			return fmt.Errorf("apply reverse edit %d: %w", i+1, err)
+			return nil, err
+		}
+	} else {
+		var createdDirs []string
+		if index < len(editHistory.Edits) && editHistory.Edits[index].Event == EventCreated {
+			createdDirs = editHistory.Edits[index].CreatedDirs
 		}
 
-		editHistory.Edits = editHistory.Edits[:lastEditIndex]
-	}
-
-	if showChanges || showResult {
-		afterContent, err := os.ReadFile(path)
+		restored, err = u.writeState(path, targetHash, mode, exists, createdDirs)
 		if err != nil {
-			return fmt.Errorf("read file after undo: %w", err)
+			return nil, err
 		}
-		if showChanges {
-			u.display.ShowDiff(path, beforeContent, string(afterContent))
+		editHistory.Merged = ""
+	}
+
+	editHistory.Current = index
+
+	err = u.writeEditHistory(editPath, editHistory)
+	if err != nil {
+		return nil, fmt.Errorf("write updated edit history: %w", err)
+	}
+
+	edit := &output.Edit{Path: path, Created: !exists && targetHash != "", Deleted: exists && targetHash == "", Warnings: warnings}
+	if showChanges {
+		unified := display.Diff(path, beforeContent, restored)
+		if edit.Created {
+			unified = display.NewFileDiff(path, restored)
 		}
-		if showResult {
-			u.display.ShowResult(path, string(afterContent))
+		edit.Diff = &unified
+	}
+	if showResult && targetHash != "" {
+		edit.Content = &restored
+	}
+	return edit, nil
+}
+
+// mergeState moves path to edit index of history while keeping the changes
+// in content that were made outside of eddie, using a three-way merge with
+// the state at the current edit as the base.
+func (u *UndoEditor) mergeState(path string, history *EditHistory, index int, content string, mode os.FileMode) (string, error) {
+	baseHash, targetHash := history.StateAt(history.Current), history.StateAt(index)
+	if baseHash == "" || targetHash == "" {
+		return "", fmt.Errorf("cannot merge changes made outside of eddie into %s: the file would be created or deleted", path)
+	}
+
+	base, err := u.loadSnapshot(baseHash)
+	if err != nil {
+		return "", fmt.Errorf("load snapshot: %w", err)
+	}
+	theirs, err := u.loadSnapshot(targetHash)
+	if err != nil {
+		return "", fmt.Errorf("load snapshot: %w", err)
+	}
+
+	merged, conflicts := diff.Merge(base, content, theirs)
+	if len(conflicts) > 0 {
+		lines := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			if c.End > c.Start {
+				lines[i] = fmt.Sprintf("%d-%d", c.Start, c.End)
+			} else {
+				lines[i] = strconv.Itoa(c.Start)
+			}
 		}
+		return "", fmt.Errorf("cannot merge changes made outside of eddie into %s: conflicts at lines %s",
+			path, strings.Join(lines, ", "))
 	}
 
-	if len(editHistory.Edits) > 0 {
-		newInfo, err := os.Stat(path)
-		if err != nil {
-			return fmt.Errorf("stat file after undo: %w", err)
+	mergedHash, err := u.storeSnapshot(merged)
+	if err != nil {
+		return "", fmt.Errorf("store snapshot: %w", err)
+	}
+
+	err = u.fileOps.WriteFileContent(path, merged, mode)
+	if err != nil {
+		return "", err
+	}
+
+	history.Merged = ""
+	if mergedHash != targetHash {
+		history.Merged = mergedHash
+	}
+	return merged, nil
+}
+
+// errModified reports that a file no longer matches the current state of its
+// history, which means something other than eddie changed it.
+var errModified = errors.New("file has been modified since last tracked edit")
+
+// ModifiedError is the errModified error of Path. Diff is the unified diff
+// from the content eddie last recorded to the file, if it is known.
+type ModifiedError struct {
+	Path string
+	Diff string
+}
+
+func (e *ModifiedError) Error() string {
+	return fmt.Sprintf("%v: %s", errModified, e.Path)
+}
+
+func (e *ModifiedError) Unwrap() error {
+	return errModified
+}
+
+// Details shows the changes made outside of eddie, which output.ErrorDetails
+// adds to the message.
+func (e *ModifiedError) Details() string {
+	if e.Diff == "" {
+		return ""
+	}
+	return output.DiffSection(e.Path, e.Diff)
+}
+
+// checkUnchanged returns an error if content, the content of path, no longer
+// matches the current state of its history. If the file was modified, the
+// error is a *ModifiedError with the difference.
+func (u *UndoEditor) checkUnchanged(path string, history *EditHistory, content string, exists bool) error {
+	expected := history.State()
+	if expected == "" {
+		if exists {
+			return fmt.Errorf("file has been created since last tracked edit: %s", path)
 		}
+		return nil
+	}
+
+	if !exists {
+		return fmt.Errorf("file does not exist: %s", path)
+	}
+	if hashContent(content) == expected {
+		return nil
+	}
 
-		editHistory.Edits[len(editHistory.Edits)-1].FileModTime = newInfo.ModTime()
+	modified := &ModifiedError{Path: path}
+	expectedContent, err := u.loadSnapshot(expected)
+	if err == nil {
+		modified.Diff = display.Diff(path, expectedContent, content)
 	}
+	return modified
+}
 
-	if len(editHistory.Edits) == 0 {
-		err = os.Remove(editPath)
-		if err != nil {
-			return fmt.Errorf("remove empty edit file %s: %w", editPath, err)
+// writeState replaces path with the snapshot hash and returns its content. An
+// empty hash deletes path along with createdDirs, as long as they are empty.
+func (u *UndoEditor) writeState(path, hash string, mode os.FileMode, exists bool, createdDirs []string) (string, error) {
+	if hash == "" {
+		if !exists {
+			return "", nil
 		}
-	} else {
-		err = u.writeEditHistory(editPath, editHistory)
+
+		err := os.Remove(path)
 		if err != nil {
-			return fmt.Errorf("write updated edit history: %w", err)
+			return "", fmt.Errorf("remove file: %w", err)
+		}
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			if os.Remove(createdDirs[i]) != nil {
+				break
+			}
 		}
+		return "", nil
 	}
 
-	if count == 1 {
-		fmt.Printf("Undid 1 edit in %s\n", path)
-	} else {
-		fmt.Printf("Undid %d edits in %s\n", count, path)
+	content, err := u.loadSnapshot(hash)
+	if err != nil {
+		return "", fmt.Errorf("load snapshot: %w", err)
 	}
-	return nil
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return "", fmt.Errorf("create directories for %s: %w", path, err)
+	}
+
+	err = u.fileOps.WriteFileContent(path, content, mode)
+	if err != nil {
+		return "", err
+	}
+
+	return content, nil
+}
+
+// StateAt returns the snapshot of the file once the first index edits of the
+// history are applied, or an empty string if the file did not exist then.
+func (h *EditHistory) StateAt(index int) string {
+	if len(h.Edits) == 0 {
+		return ""
+	}
+	if index == 0 {
+		return h.Edits[0].Before
+	}
+	return h.Edits[index-1].After
+}
+
+// State returns the snapshot of the file as eddie last left it.
+func (h *EditHistory) State() string {
+	if h.Merged != "" {
+		return h.Merged
+	}
+	return h.StateAt(h.Current)
+}
+
+// RecordEdit records an edit of path from before to after, the complete file
+// content on either side of the edit. It must be called after the edited
+// content has been written. Any undone edits are discarded.
+func (u *UndoEditor) RecordEdit(path, editType, before, after string) error {
+	beforeHash, err := u.storeSnapshot(before)
+	if err != nil {
+		return fmt.Errorf("store snapshot: %w", err)
+	}
+	afterHash, err := u.storeSnapshot(after)
+	if err != nil {
+		return fmt.Errorf("store snapshot: %w", err)
+	}
+
+	return u.appendEdit(path, EditRecord{
+		EditType: editType,
+		Before:   beforeHash,
+		After:    afterHash,
+	}, before)
 }
 
-func (u *UndoEditor) RecordEdit(path, editType, oldContent, newContent string, position int) error {
+// RecordCreate records that path was created with content. createdDirs are
+// the parent directories made for it, which undoing the create removes along
+// with the file.
+func (u *UndoEditor) RecordCreate(path, content string, createdDirs []string) error {
+	afterHash, err := u.storeSnapshot(content)
+	if err != nil {
+		return fmt.Errorf("store snapshot: %w", err)
+	}
+
+	absDirs := make([]string, 0, len(createdDirs))
+	for _, dir := range createdDirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("get absolute path: %w", err)
+		}
+		absDirs = append(absDirs, absDir)
+	}
+
+	return u.appendEdit(path, EditRecord{
+		EditType:    "create",
+		After:       afterHash,
+		Event:       EventCreated,
+		CreatedDirs: absDirs,
+	}, "")
+}
+
+// appendEdit adds edit to the history of path after its current edit. before
+// is the file content the edit started from, used to migrate old histories.
+func (u *UndoEditor) appendEdit(path string, edit EditRecord, before string) error {
 	editPath, err := u.getEditFilePath(path)
 	if err != nil {
 		return fmt.Errorf("get edit file path: %w", err)
@@ -146,29 +473,33 @@ func (u *UndoEditor) RecordEdit(path, editType, oldContent, newContent string, p
 		return fmt.Errorf("create edit directory %s: %w", editDir, err)
 	}
 
-	info, err := os.Stat(path)
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("stat file %s: %w", path, err)
-	}
-
-	newEdit := EditRecord{
-		EditType:    editType,
-		OldContent:  oldContent,
-		NewContent:  newContent,
-		Position:    position,
-		Timestamp:   time.Now(),
-		FileModTime: info.ModTime(),
+		return fmt.Errorf("get absolute path: %w", err)
 	}
 
 	editHistory, err := u.readEditHistory(editPath)
 	if err != nil {
 		editHistory = &EditHistory{
-			FilePath: path,
-			Edits:    []EditRecord{},
+			Version: historyVersion,
+			Edits:   []EditRecord{},
+		}
+	}
+
+	if editHistory.Version < historyVersion {
+		// The edit being recorded starts from before, so there is nothing
+		// to check and no result to carry warnings in.
+		_, err = u.migrateHistory(editHistory, before)
+		if err != nil {
+			return fmt.Errorf("migrate edit history: %w", err)
 		}
 	}
 
-	editHistory.Edits = append(editHistory.Edits, newEdit)
+	edit.Timestamp = time.Now()
+	editHistory.FilePath = absPath
+	editHistory.Edits = append(editHistory.Edits[:editHistory.Current], edit)
+	editHistory.Current = len(editHistory.Edits)
+	editHistory.Merged = ""
 
 	err = u.writeEditHistory(editPath, editHistory)
 	if err != nil {
@@ -243,7 +574,7 @@ func (u *UndoEditor) writeEditHistory(editPath string, history *EditHistory) err
 		return fmt.Errorf("marshal JSON: %w", err)
 	}
 
-	err = os.WriteFile(editPath, data, 0o644)
+	err = fileops.WriteFileAtomic(editPath, data, 0o644)
 	if err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func BenchmarkUndoEditor_RecordEdit(b *testing.B) {
@@ -46,7 +48,7 @@ func BenchmarkUndoEditor_RecordEdit(b *testing.B) {
 				}
 				b.StartTimer()
 
-				err = u.RecordEdit(testFile, "str_replace", "old", "new", -1)
+				err = u.RecordEdit(testFile, "str_replace", content, content)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -102,14 +104,14 @@ func BenchmarkUndoEditor_UndoEdit(b *testing.B) {
 				}
 
 				// Record edit after modification
-				err = u.RecordEdit(testFile, "str_replace", "original", "modified", -1)
+				err = u.RecordEdit(testFile, "str_replace", originalContent, modifiedContent)
 				if err != nil {
 					b.Fatal(err)
 				}
 				b.StartTimer()
 
 				// Undo edit
-				err = u.UndoEdit(testFile, false, false, 1)
+				_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -163,16 +165,17 @@ func BenchmarkUndoEditor_MultipleEdits(b *testing.B) {
 						b.Fatal(err)
 					}
 
-					err = u.RecordEdit(testFile, "str_replace", "content", fmt.Sprintf("content_%d", i), -1)
+					err = u.RecordEdit(testFile, "str_replace", content, newContent)
 					if err != nil {
 						b.Fatal(err)
 					}
+					content = newContent
 				}
 
 				b.StartTimer()
 
 				// Undo one edit
-				err = u.UndoEdit(testFile, false, false, 1)
+				_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 				if err != nil {
 					b.Fatal(err)
 				}
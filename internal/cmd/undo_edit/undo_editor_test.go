@@ -9,6 +9,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
 func TestUndoEditor_RecordEdit(t *testing.T) {
@@ -26,23 +29,22 @@ func TestUndoEditor_RecordEdit(t *testing.T) {
 	os.Setenv("XDG_CACHE_HOME", tmpDir)
 
 	tests := []struct {
-		name        string
-		editType    string
-		oldContent  string
-		newContent  string
-		fileContent string
-		position    int
+		name     string
+		editType string
+		before   string
+		after    string
 	}{
-		{"str_replace edit", "str_replace", "old", "new", "some old content", -1},
-		{"insert edit", "insert", "", "new line", "line1\nline2\n", 2},
+		{"str_replace edit", "str_replace", "some old content", "some new content"},
+		{"insert edit", "insert", "line1\nline2\n", "line1\nnew line\nline2\n"},
+		{"binary content", "str_replace", "\x00\xff\xfe\r\n", "\x00\xff\r\n"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			testFile := filepath.Join(tmpDir, "test_"+tt.name+".txt")
-			require.NoError(t, os.WriteFile(testFile, []byte(tt.fileContent), 0o644))
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.after), 0o644))
 
-			err := u.RecordEdit(testFile, tt.editType, tt.oldContent, tt.newContent, tt.position)
+			err := u.RecordEdit(testFile, tt.editType, tt.before, tt.after)
 			require.NoError(t, err)
 
 			editPath, err := u.getEditFilePath(testFile)
@@ -56,9 +58,12 @@ func TestUndoEditor_RecordEdit(t *testing.T) {
 
 			edit := history.Edits[0]
 			assert.Equal(t, tt.editType, edit.EditType)
-			assert.Equal(t, tt.oldContent, edit.OldContent)
-			assert.Equal(t, tt.newContent, edit.NewContent)
-			assert.Equal(t, tt.position, edit.Position)
+			before, err := u.loadSnapshot(edit.Before)
+			require.NoError(t, err)
+			assert.Equal(t, tt.before, before)
+			after, err := u.loadSnapshot(edit.After)
+			require.NoError(t, err)
+			assert.Equal(t, tt.after, after)
 		})
 	}
 }
@@ -93,7 +98,7 @@ func TestUndoEditor_UndoEdit_MultipleCount_Basic(t *testing.T) {
 
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
-				err := u.UndoEdit(testFile, false, false, tt.count)
+				_, err := u.UndoEdit(testFile, false, false, tt.count, false, fileops.Precondition{})
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.wantErr)
 			})
@@ -118,14 +123,14 @@ func TestUndoEditor_RecordEdit_Multiple(t *testing.T) {
 	os.Setenv("XDG_CACHE_HOME", tmpDir)
 
 	testFile := filepath.Join(tmpDir, "test.txt")
-	require.NoError(t, os.WriteFile(testFile, []byte("original content"), 0o644))
+	require.NoError(t, os.WriteFile(testFile, []byte("first content"), 0o644))
 
-	err := u.RecordEdit(testFile, "str_replace", "original", "first", -1)
+	err := u.RecordEdit(testFile, "str_replace", "original content", "first content")
 	require.NoError(t, err)
 
-	require.NoError(t, os.WriteFile(testFile, []byte("first content"), 0o644))
+	require.NoError(t, os.WriteFile(testFile, []byte("first text"), 0o644))
 
-	err = u.RecordEdit(testFile, "str_replace", "content", "text", -1)
+	err = u.RecordEdit(testFile, "str_replace", "first content", "first text")
 	require.NoError(t, err)
 
 	editPath, err := u.getEditFilePath(testFile)
@@ -135,13 +140,10 @@ func TestUndoEditor_RecordEdit_Multiple(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Len(t, history.Edits, 2)
+	assert.Equal(t, historyVersion, history.Version)
 	assert.Equal(t, "str_replace", history.Edits[0].EditType)
-	assert.Equal(t, "original", history.Edits[0].OldContent)
-	assert.Equal(t, "first", history.Edits[0].NewContent)
-
 	assert.Equal(t, "str_replace", history.Edits[1].EditType)
-	assert.Equal(t, "content", history.Edits[1].OldContent)
-	assert.Equal(t, "text", history.Edits[1].NewContent)
+	assert.Equal(t, history.Edits[0].After, history.Edits[1].Before, "consecutive edits should share a snapshot")
 }
 
 func TestUndoEditor_UndoEdit_StrReplace(t *testing.T) {
@@ -165,14 +167,14 @@ func TestUndoEditor_UndoEdit_StrReplace(t *testing.T) {
 	modifiedContent := "hi world\nline2\nline3\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
 
-	err := u.RecordEdit(testFile, "str_replace", "hello", "hi", -1)
+	err := u.RecordEdit(testFile, "str_replace", originalContent, modifiedContent)
 	require.NoError(t, err)
 
 	currentContent, err := os.ReadFile(testFile)
 	require.NoError(t, err)
 	assert.Equal(t, modifiedContent, string(currentContent))
 
-	err = u.UndoEdit(testFile, false, false, 1)
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 	require.NoError(t, err)
 
 	restoredContent, err := os.ReadFile(testFile)
@@ -181,8 +183,10 @@ func TestUndoEditor_UndoEdit_StrReplace(t *testing.T) {
 
 	editPath, err := u.getEditFilePath(testFile)
 	require.NoError(t, err)
-	_, err = os.Stat(editPath)
-	assert.True(t, os.IsNotExist(err), "Edit history file should be removed after all edits are undone")
+	history, err := u.readEditHistory(editPath)
+	require.NoError(t, err, "Edit history should be kept after all edits are undone so they can be redone")
+	assert.Equal(t, 0, history.Current)
+	assert.Len(t, history.Edits, 1)
 }
 
 func TestUndoEditor_UndoEdit_Insert(t *testing.T) {
@@ -206,14 +210,144 @@ func TestUndoEditor_UndoEdit_Insert(t *testing.T) {
 	modifiedContent := "line1\nline2\nline3\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
 
-	err := u.RecordEdit(testFile, "insert", "", "line2", 2)
+	err := u.RecordEdit(testFile, "insert", originalContent, modifiedContent)
 	require.NoError(t, err)
 
 	currentContent, err := os.ReadFile(testFile)
 	require.NoError(t, err)
 	assert.Equal(t, modifiedContent, string(currentContent))
 
-	err = u.UndoEdit(testFile, false, false, 1)
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	restoredContent, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, originalContent, string(restoredContent))
+}
+
+func TestUndoEditor_UndoEdit_Create(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	outerDir := filepath.Join(tmpDir, "a")
+	innerDir := filepath.Join(outerDir, "b")
+	testFile := filepath.Join(innerDir, "new.txt")
+	require.NoError(t, os.MkdirAll(innerDir, 0o755))
+	require.NoError(t, os.WriteFile(testFile, []byte("created\n"), 0o644))
+	require.NoError(t, u.RecordCreate(testFile, "created\n", []string{outerDir, innerDir}))
+
+	require.NoError(t, os.WriteFile(testFile, []byte("edited\n"), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "str_replace", "created\n", "edited\n"))
+
+	_, err := u.UndoEdit(testFile, false, false, 2, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.NoFileExists(t, testFile)
+	assert.NoDirExists(t, outerDir)
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "created\n", string(content))
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "edited\n", string(content))
+}
+
+func TestUndoEditor_UndoEdit_CreateKeepsNonEmptyDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	dir := filepath.Join(tmpDir, "pkg")
+	testFile := filepath.Join(dir, "new.txt")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(testFile, []byte("created\n"), 0o644))
+	require.NoError(t, u.RecordCreate(testFile, "created\n", []string{dir}))
+
+	otherFile := filepath.Join(dir, "other.txt")
+	require.NoError(t, os.WriteFile(otherFile, []byte("other\n"), 0o644))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	assert.NoFileExists(t, testFile)
+	assert.FileExists(t, otherFile)
+}
+
+func TestUndoEditor_UndoEdit_CreateRecreatedFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "new.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("created\n"), 0o644))
+	require.NoError(t, u.RecordCreate(testFile, "created\n", nil))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(testFile, []byte("someone else\n"), 0o644))
+
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file has been created since last tracked edit")
+}
+
+func TestUndoEditor_UndoEdit_RegexReplace(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	originalContent := "fooV1(a)\nfooV2(b)\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(originalContent), 0o644))
+
+	modifiedContent := "fooV2(a)\nfooV2(b)\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
+
+	err := u.RecordEdit(testFile, "regex_replace", originalContent, modifiedContent)
+	require.NoError(t, err)
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 	require.NoError(t, err)
 
 	restoredContent, err := os.ReadFile(testFile)
@@ -242,22 +376,22 @@ func TestUndoEditor_UndoEdit_Multiple(t *testing.T) {
 
 	content2 := "version 2\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(content2), 0o644))
-	err := u.RecordEdit(testFile, "str_replace", "1", "2", -1)
+	err := u.RecordEdit(testFile, "str_replace", content1, content2)
 	require.NoError(t, err)
 
 	content3 := "version 3\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(content3), 0o644))
-	err = u.RecordEdit(testFile, "str_replace", "2", "3", -1)
+	err = u.RecordEdit(testFile, "str_replace", content2, content3)
 	require.NoError(t, err)
 
-	err = u.UndoEdit(testFile, false, false, 1)
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 	require.NoError(t, err)
 
 	restoredContent, err := os.ReadFile(testFile)
 	require.NoError(t, err)
 	assert.Equal(t, content2, string(restoredContent))
 
-	err = u.UndoEdit(testFile, false, false, 1)
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 	require.NoError(t, err)
 
 	restoredContent, err = os.ReadFile(testFile)
@@ -266,11 +400,13 @@ func TestUndoEditor_UndoEdit_Multiple(t *testing.T) {
 
 	editPath, err := u.getEditFilePath(testFile)
 	require.NoError(t, err)
-	_, err = os.Stat(editPath)
-	assert.True(t, os.IsNotExist(err), "Edit history file should be removed after all edits are undone")
+	history, err := u.readEditHistory(editPath)
+	require.NoError(t, err, "Edit history should be kept after all edits are undone so they can be redone")
+	assert.Equal(t, 0, history.Current)
+	assert.Len(t, history.Edits, 2)
 }
 
-func TestUndoEditor_UndoEdit_ModificationTimeValidation(t *testing.T) {
+func TestUndoEditor_UndoEdit_ContentValidation(t *testing.T) {
 	tmpDir := t.TempDir()
 	u := &UndoEditor{}
 
@@ -291,17 +427,164 @@ func TestUndoEditor_UndoEdit_ModificationTimeValidation(t *testing.T) {
 	// Modify file and record edit
 	modifiedContent := "hi world\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
-	err := u.RecordEdit(testFile, "str_replace", "hello", "hi", -1)
+	err := u.RecordEdit(testFile, "str_replace", originalContent, modifiedContent)
 	require.NoError(t, err)
 
-	time.Sleep(100 * time.Millisecond)
-
 	externalContent := "external change\n"
 	require.NoError(t, os.WriteFile(testFile, []byte(externalContent), 0o644))
 
-	err = u.UndoEdit(testFile, false, false, 1)
+	var out bytes.Buffer
+	_, err = NewUndoEditor(&out).UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "file has been modified since last tracked edit")
+	assert.Contains(t, err.Error(), "--force")
+	assert.Empty(t, out.String(), "the diff belongs to the error, not the output")
+
+	var modified *ModifiedError
+	require.ErrorAs(t, err, &modified)
+	assert.Equal(t, testFile, modified.Path)
+	assert.Contains(t, modified.Diff, "-hi world")
+	assert.Contains(t, modified.Diff, "+external change")
+	assert.Contains(t, output.ErrorDetails(err), "Changes in "+testFile+":")
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, externalContent, string(content))
+}
+
+func TestUndoEditor_UndoEdit_Precondition(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("hi world\n"), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "str_replace", "hello world\n", "hi world\n"))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{SHA256: fileops.HashContent("hello world\n")})
+	require.ErrorIs(t, err, fileops.ErrConflict)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "hi world\n", string(content))
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{SHA256: fileops.HashContent("hi world\n")})
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(content))
+}
+
+func TestUndoEditor_UndoEdit_IgnoresModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	originalContent := "hello world\n"
+	modifiedContent := "hi world\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "str_replace", originalContent, modifiedContent))
+
+	// Rewriting identical content, as touch or a git checkout would, is not a
+	// modification.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
+	require.NoError(t, os.Chtimes(testFile, later, later))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, originalContent, string(content))
+}
+
+func TestUndoEditor_UndoEdit_Force(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	v1 := "func a() {}\n\nfunc b() {}\n\nfunc c() {}\n"
+	v2 := "func a() {}\n\nfunc b() { return }\n\nfunc c() {}\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(v2), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "str_replace", v1, v2))
+
+	external := "// header\nfunc a() {}\n\nfunc b() { return }\n\nfunc c() {}\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(external), 0o644))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, true, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "// header\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n", string(content))
+
+	// The external change is carried along by later moves without --force.
+	_, err = u.RedoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, external, string(content))
+}
+
+func TestUndoEditor_UndoEdit_ForceConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("a\nB\nc\n"), 0o644))
+	require.NoError(t, u.RecordEdit(testFile, "str_replace", "a\nb\nc\n", "a\nB\nc\n"))
+
+	external := "a\nX\nc\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(external), 0o644))
+
+	_, err := u.UndoEdit(testFile, false, false, 1, true, fileops.Precondition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicts at lines 2")
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, external, string(content))
 }
 
 func TestUndoEditor_UndoEdit_Errors(t *testing.T) {
@@ -344,9 +627,201 @@ func TestUndoEditor_UndoEdit_Errors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup()
-			err := u.UndoEdit(path, false, false, 1)
+			_, err := u.UndoEdit(path, false, false, 1, false, fileops.Precondition{})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
 }
+
+func TestUndoEditor_UndoEdit_RestoresExactSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	// "hi" already exists before the edit, so reversing hello->hi across the
+	// file would also rewrite the untouched "hi".
+	testFile := filepath.Join(tmpDir, "test.txt")
+	originalContent := "hi there\nhello world\n"
+	modifiedContent := "hi there\nhi world\n"
+	require.NoError(t, os.WriteFile(testFile, []byte(modifiedContent), 0o644))
+
+	err := u.RecordEdit(testFile, "str_replace", originalContent, modifiedContent)
+	require.NoError(t, err)
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	restoredContent, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, originalContent, string(restoredContent))
+}
+
+func TestUndoEditor_UndoEdit_MigratesLegacyHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	u := &UndoEditor{}
+
+	oldCacheHome := os.Getenv("XDG_CACHE_HOME")
+	defer func() {
+		if oldCacheHome != "" {
+			os.Setenv("XDG_CACHE_HOME", oldCacheHome)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("line1\nline2\nhi world\n"), 0o644))
+	info, err := os.Stat(testFile)
+	require.NoError(t, err)
+	modTime := info.ModTime()
+
+	editPath, err := u.getEditFilePath(testFile)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(editPath), 0o755))
+
+	// Version 0 history: line2 was inserted, then hello was replaced by hi.
+	legacy := &EditHistory{
+		FilePath: testFile,
+		Edits: []EditRecord{
+			{EditType: "insert", NewContent: "line2", Position: 2, FileModTime: &modTime},
+			{EditType: "str_replace", OldContent: "hello", NewContent: "hi", Position: -1, FileModTime: &modTime},
+		},
+	}
+	require.NoError(t, u.writeEditHistory(editPath, legacy))
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "line1\nline2\nhello world\n", string(content))
+
+	history, err := u.readEditHistory(editPath)
+	require.NoError(t, err)
+	assert.Equal(t, historyVersion, history.Version)
+	require.Len(t, history.Edits, 2)
+	assert.Equal(t, 1, history.Current)
+	assert.NotEmpty(t, history.Edits[0].Before)
+	assert.Empty(t, history.Edits[0].NewContent)
+	assert.Nil(t, history.Edits[0].FileModTime)
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "line1\nhello world\n", string(content))
+}
+
+func TestUndoEditor_UndoEdit_LegacyHistoryModified(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", tmpDir)
+	u := &UndoEditor{}
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("hi world\nchanged later\n"), 0o644))
+	modTime := time.Now().Add(-time.Hour)
+
+	editPath, err := u.getEditFilePath(testFile)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(editPath), 0o755))
+	legacy := &EditHistory{
+		FilePath: testFile,
+		Edits: []EditRecord{
+			{EditType: "str_replace", OldContent: "hello", NewContent: "hi", Position: -1, FileModTime: &modTime},
+		},
+	}
+	require.NoError(t, u.writeEditHistory(editPath, legacy))
+
+	_, err = u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	assert.ErrorIs(t, err, errModified)
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "hi world\nchanged later\n", string(content))
+
+	history, err := u.ShowHistory(testFile)
+	require.NoError(t, err)
+	require.Len(t, history.Warnings, 1)
+	assert.Contains(t, history.Warnings[0], errModified.Error())
+	stored, err := u.readEditHistory(editPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.Version)
+
+	_, err = u.UndoEdit(testFile, false, false, 1, true, fileops.Precondition{})
+	require.NoError(t, err)
+	content, err = os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\nchanged later\n", string(content))
+}
+
+func TestUndoEditor_UndoEdit_LegacyHistoryDroppedWarning(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", tmpDir)
+	u := &UndoEditor{}
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("hi world\n"), 0o644))
+
+	editPath, err := u.getEditFilePath(testFile)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(editPath), 0o755))
+	// The older edit inserted a line that is no longer there, so it cannot
+	// be reversed and is dropped.
+	legacy := &EditHistory{
+		FilePath: testFile,
+		Edits: []EditRecord{
+			{EditType: "insert", NewContent: "gone", Position: 5},
+			{EditType: "str_replace", OldContent: "hello", NewContent: "hi", Position: -1},
+		},
+	}
+	require.NoError(t, u.writeEditHistory(editPath, legacy))
+
+	res, err := u.UndoEdit(testFile, false, false, 1, false, fileops.Precondition{})
+	require.NoError(t, err)
+	require.Len(t, res.Edits[0].Warnings, 1)
+	assert.Contains(t, res.Edits[0].Warnings[0], "Dropped 1 edit(s)")
+	assert.Contains(t, res.String(), "Warning: Dropped 1 edit(s)")
+}
+
+func TestUndoEditor_reverseInsert(t *testing.T) {
+	u := &UndoEditor{}
+
+	tests := []struct {
+		name    string
+		content string
+		lineNum int
+		newStr  string
+		want    string
+		wantErr bool
+	}{
+		{"single line", "a\nnew\nb\n", 2, "new", "a\nb\n", false},
+		{"multiple lines", "a\nnew1\nnew2\nb\n", 2, "new1\nnew2", "a\nb\n", false},
+		{"at end", "a\nnew1\nnew2\n", 2, "new1\nnew2", "a\n", false},
+		{"no recorded content", "a\nnew\nb\n", 1, "", "new\nb\n", false},
+		{"content differs", "a\nother\nb\n", 2, "new", "", true},
+		{"out of range", "a\nnew1\n", 2, "new1\nnew2", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.reverseInsert(tt.content, tt.lineNum, tt.newStr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,43 +2,24 @@ package undo_edit
 
 import (
 	"fmt"
-	"os"
 	"strings"
-)
 
-func (u *UndoEditor) applyReverseEdit(path string, record *EditRecord) error {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read file %s: %w", path, err)
-	}
+	"github.com/RRethy/eddie/internal/fileops"
+)
 
-	var newContent string
+// reverseLegacyEdit reverses an edit recorded before snapshots were
+// introduced. It is only used to migrate old histories.
+func (u *UndoEditor) reverseLegacyEdit(content string, record *EditRecord) (string, error) {
 	switch record.EditType {
 	case "str_replace":
-		newContent, err = u.reverseStrReplace(string(content), record.OldContent, record.NewContent)
-		if err != nil {
-			return fmt.Errorf("reverse str_replace: %w", err)
-		}
+		return u.reverseStrReplace(content, record.OldContent, record.NewContent)
+	case "regex_replace", "replace_lines", "delete_lines":
+		return u.reverseWholeFile(content, record.OldContent, record.NewContent)
 	case "insert":
-		newContent, err = u.reverseInsert(string(content), record.Position)
-		if err != nil {
-			return fmt.Errorf("reverse insert: %w", err)
-		}
+		return u.reverseInsert(content, record.Position, record.NewContent)
 	default:
-		return fmt.Errorf("unknown edit type: %s", record.EditType)
-	}
-
-	info, err := os.Stat(path)
-	if err != nil {
-		return fmt.Errorf("stat file: %w", err)
-	}
-
-	err = os.WriteFile(path, []byte(newContent), info.Mode())
-	if err != nil {
-		return fmt.Errorf("write file: %w", err)
+		return "", fmt.Errorf("unknown edit type: %s", record.EditType)
 	}
-
-	return nil
 }
 
 func (u *UndoEditor) reverseStrReplace(content, oldStr, newStr string) (string, error) {
@@ -51,33 +32,32 @@ func (u *UndoEditor) reverseStrReplace(content, oldStr, newStr string) (string,
 	return reversed, nil
 }
 
-func (u *UndoEditor) reverseInsert(content string, lineNum int) (string, error) {
-	lines := strings.Split(content, "\n")
-	hasTrailingNewline := strings.HasSuffix(content, "\n")
-
-	if hasTrailingNewline && len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
+// reverseWholeFile restores oldContent for edits that record the complete file
+// before and after the change.
+func (u *UndoEditor) reverseWholeFile(content, oldContent, newContent string) (string, error) {
+	if content != newContent {
+		return "", fmt.Errorf("file content does not match the recorded edit")
 	}
+	return oldContent, nil
+}
 
-	if lineNum < 1 || lineNum > len(lines) {
-		return "", fmt.Errorf("line number %d is out of range (1-%d)", lineNum, len(lines))
-	}
+// reverseInsert removes the lines of newStr inserted at lineNum, which span
+// as many lines as newStr has. Legacy inserts split the raw content on "\n",
+// so content is not decoded here either: a CRLF file keeps the "\r" of its
+// other lines, which the inserted lines never had.
+func (u *UndoEditor) reverseInsert(content string, lineNum int, newStr string) (string, error) {
+	lines, hasTrailingNewline := fileops.SplitLines(content)
+	count := strings.Count(newStr, "\n") + 1
 
-	var result []string
-	if lineNum == 1 {
-		result = lines[1:]
-	} else if lineNum == len(lines) {
-		result = lines[:len(lines)-1]
-	} else {
-		result = make([]string, 0, len(lines)-1)
-		result = append(result, lines[:lineNum-1]...)
-		result = append(result, lines[lineNum:]...)
+	if lineNum < 1 || lineNum+count-1 > len(lines) {
+		return "", fmt.Errorf("lines %d-%d are out of range (1-%d)", lineNum, lineNum+count-1, len(lines))
 	}
-
-	joined := strings.Join(result, "\n")
-	if hasTrailingNewline {
-		joined += "\n"
+	if newStr != "" && strings.Join(lines[lineNum-1:lineNum-1+count], "\n") != newStr {
+		return "", fmt.Errorf("file content at line %d does not match the recorded insert", lineNum)
 	}
 
-	return joined, nil
+	result := make([]string, 0, len(lines)-count)
+	result = append(result, lines[:lineNum-1]...)
+	result = append(result, lines[lineNum-1+count:]...)
+	return fileops.JoinLines(result, hasTrailingNewline), nil
 }
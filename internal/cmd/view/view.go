@@ -1,5 +1,8 @@
 package view
 
-func View(path, viewRange string) error {
-	return (&Viewer{}).View(path, viewRange)
+import "os"
+
+func View(path, viewRange, symbol string, lineNumbers, showFormat bool, maxLineLength, maxLines, maxBytes int) error {
+	_, err := NewViewer(os.Stdout).View(path, viewRange, symbol, lineNumbers, showFormat, maxLineLength, maxLines, maxBytes)
+	return err
 }
@@ -2,72 +2,354 @@ package view
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/RRethy/eddie/internal/cmd/outline"
+	"github.com/RRethy/eddie/internal/fileops"
+	"github.com/RRethy/eddie/internal/output"
 )
 
-type Viewer struct{}
+// DefaultMaxLineLength is the number of bytes of a line shown before it is
+// truncated, unless the caller picks another limit.
+const DefaultMaxLineLength = 2000
+
+type Viewer struct {
+	out io.Writer
+}
+
+// Result is what View shows of a file or directory. Directories list their
+// Entries, with a trailing slash for subdirectories. Files give the SHA-256
+// and modification time of the whole file, or only its size if it is over
+// fileops.MaxFileSize, and the Lines shown. More is the number of lines left
+// out by the output limits.
+type Result struct {
+	Path    string          `json:"path"`
+	Dir     bool            `json:"dir,omitempty"`
+	Entries []string        `json:"entries,omitempty"`
+	SHA256  string          `json:"sha256,omitempty"`
+	ModTime string          `json:"mtime,omitempty"`
+	Size    int64           `json:"size"`
+	Format  *fileops.Format `json:"format,omitempty"`
+	Lines   []Line          `json:"lines,omitempty"`
+	More    int             `json:"more,omitempty"`
+
+	lineNumbers bool
+}
+
+// Line is one line of a file, decoded and without its line ending. Dropped is
+// the number of bytes cut off the end of a line that was too long.
+type Line struct {
+	Number  int    `json:"number"`
+	Text    string `json:"text"`
+	Dropped int    `json:"dropped,omitempty"`
+}
+
+func (r *Result) String() string {
+	var b strings.Builder
+	if r.Dir {
+		for _, entry := range r.Entries {
+			b.WriteString(entry + "\n")
+		}
+		return b.String()
+	}
+
+	if r.SHA256 == "" {
+		fmt.Fprintf(&b, "size: %s (over the --max-file-size limit, not hashed) mtime: %s\n", fileops.FormatSize(r.Size), r.ModTime)
+	} else {
+		fmt.Fprintf(&b, "sha256: %s mtime: %s\n", r.SHA256, r.ModTime)
+	}
+	if r.Format != nil {
+		fmt.Fprintf(&b, "format: %s\n", r.Format)
+	}
+	for _, line := range r.Lines {
+		b.WriteString(formatLine(line.Number, []byte(line.Text), line.Dropped, r.lineNumbers))
+	}
+	if r.More > 0 {
+		fmt.Fprintf(&b, "... %d more lines\n", r.More)
+	}
+	return b.String()
+}
+
+func NewViewer(out io.Writer) *Viewer {
+	return &Viewer{out: out}
+}
 
-func (v *Viewer) View(path, viewRange string) error {
+// View prints the directory or file at path. For files, only the lines in
+// viewRange or of the declaration named by symbol, with its doc comment, are
+// printed. lineNumbers prefixes each line with its number, lines longer than
+// maxLineLength bytes are truncated, and output stops with a trailer once
+// maxLines lines or maxBytes bytes have been printed. A limit of 0 disables
+// it. showFormat also prints the detected encoding, BOM and line endings of
+// the file. Files not in UTF-8 are shown decoded.
+func (v *Viewer) View(path, viewRange, symbol string, lineNumbers, showFormat bool, maxLineLength, maxLines, maxBytes int) (*Result, error) {
 	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("stat %s: %w", path, err)
+		return nil, fmt.Errorf("stat %s: %w", path, err)
 	}
 
+	var res *Result
 	if info.IsDir() {
-		return v.viewDir(path)
+		res, err = v.viewDir(path)
+	} else {
+		if symbol != "" {
+			if viewRange != "" {
+				return nil, fmt.Errorf("cannot view both a range and a symbol")
+			}
+			if err := fileops.CheckSize(path, info.Size()); err != nil {
+				return nil, err
+			}
+			viewRange, err = v.symbolRange(path, symbol)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res, err = v.viewFile(path, viewRange, lineNumbers, showFormat, maxLineLength, maxLines, maxBytes)
 	}
-	return v.viewFile(path, viewRange)
+	if err != nil {
+		return nil, err
+	}
+	return res, output.Print(v.out, res)
 }
 
-func (v *Viewer) viewDir(path string) error {
+// symbolRange returns the view range of the declaration named by symbol in
+// path, including its doc comment.
+func (v *Viewer) symbolRange(path, symbol string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	content, _, err := fileops.DecodeText(string(raw))
+	if err != nil {
+		return "", fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	resolved, err := outline.Resolve(path, []byte(content), symbol)
+	if err != nil {
+		return "", err
+	}
+
+	start := resolved.StartLine
+	if resolved.DocLine > 0 {
+		start = resolved.DocLine
+	}
+	return fmt.Sprintf("%d,%d", start, resolved.EndLine), nil
+}
+
+func (v *Viewer) viewDir(path string) (*Result, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
-		return fmt.Errorf("read dir %s: %w", path, err)
+		return nil, fmt.Errorf("read dir %s: %w", path, err)
 	}
 
-	for _, entry := range entries {
+	res := &Result{Path: path, Dir: true, Entries: make([]string, len(entries))}
+	for i, entry := range entries {
 		name := entry.Name()
 		if entry.IsDir() {
 			name += "/"
 		}
-		fmt.Println(name)
+		res.Entries[i] = name
 	}
-	return nil
+	return res, nil
 }
 
-func (v *Viewer) viewFile(path, viewRange string) error {
+// viewFile returns the lines of path in viewRange with the SHA-256 and
+// modification time of the whole file, so they can be passed back as the
+// precondition of a later edit. Files over fileops.MaxFileSize are streamed
+// without being hashed, and no more than fileops.MaxFileSize bytes of them
+// are kept unless maxBytes is set. Files that look binary are refused unless
+// fileops.AllowBinary is set.
+func (v *Viewer) viewFile(path, viewRange string, lineNumbers, showFormat bool, maxLineLength, maxLines, maxBytes int) (*Result, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("open %s: %w", path, err)
+		return nil, fmt.Errorf("open %s: %w", path, err)
 	}
 	defer f.Close()
 
 	start, end, err := v.parseRange(viewRange)
 	if err != nil {
-		return fmt.Errorf("parse range: %w", err)
+		return nil, fmt.Errorf("parse range: %w", err)
 	}
 
-	scanner := bufio.NewScanner(f)
-	line := 1
-	for scanner.Scan() {
-		if start > 0 && line < start {
-			line++
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	sample := make([]byte, fileops.SniffLen)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	sample = sample[:n]
+	if !fileops.AllowBinary && fileops.LooksBinary(sample) {
+		return nil, fmt.Errorf("%w: refusing to view %s (see --allow-binary)", fileops.ErrBinary, path)
+	}
+
+	// Files over the size limit cannot be edited, so they are not hashed,
+	// and a range near their start only reads the lines it needs.
+	hash := ""
+	format := fileops.DetectFormatPrefix(sample)
+	huge := fileops.MaxFileSize > 0 && info.Size() > fileops.MaxFileSize
+	if !huge {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seek %s: %w", path, err)
+		}
+		var detector fileops.FormatDetector
+		hash, err = fileops.HashReader(io.TeeReader(f, &detector))
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %w", path, err)
+		}
+		format = detector.Format()
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek %s: %w", path, err)
+	}
+
+	reader := bufio.NewReader(f)
+	decode := func(line []byte) []byte { return line }
+	switch format.Encoding {
+	case fileops.EncodingUTF16LE, fileops.EncodingUTF16BE:
+		// UTF-16 is decoded as a whole rather than line by line.
+		if huge {
+			return nil, fmt.Errorf("%w: %s is %s, and %s files over the %s limit cannot be viewed (see --max-file-size)",
+				fileops.ErrTooLarge, path, fileops.FormatSize(info.Size()), format.Encoding, fileops.FormatSize(fileops.MaxFileSize))
+		}
+		raw, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		text, decoded, err := fileops.DecodeText(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", path, err)
+		}
+		format = decoded
+		reader = bufio.NewReader(strings.NewReader(text))
+	case fileops.EncodingLatin1:
+		decode = func(line []byte) []byte {
+			text, _ := format.Decode(string(line))
+			return []byte(text)
+		}
+	default:
+		if format.BOM {
+			_, _ = reader.Discard(3)
+		}
+	}
+
+	if huge && maxBytes <= 0 {
+		maxBytes = int(min(fileops.MaxFileSize, math.MaxInt))
+	}
+
+	res := &Result{
+		Path:        path,
+		ModTime:     fileops.FormatModTime(info.ModTime()),
+		Size:        info.Size(),
+		SHA256:      hash,
+		lineNumbers: lineNumbers,
+	}
+	if showFormat {
+		res.Format = &format
+	}
+	printedBytes := 0
+	for line := 1; end <= 0 || line <= end; line++ {
+		text, dropped, err := readLine(reader, maxLineLength)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if line < start {
+			continue
+		}
+
+		decoded := decode(text)
+		size := len(formatLine(line, decoded, dropped, lineNumbers))
+		if res.More > 0 || (maxLines > 0 && len(res.Lines) >= maxLines) ||
+			(maxBytes > 0 && printedBytes+size > maxBytes) {
+			res.More++
 			continue
 		}
-		if end > 0 && line > end {
+
+		res.Lines = append(res.Lines, Line{Number: line, Text: string(decoded), Dropped: dropped})
+		printedBytes += size
+	}
+	return res, nil
+}
+
+// formatLine renders one line of output, numbered like cat -n when
+// lineNumbers is set and with a marker if dropped bytes were cut off.
+func formatLine(number int, text []byte, dropped int, lineNumbers bool) string {
+	var b strings.Builder
+	if lineNumbers {
+		fmt.Fprintf(&b, "%6d\t", number)
+	}
+	b.Write(text)
+	if dropped > 0 {
+		fmt.Fprintf(&b, " ... [%d more bytes]", dropped)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// readLine reads the next line from r without its line terminator. Only the
+// first limit bytes of the line are kept, cut back to a UTF-8 boundary, and
+// the number of bytes dropped is returned; a limit of 0 keeps the whole line.
+// Unlike bufio.Scanner, lines may be of any length.
+func readLine(r *bufio.Reader, limit int) ([]byte, int, error) {
+	var line []byte
+	var last byte
+	dropped := 0
+	for started := false; ; started = true {
+		chunk, err := r.ReadSlice('\n')
+		more := errors.Is(err, bufio.ErrBufferFull)
+		if err != nil && !more && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		if errors.Is(err, io.EOF) && !started && len(chunk) == 0 {
+			return nil, 0, io.EOF
+		}
+
+		if !more {
+			chunk = bytes.TrimSuffix(chunk, []byte("\n"))
+		}
+		if len(chunk) > 0 {
+			last = chunk[len(chunk)-1]
+		}
+
+		if dropped == 0 && (limit <= 0 || len(line)+len(chunk) <= limit) {
+			line = append(line, chunk...)
+		} else {
+			keep := 0
+			if dropped == 0 {
+				keep = limit - len(line)
+				for keep > 0 && !utf8.RuneStart(chunk[keep]) {
+					keep--
+				}
+			}
+			line = append(line, chunk[:keep]...)
+			dropped += len(chunk) - keep
+		}
+
+		if !more {
 			break
 		}
-		fmt.Println(scanner.Text())
-		line++
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scan file: %w", err)
+	if last == '\r' {
+		if dropped > 0 {
+			dropped--
+		} else {
+			line = line[:len(line)-1]
+		}
 	}
-	return nil
+	return line, dropped, nil
 }
 
 func (v *Viewer) parseRange(viewRange string) (int, int, error) {
@@ -1,6 +1,7 @@
 package view
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,11 +31,11 @@ func BenchmarkViewer_ViewFile(b *testing.B) {
 				b.Fatal(err)
 			}
 
-			v := &Viewer{}
+			v := NewViewer(io.Discard)
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				err := v.viewFile(testFile, "")
+				_, err := v.viewFile(testFile, "", true, false, DefaultMaxLineLength, 0, 0)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -66,11 +67,11 @@ func BenchmarkViewer_ViewFileWithRange(b *testing.B) {
 
 	for _, r := range ranges {
 		b.Run(r.name, func(b *testing.B) {
-			v := &Viewer{}
+			v := NewViewer(io.Discard)
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				err := v.viewFile(testFile, r.range_)
+				_, err := v.viewFile(testFile, r.range_, true, false, DefaultMaxLineLength, 0, 0)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -109,11 +110,11 @@ func BenchmarkViewer_ViewDir(b *testing.B) {
 				}
 			}
 
-			v := &Viewer{}
+			v := NewViewer(io.Discard)
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				err := v.viewDir(testDir)
+				_, err := v.viewDir(testDir)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -123,7 +124,7 @@ func BenchmarkViewer_ViewDir(b *testing.B) {
 }
 
 func BenchmarkViewer_ParseRange(b *testing.B) {
-	v := &Viewer{}
+	v := NewViewer(io.Discard)
 
 	ranges := []string{
 		"",
@@ -1,12 +1,18 @@
 package view
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/RRethy/eddie/internal/fileops"
 )
 
 func TestViewer_parseRange(t *testing.T) {
@@ -54,7 +60,7 @@ func TestViewer_View(t *testing.T) {
 	require.NoError(t, os.Mkdir(testSubDir, 0o755))
 	require.NoError(t, os.WriteFile(filepath.Join(testSubDir, "file1.txt"), []byte("content"), 0o644))
 
-	v := &Viewer{}
+	v := NewViewer(io.Discard)
 
 	tests := []struct {
 		name    string
@@ -72,7 +78,7 @@ func TestViewer_View(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := v.View(tt.path, tt.range_)
+			_, err := v.View(tt.path, tt.range_, "", true, false, DefaultMaxLineLength, 0, 0)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -81,3 +87,215 @@ func TestViewer_View(t *testing.T) {
 		})
 	}
 }
+
+func TestViewer_ViewFile_Output(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		content       string
+		viewRange     string
+		lineNumbers   bool
+		maxLineLength int
+		maxLines      int
+		maxBytes      int
+		want          string
+	}{
+		{
+			name:        "numbered lines",
+			content:     "a\nb\nc\n",
+			lineNumbers: true,
+			want:        "     1\ta\n     2\tb\n     3\tc\n",
+		},
+		{
+			name:    "raw lines",
+			content: "a\nb\nc",
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:        "range keeps file line numbers",
+			content:     "a\nb\nc\nd\n",
+			viewRange:   "2,3",
+			lineNumbers: true,
+			want:        "     2\tb\n     3\tc\n",
+		},
+		{
+			name:    "crlf line endings",
+			content: "a\r\nb\r\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:          "long line truncated",
+			content:       "short\n" + strings.Repeat("x", 30) + "\n",
+			maxLineLength: 10,
+			want:          "short\nxxxxxxxxxx ... [20 more bytes]\n",
+		},
+		{
+			name:          "truncation respects utf-8",
+			content:       "aéé\n",
+			maxLineLength: 2,
+			want:          "a ... [4 more bytes]\n",
+		},
+		{
+			name:     "line budget",
+			content:  "a\nb\nc\nd\ne\n",
+			maxLines: 2,
+			want:     "a\nb\n... 3 more lines\n",
+		},
+		{
+			name:      "line budget within range",
+			content:   "a\nb\nc\nd\ne\n",
+			viewRange: "2,4",
+			maxLines:  1,
+			want:      "b\n... 2 more lines\n",
+		},
+		{
+			name:     "byte budget",
+			content:  "aaa\nbbb\nccc\n",
+			maxBytes: 9,
+			want:     "aaa\nbbb\n... 1 more lines\n",
+		},
+		{
+			name:     "budget not reached",
+			content:  "a\nb\n",
+			maxLines: 2,
+			maxBytes: 100,
+			want:     "a\nb\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.txt", i))
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			var buf bytes.Buffer
+			_, err := NewViewer(&buf).View(testFile, tt.viewRange, "", tt.lineNumbers, false, tt.maxLineLength, tt.maxLines, tt.maxBytes)
+			require.NoError(t, err)
+
+			header, body, ok := strings.Cut(buf.String(), "\n")
+			require.True(t, ok)
+			assert.Contains(t, header, "sha256: "+fileops.HashContent(tt.content))
+			assert.Equal(t, tt.want, body)
+		})
+	}
+}
+
+func TestViewer_ViewFile_LongLines(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "minified.js")
+	long := strings.Repeat("var a=1;", 100_000)
+	require.NoError(t, os.WriteFile(testFile, []byte(long+"\nend\n"), 0o644))
+
+	var buf bytes.Buffer
+	_, err := NewViewer(&buf).View(testFile, "", "", true, false, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "     1\t"+long+"\n     2\tend\n")
+
+	buf.Reset()
+	_, err = NewViewer(&buf).View(testFile, "", "", true, false, DefaultMaxLineLength, 0, 0)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), fmt.Sprintf("     1\t%s ... [%d more bytes]\n     2\tend\n", long[:DefaultMaxLineLength], len(long)-DefaultMaxLineLength))
+}
+
+func TestViewer_View_Symbol(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "server.go")
+	content := `package main
+
+type Server struct{}
+
+// Start starts the server.
+func (s *Server) Start() error {
+	return nil
+}
+
+func main() {}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0o644))
+
+	var buf bytes.Buffer
+	_, err := NewViewer(&buf).View(testFile, "", "Server.Start", true, false, DefaultMaxLineLength, 0, 0)
+	require.NoError(t, err)
+	_, body, _ := strings.Cut(buf.String(), "\n")
+	assert.Equal(t, "     5\t// Start starts the server.\n     6\tfunc (s *Server) Start() error {\n     7\t\treturn nil\n     8\t}\n", body)
+
+	_, err = NewViewer(&buf).View(testFile, "1,2", "main", true, false, DefaultMaxLineLength, 0, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "both a range and a symbol")
+
+	_, err = NewViewer(&buf).View(testFile, "", "Stop", true, false, DefaultMaxLineLength, 0, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestViewer_ViewFile_Format(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFormat string
+		wantLines  string
+	}{
+		{"utf-8 crlf", "a\r\nb\r\n", "format: UTF-8, CRLF\n", "     1\ta\n     2\tb\n"},
+		{"utf-8 bom", "\xef\xbb\xbfa\n", "format: UTF-8, BOM, LF\n", "     1\ta\n"},
+		{"utf-16le bom", "\xff\xfea\x00\r\x00\n\x00\xe9\x00\r\x00\n\x00", "format: UTF-16LE, BOM, CRLF\n", "     1\ta\n     2\té\n"},
+		{"latin-1", "caf\xe9\n", "format: ISO-8859-1, LF\n", "     1\tcafé\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "test.txt")
+			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0o644))
+
+			var buf bytes.Buffer
+			_, err := NewViewer(&buf).View(testFile, "", "", true, true, DefaultMaxLineLength, 0, 0)
+			require.NoError(t, err)
+
+			_, rest, ok := strings.Cut(buf.String(), "\n")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantFormat+tt.wantLines, rest)
+		})
+	}
+}
+
+func TestViewer_ViewFile_Limits(t *testing.T) {
+	oldMax, oldAllow := fileops.MaxFileSize, fileops.AllowBinary
+	defer func() { fileops.MaxFileSize, fileops.AllowBinary = oldMax, oldAllow }()
+	tmpDir := t.TempDir()
+
+	binary := filepath.Join(tmpDir, "image.png")
+	require.NoError(t, os.WriteFile(binary, []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0o644))
+	var buf bytes.Buffer
+	_, err := NewViewer(&buf).View(binary, "", "", true, false, DefaultMaxLineLength, 0, 0)
+	require.ErrorIs(t, err, fileops.ErrBinary)
+
+	fileops.AllowBinary = true
+	_, err = NewViewer(&buf).View(binary, "", "", true, false, DefaultMaxLineLength, 0, 0)
+	require.NoError(t, err)
+
+	large := filepath.Join(tmpDir, "large.log")
+	var content strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&content, "caf\xe9 %d\n", i)
+	}
+	require.NoError(t, os.WriteFile(large, []byte(content.String()), 0o644))
+
+	fileops.MaxFileSize = 1 << 10
+	buf.Reset()
+	_, err = NewViewer(&buf).View(large, "2,3", "", true, true, DefaultMaxLineLength, 0, 0)
+	require.NoError(t, err)
+	header, rest, _ := strings.Cut(buf.String(), "\n")
+	assert.True(t, strings.HasPrefix(header, "size: 8.7 KiB (over the --max-file-size limit, not hashed) mtime: "), header)
+	assert.Equal(t, "format: ISO-8859-1, LF\n     2\tcafé 2\n     3\tcafé 3\n", rest)
+
+	// Without a range or limits, no more of a huge file than the size limit
+	// is kept.
+	res, err := NewViewer(nil).View(large, "", "", true, false, DefaultMaxLineLength, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, res.SHA256)
+	assert.Len(t, res.Lines, 64)
+	assert.Equal(t, 1000, len(res.Lines)+res.More)
+
+	utf16 := filepath.Join(tmpDir, "utf16.txt")
+	require.NoError(t, os.WriteFile(utf16, append([]byte("\xff\xfe"), bytes.Repeat([]byte("a\x00\n\x00"), 1000)...), 0o644))
+	_, err = NewViewer(&buf).View(utf16, "1,1", "", true, false, DefaultMaxLineLength, 0, 0)
+	require.ErrorIs(t, err, fileops.ErrTooLarge)
+}
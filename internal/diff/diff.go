@@ -0,0 +1,192 @@
+// Package diff computes line diffs with the Myers algorithm and merges
+// concurrent changes to the same text.
+package diff
+
+// Hunk is a changed region between two sequences of lines: Old[OldStart:OldEnd]
+// was replaced by New[NewStart:NewEnd]. Either range may be empty.
+type Hunk struct {
+	OldStart, OldEnd int
+	NewStart, NewEnd int
+}
+
+// Lines returns the hunks that turn a into b, in order. Unchanged lines
+// between hunks are left out.
+func Lines(a, b []string) []Hunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	hunks := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for i := range hunks {
+		hunks[i].OldStart += prefix
+		hunks[i].OldEnd += prefix
+		hunks[i].NewStart += prefix
+		hunks[i].NewEnd += prefix
+	}
+	return hunks
+}
+
+// myers finds a shortest edit script from a to b as described in "An O(ND)
+// Difference Algorithm and Its Variations" and groups it into hunks. It uses
+// the linear space refinement from the paper, so memory stays proportional
+// to the input however different a and b are.
+func myers(a, b []string) []Hunk {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	if n == 0 || m == 0 {
+		return []Hunk{{OldEnd: n, NewEnd: m}}
+	}
+
+	s := &common{
+		keptA: make([]bool, n),
+		keptB: make([]bool, m),
+		v:     make([]int, 2*(n+m+4)),
+	}
+	s.keep(a, b, 0, 0)
+
+	// The kept lines of a and b pair up in order, and the runs between them
+	// are the hunks.
+	var hunks []Hunk
+	i, j := 0, 0
+	for i < n || j < m {
+		if i < n && j < m && s.keptA[i] && s.keptB[j] {
+			i++
+			j++
+			continue
+		}
+		h := Hunk{OldStart: i, NewStart: j}
+		for i < n && !s.keptA[i] {
+			i++
+		}
+		for j < m && !s.keptB[j] {
+			j++
+		}
+		h.OldEnd, h.NewEnd = i, j
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// common finds a longest common subsequence of two sequences of lines and
+// marks its lines in keptA and keptB. v holds the search frontiers of bisect,
+// which every call reuses.
+type common struct {
+	keptA, keptB []bool
+	v            []int
+}
+
+// keep marks the common lines of a and b, which start at aOff and bOff in
+// the whole sequences. The problem is split at a point on a shortest edit
+// path found by bisect and each half is solved on its own.
+func (s *common) keep(a, b []string, aOff, bOff int) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		s.keptA[aOff], s.keptB[bOff] = true, true
+		a, b = a[1:], b[1:]
+		aOff++
+		bOff++
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		s.keptA[aOff+len(a)-1], s.keptB[bOff+len(b)-1] = true, true
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+
+	x, y, ok := s.bisect(a, b)
+	if !ok {
+		return
+	}
+	s.keep(a[:x], b[:y], aOff, bOff)
+	s.keep(a[x:], b[y:], aOff+x, bOff+y)
+}
+
+// bisect searches for a shortest edit path from a to b from both ends at
+// once and returns the point where the two searches meet, which splits the
+// path in half. It returns false if a and b have no line in common. The
+// first and last lines of a and b must differ.
+func (s *common) bisect(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	forward, backward := s.v[:size], s.v[size:2*size]
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	// The searches meet on the forward step if the difference in length is
+	// odd, and on the backward step otherwise. Diagonals that run off the
+	// edges are trimmed from later steps.
+	delta := n - m
+	front := delta%2 != 0
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					fy := fx - (j - offset)
+					if fx >= n-x {
+						return fx, fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
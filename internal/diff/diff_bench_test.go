@@ -0,0 +1,50 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func BenchmarkLines(b *testing.B) {
+	sizes := []struct {
+		name  string
+		lines int
+	}{
+		{"small", 100},
+		{"medium", 1000},
+		{"large", 10000},
+	}
+
+	for _, size := range sizes {
+		b.Run(size.name, func(b *testing.B) {
+			a := make([]string, size.lines)
+			for i := range a {
+				a[i] = "line " + strconv.Itoa(i)
+			}
+			c := append([]string(nil), a...)
+			for i := 0; i < len(c); i += 50 {
+				c[i] = "changed " + strconv.Itoa(i)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				Lines(a, c)
+			}
+		})
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	var base strings.Builder
+	for i := 0; i < 1000; i++ {
+		base.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	ours := strings.Replace(base.String(), "line 10\n", "ours 10\n", 1)
+	theirs := strings.Replace(base.String(), "line 900\n", "theirs 900\n", 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Merge(base.String(), ours, theirs)
+	}
+}
@@ -0,0 +1,226 @@
+package diff
+
+import (
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// apply rebuilds b from a and the hunks returned by Lines.
+func apply(a, b []string, hunks []Hunk) []string {
+	var result []string
+	pos := 0
+	for _, h := range hunks {
+		result = append(result, a[pos:h.OldStart]...)
+		result = append(result, b[h.NewStart:h.NewEnd]...)
+		pos = h.OldEnd
+	}
+	return append(result, a[pos:]...)
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []Hunk
+	}{
+		{
+			name: "identical",
+			a:    "a b c",
+			b:    "a b c",
+		},
+		{
+			name: "replace middle",
+			a:    "a b c",
+			b:    "a X c",
+			want: []Hunk{{OldStart: 1, OldEnd: 2, NewStart: 1, NewEnd: 2}},
+		},
+		{
+			name: "insert at start",
+			a:    "b c",
+			b:    "a b c",
+			want: []Hunk{{OldStart: 0, OldEnd: 0, NewStart: 0, NewEnd: 1}},
+		},
+		{
+			name: "delete at end",
+			a:    "a b c",
+			b:    "a b",
+			want: []Hunk{{OldStart: 2, OldEnd: 3, NewStart: 2, NewEnd: 2}},
+		},
+		{
+			name: "separate changes",
+			a:    "a b c d e",
+			b:    "a X c d Y e",
+			want: []Hunk{
+				{OldStart: 1, OldEnd: 2, NewStart: 1, NewEnd: 2},
+				{OldStart: 4, OldEnd: 4, NewStart: 4, NewEnd: 5},
+			},
+		},
+		{
+			name: "everything replaced",
+			a:    "a b",
+			b:    "c d e",
+			want: []Hunk{{OldStart: 0, OldEnd: 2, NewStart: 0, NewEnd: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			got := Lines(a, b)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, b, apply(a, b, got))
+		})
+	}
+}
+
+func TestLines_Minimal(t *testing.T) {
+	a := strings.Fields("a b c a b b a")
+	b := strings.Fields("c b a b a c")
+
+	hunks := Lines(a, b)
+	assert.Equal(t, b, apply(a, b, hunks))
+
+	edits := 0
+	for _, h := range hunks {
+		edits += (h.OldEnd - h.OldStart) + (h.NewEnd - h.NewStart)
+	}
+	assert.Equal(t, 5, edits)
+}
+
+func TestLines_Random(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d"}
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = words[rng.Intn(len(words))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		hunks := Lines(a, b)
+		if got := apply(a, b, hunks); len(b) == 0 {
+			assert.Empty(t, got)
+		} else {
+			assert.Equal(t, b, got)
+		}
+
+		edits := 0
+		for _, h := range hunks {
+			edits += (h.OldEnd - h.OldStart) + (h.NewEnd - h.NewStart)
+		}
+		assert.Equal(t, len(a)+len(b)-2*lcs(a, b), edits, "diff of %v and %v is not minimal", a, b)
+	}
+}
+
+func TestLines_LargeDisjoint(t *testing.T) {
+	const n = 5000
+	a, b := make([]string, n), make([]string, n)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	hunks := Lines(a, b)
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, []Hunk{{OldStart: 0, OldEnd: n, NewStart: 0, NewEnd: n}}, hunks)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(16<<20), "memory should grow with the input, not the edit distance squared")
+
+	// A line shared in the middle splits the search.
+	b[n/2] = a[n/2]
+	hunks = Lines(a, b)
+	assert.Equal(t, b, apply(a, b, hunks))
+	assert.Len(t, hunks, 2)
+}
+
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i][j] = dp[i-1][j-1] + 1
+			} else {
+				dp[i][j] = max(dp[i-1][j], dp[i][j-1])
+			}
+		}
+	}
+	return dp[len(a)][len(b)]
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts []Conflict
+	}{
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nB\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "separate changes",
+			base:   "1\n2\n3\n4\n5\n",
+			ours:   "1\nTWO\n3\n4\n5\n",
+			theirs: "1\n2\n3\n4\nFIVE\n",
+			want:   "1\nTWO\n3\n4\nFIVE\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\n",
+			ours:   "a\nX\n",
+			theirs: "a\nX\n",
+			want:   "a\nX\n",
+		},
+		{
+			name:          "conflicting change",
+			base:          "a\nb\nc\n",
+			ours:          "a\nX\nc\n",
+			theirs:        "a\nY\nc\n",
+			want:          "a\n<<<<<<< ours\nX\n=======\nY\n>>>>>>> theirs\nc\n",
+			wantConflicts: []Conflict{{Start: 2, End: 2}},
+		},
+		{
+			name:          "adjacent changes conflict",
+			base:          "a\nb\n",
+			ours:          "A\nb\n",
+			theirs:        "a\nB\n",
+			want:          "<<<<<<< ours\nA\nb\n=======\na\nB\n>>>>>>> theirs\n",
+			wantConflicts: []Conflict{{Start: 1, End: 2}},
+		},
+		{
+			name:   "missing trailing newline",
+			base:   "a\nb\nc",
+			ours:   "A\nb\nc",
+			theirs: "a\nb\nC",
+			want:   "A\nb\nC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge(tt.base, tt.ours, tt.theirs)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantConflicts, conflicts)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict is a region where ours and theirs both changed the base text
+// differently. Start and End are 1-based line numbers in the base, with End
+// less than Start when both sides inserted lines at the same point.
+type Conflict struct {
+	Start, End int
+}
+
+// Merge applies the changes from base to theirs on top of ours, keeping the
+// changes from base to ours. Regions changed by both sides are conflicts
+// unless both made the same change; the merged text holds conflict markers
+// for them.
+func Merge(base, ours, theirs string) (string, []Conflict) {
+	baseLines := SplitLines(base)
+	oursLines := SplitLines(ours)
+	theirsLines := SplitLines(theirs)
+
+	oursHunks := Lines(baseLines, oursLines)
+	theirsHunks := Lines(baseLines, theirsLines)
+
+	var merged strings.Builder
+	var conflicts []Conflict
+	pos, i, j := 0, 0, 0
+	for i < len(oursHunks) || j < len(theirsHunks) {
+		// Start a region at the earliest remaining hunk and grow it while
+		// hunks from either side overlap or touch it.
+		var lo, hi int
+		if j >= len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].OldStart <= theirsHunks[j].OldStart) {
+			lo, hi = oursHunks[i].OldStart, oursHunks[i].OldEnd
+		} else {
+			lo, hi = theirsHunks[j].OldStart, theirsHunks[j].OldEnd
+		}
+		firstOurs, firstTheirs := i, j
+		for {
+			if i < len(oursHunks) && oursHunks[i].OldStart <= hi {
+				hi = max(hi, oursHunks[i].OldEnd)
+				i++
+			} else if j < len(theirsHunks) && theirsHunks[j].OldStart <= hi {
+				hi = max(hi, theirsHunks[j].OldEnd)
+				j++
+			} else {
+				break
+			}
+		}
+
+		writeLines(&merged, baseLines[pos:lo])
+		pos = hi
+
+		oursRegion := region(baseLines, oursLines, oursHunks[firstOurs:i], lo, hi)
+		theirsRegion := region(baseLines, theirsLines, theirsHunks[firstTheirs:j], lo, hi)
+		switch {
+		case firstTheirs == j:
+			writeLines(&merged, oursRegion)
+		case firstOurs == i:
+			writeLines(&merged, theirsRegion)
+		case slices.Equal(oursRegion, theirsRegion):
+			writeLines(&merged, oursRegion)
+		default:
+			conflicts = append(conflicts, Conflict{Start: lo + 1, End: hi})
+			merged.WriteString("<<<<<<< ours\n")
+			writeConflictSide(&merged, oursRegion)
+			merged.WriteString("=======\n")
+			writeConflictSide(&merged, theirsRegion)
+			merged.WriteString(">>>>>>> theirs\n")
+		}
+	}
+	writeLines(&merged, baseLines[pos:])
+
+	return merged.String(), conflicts
+}
+
+// region returns the lines that replace base[lo:hi] on one side, given that
+// side's hunks within the region.
+func region(base, side []string, hunks []Hunk, lo, hi int) []string {
+	if len(hunks) == 0 {
+		return base[lo:hi]
+	}
+	first, last := hunks[0], hunks[len(hunks)-1]
+	start := first.NewStart - (first.OldStart - lo)
+	end := last.NewEnd + (hi - last.OldEnd)
+	return side[start:end]
+}
+
+// SplitLines splits s into lines that keep their line terminators, so joining
+// them gives back s exactly.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+func writeConflictSide(b *strings.Builder, lines []string) {
+	writeLines(b, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		b.WriteString("\n")
+	}
+}
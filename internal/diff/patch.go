@@ -0,0 +1,292 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the name a patch gives the missing side of a created or deleted
+// file.
+const DevNull = "/dev/null"
+
+// FilePatch is the part of a unified diff that changes one file. OldName is
+// DevNull if the patch creates the file and NewName is DevNull if it deletes
+// it.
+type FilePatch struct {
+	OldName string
+	NewName string
+	Hunks   []PatchHunk
+}
+
+// PatchHunk is one @@ hunk of a FilePatch. Starts are 1-based as written in
+// the header. Lines keep their ' ', '-' or '+' prefix and their terminator,
+// which is missing when the line is followed by "\ No newline at end of
+// file".
+type PatchHunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []string
+}
+
+// HunkResult reports how a hunk was applied. Line is the 1-based line of the
+// patched content where the hunk starts, Offset the number of lines between
+// that line and where the header placed it, and Fuzz the number of context
+// lines ignored at each end to make it fit.
+type HunkResult struct {
+	Applied bool
+	Line    int
+	Offset  int
+	Fuzz    int
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses a unified diff changing any number of files. Lines
+// outside of file headers and hunks, such as "diff --git" and "index" lines,
+// are ignored. The a/ and b/ prefixes of git diffs are removed from names.
+func ParsePatch(patch string) ([]FilePatch, error) {
+	lines := SplitLines(patch)
+	var files []FilePatch
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			files = append(files, FilePatch{
+				OldName: headerName(line),
+				NewName: headerName(strings.TrimRight(lines[i+1], "\r\n")),
+			})
+			i++
+		case strings.HasPrefix(line, "@@ "):
+			if len(files) == 0 {
+				return nil, fmt.Errorf("line %d: hunk before any file header", i+1)
+			}
+			hunk, n, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			file := &files[len(files)-1]
+			file.Hunks = append(file.Hunks, hunk)
+			i += n - 1
+		}
+	}
+
+	for i := range files {
+		stripGitPrefixes(&files[i])
+	}
+	return files, nil
+}
+
+// headerName returns the file name of a --- or +++ line, without the
+// timestamp diff writes after a tab.
+func headerName(line string) string {
+	name := line[4:]
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+func stripGitPrefixes(file *FilePatch) {
+	oldOK := file.OldName == DevNull || strings.HasPrefix(file.OldName, "a/")
+	newOK := file.NewName == DevNull || strings.HasPrefix(file.NewName, "b/")
+	if !oldOK || !newOK || (file.OldName == DevNull && file.NewName == DevNull) {
+		return
+	}
+	if file.OldName != DevNull {
+		file.OldName = file.OldName[2:]
+	}
+	if file.NewName != DevNull {
+		file.NewName = file.NewName[2:]
+	}
+}
+
+// parseHunk parses the hunk whose header is lines[start] and returns it with
+// the number of lines it spans.
+func parseHunk(lines []string, start int) (PatchHunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[start])
+	if m == nil {
+		return PatchHunk{}, 0, fmt.Errorf("line %d: invalid hunk header %q", start+1, strings.TrimRight(lines[start], "\r\n"))
+	}
+
+	hunk := PatchHunk{
+		OldStart: atoi(m[1], 0),
+		OldCount: atoi(m[2], 1),
+		NewStart: atoi(m[3], 0),
+		NewCount: atoi(m[4], 1),
+	}
+
+	oldLeft, newLeft := hunk.OldCount, hunk.NewCount
+	i := start + 1
+	for ; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		if line == "\n" || line == "\r\n" {
+			// Editors often strip the space from blank context lines.
+			line = " " + line
+		}
+		switch line[0] {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\\':
+			if len(hunk.Lines) > 0 {
+				last := &hunk.Lines[len(hunk.Lines)-1]
+				*last = strings.TrimSuffix(*last, "\n")
+			}
+			continue
+		default:
+			return PatchHunk{}, 0, fmt.Errorf("line %d: hunk %q has %d fewer old and %d fewer new lines than its header says",
+				start+1, strings.TrimRight(lines[start], "\r\n"), oldLeft, newLeft)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return PatchHunk{}, 0, fmt.Errorf("line %d: hunk %q has more lines than its header says",
+				start+1, strings.TrimRight(lines[start], "\r\n"))
+		}
+		hunk.Lines = append(hunk.Lines, line)
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return PatchHunk{}, 0, fmt.Errorf("line %d: hunk %q is cut short", start+1, strings.TrimRight(lines[start], "\r\n"))
+	}
+
+	if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		last := &hunk.Lines[len(hunk.Lines)-1]
+		*last = strings.TrimSuffix(*last, "\n")
+		i++
+	}
+	return hunk, i - start, nil
+}
+
+func atoi(s string, empty int) int {
+	if s == "" {
+		return empty
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// Apply applies the hunks of p to content in order and returns the result
+// with a report for each hunk. A hunk is tried where its header places it,
+// shifted by the offset of the hunks before it, and then at increasing
+// distances up to maxOffset lines, or any distance if maxOffset is negative.
+// If it still does not fit, up to fuzz lines of context are ignored at each
+// end. Hunks that do not fit are left out.
+func (p *FilePatch) Apply(content string, fuzz, maxOffset int) (string, []HunkResult) {
+	lines := SplitLines(content)
+	var out []string
+	results := make([]HunkResult, len(p.Hunks))
+	pos, delta := 0, 0
+	for i, hunk := range p.Hunks {
+		tried := -1
+		for f := 0; f <= fuzz; f++ {
+			hunkLines, oldLines, lead := hunk.trim(f)
+			if len(hunkLines) == tried {
+				// No more context to ignore.
+				break
+			}
+			tried = len(hunkLines)
+
+			expected := hunk.OldStart - 1 + lead + delta
+			if hunk.OldCount == 0 {
+				expected = hunk.OldStart + delta
+			}
+			at, ok := find(lines, oldLines, pos, expected, maxOffset)
+			if !ok {
+				continue
+			}
+
+			out = append(out, lines[pos:at]...)
+			results[i] = HunkResult{Applied: true, Line: len(out) + 1, Offset: at - expected, Fuzz: f}
+			out = append(out, replacement(lines[at:at+len(oldLines)], hunkLines)...)
+			pos = at + len(oldLines)
+			delta += at - expected
+			break
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	// A line matched without its terminator may no longer be the last one.
+	for i := 0; i < len(out)-1; i++ {
+		if !strings.HasSuffix(out[i], "\n") {
+			out[i] += "\n"
+		}
+	}
+	return strings.Join(out, ""), results
+}
+
+// trim returns the lines of h left after ignoring up to fuzz context lines
+// at each end, the old content those lines expect without prefixes, and the
+// number of leading lines ignored.
+func (h *PatchHunk) trim(fuzz int) (lines, oldLines []string, lead int) {
+	lines = h.Lines
+	for lead < fuzz && lead < len(lines) && lines[lead][0] == ' ' {
+		lead++
+	}
+	trail := 0
+	for trail < fuzz && len(lines)-trail > lead && lines[len(lines)-1-trail][0] == ' ' {
+		trail++
+	}
+	lines = lines[lead : len(lines)-trail]
+
+	for _, line := range lines {
+		if line[0] != '+' {
+			oldLines = append(oldLines, line[1:])
+		}
+	}
+	return lines, oldLines, lead
+}
+
+// find returns the index of lines at or after pos where want matches,
+// searching outwards from expected up to maxOffset lines away.
+func find(lines, want []string, pos, expected, maxOffset int) (int, bool) {
+	last := len(lines) - len(want)
+	for d := 0; maxOffset < 0 || d <= maxOffset; d++ {
+		if expected-d < pos && expected+d > last {
+			return 0, false
+		}
+		if at := expected - d; at >= pos && at <= last && matches(lines[at:], want) {
+			return at, true
+		}
+		if at := expected + d; d > 0 && at >= pos && at <= last && matches(lines[at:], want) {
+			return at, true
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether lines starts with want. A line matches with or
+// without its terminator, since patches rarely mark a missing final newline.
+func matches(lines, want []string) bool {
+	for i, w := range want {
+		if lines[i] != w && strings.TrimRight(lines[i], "\r\n") != strings.TrimRight(w, "\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// replacement returns the lines replacing matched, the file lines matched by
+// hunk lines. Context lines are taken from the file so their line endings are
+// kept.
+func replacement(matched, lines []string) []string {
+	var out []string
+	i := 0
+	for _, line := range lines {
+		switch line[0] {
+		case ' ':
+			out = append(out, matched[i])
+			i++
+		case '-':
+			i++
+		case '+':
+			out = append(out, line[1:])
+		}
+	}
+	return out
+}
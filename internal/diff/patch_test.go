@@ -0,0 +1,207 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatch(t *testing.T) {
+	patch := "diff --git a/main.go b/main.go\n" +
+		"index 83db48f..bf269f4 100644\n" +
+		"--- a/main.go\t2024-05-01 12:00:00\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" package main\n" +
+		"\n" +
+		"-var x = 1\n" +
+		"\\ No newline at end of file\n" +
+		"+var x = 2\n" +
+		"--- /dev/null\n" +
+		"+++ b/new.txt\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+hello\n"
+
+	files, err := ParsePatch(patch)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "main.go", files[0].OldName)
+	assert.Equal(t, "main.go", files[0].NewName)
+	assert.Equal(t, []PatchHunk{{
+		OldStart: 1, OldCount: 3, NewStart: 1, NewCount: 3,
+		Lines: []string{" package main\n", " \n", "-var x = 1", "+var x = 2\n"},
+	}}, files[0].Hunks)
+
+	assert.Equal(t, DevNull, files[1].OldName)
+	assert.Equal(t, "new.txt", files[1].NewName)
+	assert.Equal(t, []PatchHunk{{
+		OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 1,
+		Lines: []string{"+hello\n"},
+	}}, files[1].Hunks)
+}
+
+func TestParsePatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			name:  "hunk without file",
+			patch: "@@ -1 +1 @@\n-a\n+b\n",
+			want:  "hunk before any file header",
+		},
+		{
+			name:  "cut short",
+			patch: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n",
+			want:  "cut short",
+		},
+		{
+			name:  "too few lines",
+			patch: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+c\ndiff --git a/c b/c\n",
+			want:  "fewer old",
+		},
+		{
+			name:  "too many lines",
+			patch: "--- a\n+++ b\n@@ -1 +1,2 @@\n-a\n-b\n+c\n",
+			want:  "more lines than its header says",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePatch(tt.patch)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestFilePatch_Apply(t *testing.T) {
+	content := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	tests := []struct {
+		name      string
+		content   string
+		patch     string
+		fuzz      int
+		maxOffset int
+		want      string
+		results   []HunkResult
+	}{
+		{
+			name:      "exact",
+			content:   content,
+			patch:     "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n",
+			maxOffset: -1,
+			want:      "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n",
+			results:   []HunkResult{{Applied: true, Line: 2}},
+		},
+		{
+			name:      "offset",
+			content:   "0\n0\n" + content,
+			patch:     "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n@@ -8,2 +8,2 @@\n 8\n-9\n+nine\n",
+			maxOffset: -1,
+			want:      "0\n0\n1\n2\nthree\n4\n5\n6\n7\n8\nnine\n10\n",
+			results:   []HunkResult{{Applied: true, Line: 4, Offset: 2}, {Applied: true, Line: 10}},
+		},
+		{
+			name:      "offset too large",
+			content:   "0\n0\n" + content,
+			patch:     "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n",
+			maxOffset: 1,
+			want:      "0\n0\n" + content,
+			results:   []HunkResult{{}},
+		},
+		{
+			name:      "fuzz",
+			content:   content,
+			patch:     "@@ -2,3 +2,3 @@\n two\n-3\n+three\n 4\n",
+			fuzz:      1,
+			maxOffset: -1,
+			want:      "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n",
+			results:   []HunkResult{{Applied: true, Line: 3, Fuzz: 1}},
+		},
+		{
+			name:      "context mismatch without fuzz",
+			content:   content,
+			patch:     "@@ -2,3 +2,3 @@\n two\n-3\n+three\n 4\n@@ -9 +9 @@\n-9\n+nine\n",
+			maxOffset: -1,
+			want:      "1\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n",
+			results:   []HunkResult{{}, {Applied: true, Line: 9}},
+		},
+		{
+			name:      "insert",
+			content:   "a\nb\n",
+			patch:     "@@ -1,0 +2 @@\n+x\n",
+			maxOffset: -1,
+			want:      "a\nx\nb\n",
+			results:   []HunkResult{{Applied: true, Line: 2}},
+		},
+		{
+			name:      "keeps context line endings",
+			content:   "a\r\nb\r\nc\r\n",
+			patch:     "@@ -1,3 +1,3 @@\n a\n-b\n+B\r\n c\n",
+			maxOffset: -1,
+			want:      "a\r\nB\r\nc\r\n",
+			results:   []HunkResult{{Applied: true, Line: 1}},
+		},
+		{
+			name:      "missing final newline",
+			content:   "a\nb",
+			patch:     "@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+			maxOffset: -1,
+			want:      "a\nb\nc\n",
+			results:   []HunkResult{{Applied: true, Line: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParsePatch("--- a\n+++ b\n" + tt.patch)
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+
+			got, results := files[0].Apply(tt.content, tt.fuzz, tt.maxOffset)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.results, results)
+		})
+	}
+}
+
+func TestFilePatch_Apply_Unified(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		var b strings.Builder
+		for i := rng.Intn(30); i > 0; i-- {
+			fmt.Fprintf(&b, "line %d\n", rng.Intn(6))
+		}
+		if rng.Intn(4) == 0 {
+			b.WriteString("no newline")
+		}
+		return b.String()
+	}
+
+	for i := 0; i < 200; i++ {
+		a, b := randomText(), randomText()
+		unified := Unified("a", "b", a, b, rng.Intn(4))
+		if unified == "" {
+			continue
+		}
+
+		files, err := ParsePatch(unified)
+		require.NoError(t, err, unified)
+		require.Len(t, files, 1)
+
+		got, results := files[0].Apply(a, 0, 0)
+		require.Equal(t, b, got, unified)
+		for _, result := range results {
+			require.True(t, result.Applied, unified)
+		}
+	}
+}
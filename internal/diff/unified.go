@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified returns the changes that turn a into b as a unified diff with
+// context unchanged lines around each change, in the format read by patch.
+// oldName and newName are used in the file headers. The result is empty if a
+// and b are equal.
+func Unified(oldName, newName, a, b string, context int) string {
+	oldLines, newLines := SplitLines(a), SplitLines(b)
+	hunks := Lines(oldLines, newLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n", oldName)
+	fmt.Fprintf(&out, "+++ %s\n", newName)
+
+	for len(hunks) > 0 {
+		// Changes whose context would touch or overlap share a hunk.
+		n := 1
+		for n < len(hunks) && hunks[n].OldStart-hunks[n-1].OldEnd <= 2*context {
+			n++
+		}
+		group := hunks[:n]
+		hunks = hunks[n:]
+
+		first, last := group[0], group[len(group)-1]
+		oldStart := max(first.OldStart-context, 0)
+		oldEnd := min(last.OldEnd+context, len(oldLines))
+		newStart := first.NewStart - (first.OldStart - oldStart)
+		newEnd := last.NewEnd + (oldEnd - last.OldEnd)
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldEnd), hunkRange(newStart, newEnd))
+		i := oldStart
+		for _, h := range group {
+			writePrefixed(&out, ' ', oldLines[i:h.OldStart])
+			writePrefixed(&out, '-', oldLines[h.OldStart:h.OldEnd])
+			writePrefixed(&out, '+', newLines[h.NewStart:h.NewEnd])
+			i = h.OldEnd
+		}
+		writePrefixed(&out, ' ', oldLines[i:oldEnd])
+	}
+	return out.String()
+}
+
+// hunkRange formats the 0-based half-open range [start, end) the way unified
+// diffs number lines: 1-based, with the count left out when it is 1, and
+// starting at the line before the range when it is empty.
+func hunkRange(start, end int) string {
+	switch end - start {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, end-start)
+	}
+}
+
+func writePrefixed(b *strings.Builder, prefix byte, lines []string) {
+	for _, line := range lines {
+		b.WriteByte(prefix)
+		b.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
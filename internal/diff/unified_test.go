@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		context int
+		want    string
+	}{
+		{
+			name: "identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name:    "insert near top keeps later lines unchanged",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:       "1\nnew\n2\n3\n4\n5\n6\n7\n8\n",
+			context: 3,
+			want: "--- old\n+++ new\n" +
+				"@@ -1,4 +1,5 @@\n 1\n+new\n 2\n 3\n 4\n",
+		},
+		{
+			name:    "blank lines are kept",
+			a:       "a\n\nb\n",
+			b:       "a\n\nc\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n \n-b\n+c\n",
+		},
+		{
+			name:    "distant changes get separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:       "X\n2\n3\n4\n5\n6\n7\n8\nY\n",
+			context: 1,
+			want: "--- old\n+++ new\n" +
+				"@@ -1,2 +1,2 @@\n-1\n+X\n 2\n" +
+				"@@ -8,2 +8,2 @@\n 8\n-9\n+Y\n",
+		},
+		{
+			name:    "close changes share a hunk",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "X\n2\n3\n4\nY\n",
+			context: 2,
+			want:    "--- old\n+++ new\n@@ -1,5 +1,5 @@\n-1\n+X\n 2\n 3\n 4\n-5\n+Y\n",
+		},
+		{
+			name:    "new file",
+			a:       "",
+			b:       "a\nb\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:    "deleted lines",
+			a:       "a\nb\nc\n",
+			b:       "a\n",
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -2,2 +1,0 @@\n-b\n-c\n",
+		},
+		{
+			name:    "missing final newline",
+			a:       "a\nb",
+			b:       "a\nb\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Unified("old", "new", tt.a, tt.b, tt.context))
+		})
+	}
+}
+
+func TestUnified_Patch(t *testing.T) {
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch not installed")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		var b strings.Builder
+		for i := rng.Intn(30); i > 0; i-- {
+			fmt.Fprintf(&b, "line %d\n", rng.Intn(6))
+		}
+		if rng.Intn(4) == 0 {
+			b.WriteString("no newline")
+		}
+		return b.String()
+	}
+
+	dir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		a, b := randomText(), randomText()
+		unified := Unified("file.txt", "file.txt", a, b, rng.Intn(4))
+		if unified == "" {
+			continue
+		}
+
+		path := filepath.Join(dir, "file.txt")
+		require.NoError(t, os.WriteFile(path, []byte(a), 0o644))
+		cmd := exec.Command("patch", "-s", "-p0", "file.txt")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(unified)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "patch failed: %s\n%s", out, unified)
+
+		patched, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, b, string(patched), unified)
+	}
+}
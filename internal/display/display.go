@@ -3,9 +3,15 @@ package display
 import (
 	"fmt"
 	"io"
-	"strings"
+
+	"github.com/RRethy/eddie/internal/diff"
+	"github.com/RRethy/eddie/internal/output"
 )
 
+// DiffContext is the number of unchanged lines shown around each change by
+// ShowDiff and ShowNewFileContent.
+var DiffContext = 3
+
 type Display struct {
 	w io.Writer
 }
@@ -15,87 +21,26 @@ func New(w io.Writer) *Display {
 }
 
 func (d *Display) ShowResult(path, content string) {
-	fmt.Fprintf(d.w, "\nResult of %s:\n", path)
-	fmt.Fprintln(d.w, content)
+	fmt.Fprint(d.w, output.ResultSection(path, content))
 }
 
+// ShowDiff prints the changes from before to after as a unified diff that
+// can be applied with patch.
 func (d *Display) ShowDiff(path, before, after string) {
-	fmt.Fprintf(d.w, "\nChanges in %s:\n", path)
-	fmt.Fprintln(d.w, "--- Before")
-	fmt.Fprintln(d.w, "+++ After")
-
-	beforeLines := strings.Split(before, "\n")
-	afterLines := strings.Split(after, "\n")
-
-	maxLines := len(beforeLines)
-	if len(afterLines) > maxLines {
-		maxLines = len(afterLines)
-	}
-
-	for i := 0; i < maxLines; i++ {
-		beforeLine := ""
-		afterLine := ""
-
-		if i < len(beforeLines) {
-			beforeLine = beforeLines[i]
-		}
-		if i < len(afterLines) {
-			afterLine = afterLines[i]
-		}
-
-		if beforeLine != afterLine {
-			if beforeLine != "" {
-				fmt.Fprintf(d.w, "-%s\n", beforeLine)
-			}
-			if afterLine != "" {
-				fmt.Fprintf(d.w, "+%s\n", afterLine)
-			}
-		}
-	}
-	fmt.Fprintln(d.w)
+	fmt.Fprint(d.w, output.DiffSection(path, Diff(path, before, after)))
 }
 
 func (d *Display) ShowNewFileContent(path, content string) {
-	fmt.Fprintf(d.w, "\nContent of %s:\n", path)
-	fmt.Fprintln(d.w, "--- New file")
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		fmt.Fprintf(d.w, "+%s\n", line)
-	}
-	fmt.Fprintln(d.w)
+	fmt.Fprint(d.w, output.NewFileSection(path, NewFileDiff(path, content)))
 }
 
-func (d *Display) ShowInsertDiff(path, original, modified string, lineNum int) {
-	fmt.Fprintf(d.w, "\nChanges in %s:\n", path)
-	fmt.Fprintln(d.w, "--- Before")
-	fmt.Fprintln(d.w, "+++ After")
-
-	origLines := strings.Split(original, "\n")
-	modLines := strings.Split(modified, "\n")
-
-	start := lineNum - 3
-	if start < 1 {
-		start = 1
-	}
-	end := lineNum + 3
-	if end > len(modLines) {
-		end = len(modLines)
-	}
+// Diff returns the unified diff of the changes from before to after that
+// ShowDiff prints, or "" if there are none.
+func Diff(path, before, after string) string {
+	return diff.Unified(path, path, before, after, DiffContext)
+}
 
-	for i := start; i <= end; i++ {
-		if i == lineNum {
-			if i <= len(modLines) {
-				fmt.Fprintf(d.w, "+%s\n", modLines[i-1])
-			}
-		} else {
-			origIdx := i
-			if i > lineNum {
-				origIdx = i - 1
-			}
-			if origIdx <= len(origLines) && origIdx > 0 {
-				fmt.Fprintf(d.w, " %s\n", origLines[origIdx-1])
-			}
-		}
-	}
-	fmt.Fprintln(d.w)
+// NewFileDiff returns the unified diff that creates path with content.
+func NewFileDiff(path, content string) string {
+	return diff.Unified(diff.DevNull, path, "", content, DiffContext)
 }
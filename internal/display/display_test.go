@@ -63,64 +63,35 @@ func TestDisplay_ShowDiff(t *testing.T) {
 		path     string
 		before   string
 		after    string
-		expected []string
+		expected string
 	}{
 		{
-			name:   "single line change",
-			path:   "test.txt",
-			before: "hello world",
-			after:  "hello there",
-			expected: []string{
-				"Changes in test.txt:",
-				"--- Before",
-				"+++ After",
-				"-hello world",
-				"+hello there",
-			},
+			name:     "single line change",
+			path:     "test.txt",
+			before:   "hello world\n",
+			after:    "hello there\n",
+			expected: "\nChanges in test.txt:\n--- test.txt\n+++ test.txt\n@@ -1 +1 @@\n-hello world\n+hello there\n\n",
 		},
 		{
-			name:   "multiline changes",
-			path:   "config.txt",
-			before: "line1\nline2\nline3",
-			after:  "line1\nmodified\nline3",
-			expected: []string{
-				"Changes in config.txt:",
-				"--- Before",
-				"+++ After",
-				"-line2",
-				"+modified",
-			},
+			name:     "insert keeps following lines unchanged",
+			path:     "config.txt",
+			before:   "line1\n\nline2\nline3\nline4\nline5\n",
+			after:    "line1\ninserted\n\nline2\nline3\nline4\nline5\n",
+			expected: "\nChanges in config.txt:\n--- config.txt\n+++ config.txt\n@@ -1,4 +1,5 @@\n line1\n+inserted\n \n line2\n line3\n\n",
 		},
 		{
-			name:   "addition",
-			path:   "add.txt",
-			before: "existing",
-			after:  "existing\nnew line",
-			expected: []string{
-				"Changes in add.txt:",
-				"+new line",
-			},
+			name:     "deletion without trailing newline",
+			path:     "del.txt",
+			before:   "keep\nremove",
+			after:    "keep",
+			expected: "\nChanges in del.txt:\n--- del.txt\n+++ del.txt\n@@ -1,2 +1 @@\n-keep\n-remove\n\\ No newline at end of file\n+keep\n\\ No newline at end of file\n\n",
 		},
 		{
-			name:   "deletion",
-			path:   "del.txt",
-			before: "keep\nremove",
-			after:  "keep",
-			expected: []string{
-				"Changes in del.txt:",
-				"-remove",
-			},
-		},
-		{
-			name:   "no changes",
-			path:   "same.txt",
-			before: "unchanged",
-			after:  "unchanged",
-			expected: []string{
-				"Changes in same.txt:",
-				"--- Before",
-				"+++ After",
-			},
+			name:     "no changes",
+			path:     "same.txt",
+			before:   "unchanged",
+			after:    "unchanged",
+			expected: "\nChanges in same.txt:\nNo changes\n\n",
 		},
 	}
 
@@ -129,130 +100,49 @@ func TestDisplay_ShowDiff(t *testing.T) {
 			var buf bytes.Buffer
 			d := New(&buf)
 			d.ShowDiff(tt.path, tt.before, tt.after)
-
-			output := buf.String()
-			for _, exp := range tt.expected {
-				assert.Contains(t, output, exp)
-			}
+			assert.Equal(t, tt.expected, buf.String())
 		})
 	}
 }
 
-func TestDisplay_ShowNewFileContent(t *testing.T) {
-	tests := []struct {
-		name     string
-		path     string
-		content  string
-		expected []string
-	}{
-		{
-			name:    "single line",
-			path:    "new.txt",
-			content: "hello world",
-			expected: []string{
-				"Content of new.txt:",
-				"--- New file",
-				"+hello world",
-			},
-		},
-		{
-			name:    "multiline content",
-			path:    "multi.txt",
-			content: "line1\nline2\nline3",
-			expected: []string{
-				"Content of multi.txt:",
-				"--- New file",
-				"+line1",
-				"+line2",
-				"+line3",
-			},
-		},
-		{
-			name:    "empty file",
-			path:    "empty.txt",
-			content: "",
-			expected: []string{
-				"Content of empty.txt:",
-				"--- New file",
-				"+",
-			},
-		},
-	}
+func TestDisplay_ShowDiff_Context(t *testing.T) {
+	oldContext := DiffContext
+	defer func() { DiffContext = oldContext }()
+	DiffContext = 1
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var buf bytes.Buffer
-			d := New(&buf)
-			d.ShowNewFileContent(tt.path, tt.content)
+	var buf bytes.Buffer
+	d := New(&buf)
+	d.ShowDiff("context.txt", "1\n2\n3\n4\n5\n6\n7\n", "1\n2\n3\nX\n5\n6\n7\n")
 
-			output := buf.String()
-			for _, exp := range tt.expected {
-				assert.Contains(t, output, exp)
-			}
-		})
-	}
+	assert.Contains(t, buf.String(), "@@ -3,3 +3,3 @@\n 3\n-4\n+X\n 5\n")
+	assert.NotContains(t, buf.String(), " 2\n")
+	assert.NotContains(t, buf.String(), " 6\n")
 }
 
-func TestDisplay_ShowInsertDiff(t *testing.T) {
+func TestDisplay_ShowNewFileContent(t *testing.T) {
 	tests := []struct {
 		name     string
 		path     string
-		original string
-		modified string
-		expected []string
-		lineNum  int
+		content  string
+		expected string
 	}{
 		{
-			name:     "insert at beginning",
-			path:     "test.txt",
-			original: "line2\nline3\nline4",
-			modified: "inserted\nline2\nline3\nline4",
-			lineNum:  1,
-			expected: []string{
-				"Changes in test.txt:",
-				"--- Before",
-				"+++ After",
-				"+inserted",
-				" line2",
-				" line3",
-			},
+			name:     "multiline content",
+			path:     "multi.txt",
+			content:  "line1\nline2\nline3\n",
+			expected: "\nContent of multi.txt:\n--- /dev/null\n+++ multi.txt\n@@ -0,0 +1,3 @@\n+line1\n+line2\n+line3\n\n",
 		},
 		{
-			name:     "insert in middle",
-			path:     "mid.txt",
-			original: "line1\nline2\nline3\nline4\nline5",
-			modified: "line1\nline2\ninserted\nline3\nline4\nline5",
-			lineNum:  3,
-			expected: []string{
-				"Changes in mid.txt:",
-				"+inserted",
-				" line2",
-				" line3",
-			},
-		},
-		{
-			name:     "insert at end",
-			path:     "end.txt",
-			original: "line1\nline2",
-			modified: "line1\nline2\ninserted",
-			lineNum:  3,
-			expected: []string{
-				"Changes in end.txt:",
-				"+inserted",
-				" line2",
-			},
+			name:     "no trailing newline",
+			path:     "new.txt",
+			content:  "hello world",
+			expected: "\nContent of new.txt:\n--- /dev/null\n+++ new.txt\n@@ -0,0 +1 @@\n+hello world\n\\ No newline at end of file\n\n",
 		},
 		{
-			name:     "insert with context boundaries",
-			path:     "boundary.txt",
-			original: "line1",
-			modified: "inserted\nline1",
-			lineNum:  1,
-			expected: []string{
-				"Changes in boundary.txt:",
-				"+inserted",
-				" line1",
-			},
+			name:     "empty file",
+			path:     "empty.txt",
+			content:  "",
+			expected: "\nContent of empty.txt:\nNo changes\n\n",
 		},
 	}
 
@@ -260,34 +150,8 @@ func TestDisplay_ShowInsertDiff(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
 			d := New(&buf)
-			d.ShowInsertDiff(tt.path, tt.original, tt.modified, tt.lineNum)
-
-			output := buf.String()
-			for _, exp := range tt.expected {
-				assert.Contains(t, output, exp)
-			}
+			d.ShowNewFileContent(tt.path, tt.content)
+			assert.Equal(t, tt.expected, buf.String())
 		})
 	}
 }
-
-func TestDisplay_ShowInsertDiff_ContextWindow(t *testing.T) {
-	original := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9"
-	modified := "line1\nline2\nline3\nline4\ninserted\nline5\nline6\nline7\nline8\nline9"
-	lineNum := 5
-
-	var buf bytes.Buffer
-	d := New(&buf)
-	d.ShowInsertDiff("context.txt", original, modified, lineNum)
-	output := buf.String()
-
-	assert.Contains(t, output, " line2")
-	assert.Contains(t, output, " line3")
-	assert.Contains(t, output, " line4")
-	assert.Contains(t, output, "+inserted")
-	assert.Contains(t, output, " line5")
-	assert.Contains(t, output, " line6")
-	assert.Contains(t, output, " line7")
-
-	assert.NotContains(t, output, " line1")
-	assert.NotContains(t, output, " line8")
-}
@@ -0,0 +1,92 @@
+package fileops
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteFileAtomic replaces path with data without ever leaving a partially
+// written file behind. The data goes to a temporary file in the same
+// directory, which is synced and renamed over path. If path already exists,
+// its mode, ownership and extended attributes are carried over to the new
+// file. Otherwise the file is created with mode less the umask, as
+// os.WriteFile would. Hard links to path are not preserved.
+func WriteFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	existing, statErr := os.Stat(path)
+
+	perm := mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	tmp, err := createTemp(dir, "."+filepath.Base(path)+".eddie-", perm)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if statErr == nil {
+		err = tmp.Chmod(perm)
+		if err != nil {
+			return fmt.Errorf("chmod temp file: %w", err)
+		}
+		err = preserveOwner(tmp, existing)
+		if err != nil {
+			return fmt.Errorf("preserve ownership: %w", err)
+		}
+		err = copyXattrs(path, tmp.Name())
+		if err != nil {
+			return fmt.Errorf("preserve extended attributes: %w", err)
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	renamed = true
+
+	syncDir(dir)
+	return nil
+}
+
+// createTemp creates a new file in dir whose name starts with prefix, like
+// os.CreateTemp, but with permissions perm, which the umask applies to.
+func createTemp(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"), Err: os.ErrExist}
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -0,0 +1,121 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "file.sh")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+	require.NoError(t, os.Chmod(path, 0o755))
+
+	require.NoError(t, WriteFileAtomic(path, []byte("new content"), 0o755))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new content", string(content))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temp file should be renamed into place")
+}
+
+func TestWriteFileAtomic_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := WriteFileAtomic(filepath.Join(tmpDir, "missing", "file.txt"), []byte("x"), 0o644)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "create temp file")
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFileOps_WriteFileContent_Symlinks(t *testing.T) {
+	oldPolicy := Symlinks
+	defer func() { Symlinks = oldPolicy }()
+
+	tests := []struct {
+		name        string
+		policy      SymlinkPolicy
+		wantErr     string
+		wantTarget  string
+		wantLink    string
+		wantSymlink bool
+	}{
+		{
+			name:        "follow",
+			policy:      SymlinkFollow,
+			wantTarget:  "new",
+			wantLink:    "new",
+			wantSymlink: true,
+		},
+		{
+			name:        "refuse",
+			policy:      SymlinkRefuse,
+			wantErr:     "refusing to write through symlink",
+			wantTarget:  "old",
+			wantLink:    "old",
+			wantSymlink: true,
+		},
+		{
+			name:        "replace",
+			policy:      SymlinkReplace,
+			wantTarget:  "old",
+			wantLink:    "new",
+			wantSymlink: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			target := filepath.Join(tmpDir, "target.txt")
+			link := filepath.Join(tmpDir, "link.txt")
+			require.NoError(t, os.WriteFile(target, []byte("old"), 0o644))
+			require.NoError(t, os.Symlink(target, link))
+
+			Symlinks = tt.policy
+			err := (&FileOps{}).WriteFileContent(link, "new", 0o644)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			content, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTarget, string(content))
+
+			content, err = os.ReadFile(link)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLink, string(content))
+
+			info, err := os.Lstat(link)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSymlink, info.Mode()&os.ModeSymlink != 0)
+		})
+	}
+}
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	policy, err := ParseSymlinkPolicy("replace")
+	require.NoError(t, err)
+	assert.Equal(t, SymlinkReplace, policy)
+
+	_, err = ParseSymlinkPolicy("copy")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid symlink policy")
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic_Umask(t *testing.T) {
+	oldUmask := syscall.Umask(0o027)
+	defer syscall.Umask(oldUmask)
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "new.txt")
+	require.NoError(t, WriteFileAtomic(path, []byte("new"), 0o666))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm(), "new files should honor the umask")
+
+	// The mode of an existing file is kept as it is.
+	existing := filepath.Join(tmpDir, "existing.sh")
+	require.NoError(t, os.WriteFile(existing, []byte("old"), 0o600))
+	require.NoError(t, os.Chmod(existing, 0o775))
+	require.NoError(t, WriteFileAtomic(existing, []byte("new"), 0o775))
+
+	info, err = os.Stat(existing)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o775), info.Mode().Perm())
+}
@@ -23,6 +23,9 @@ func (f *FileOps) ValidateNotDir(path string, info os.FileInfo, operation string
 	return nil
 }
 
+// ReadFileContentForOperation reads the file at path for operation, such as
+// "replace strings in". It fails for directories, files over MaxFileSize and,
+// unless AllowBinary is set, files that look binary.
 func (f *FileOps) ReadFileContentForOperation(path, operation string) (string, os.FileInfo, error) {
 	info, err := f.ValidateFileExists(path)
 	if err != nil {
@@ -33,34 +36,89 @@ func (f *FileOps) ReadFileContentForOperation(path, operation string) (string, o
 		return "", nil, err
 	}
 
+	if err := CheckSize(path, (*info).Size()); err != nil {
+		return "", nil, err
+	}
+
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return "", nil, fmt.Errorf("read file %s: %w", path, err)
 	}
 
+	if !AllowBinary && LooksBinary(content[:min(len(content), SniffLen)]) {
+		return "", nil, fmt.Errorf("%w: refusing to %s %s (see --allow-binary)", ErrBinary, operation, path)
+	}
+
 	return string(content), *info, nil
 }
 
+// WriteFileContent atomically replaces path with content, applying the
+// Symlinks policy if path is a symlink.
 func (f *FileOps) WriteFileContent(path, content string, mode os.FileMode) error {
-	err := os.WriteFile(path, []byte(content), mode)
+	target, err := resolveWritePath(path)
+	if err != nil {
+		return err
+	}
+
+	err = WriteFileAtomic(target, []byte(content), mode)
 	if err != nil {
 		return fmt.Errorf("write file %s: %w", path, err)
 	}
 	return nil
 }
 
-func (f *FileOps) CreateFile(path, content string) error {
+// CheckWrite returns an error if WriteFileContent or CreateFile would refuse
+// to write path: the Symlinks policy forbids it, path is a directory, or a
+// parent of a new file is not a directory. It lets a change of several files
+// fail before any of them is written.
+func (f *FileOps) CheckWrite(path string) error {
+	target, err := resolveWritePath(path)
+	if err != nil {
+		return err
+	}
+
+	for dir := target; ; dir = filepath.Dir(dir) {
+		// Parents that are missing, or that are files, make stat fail, so
+		// the nearest one that exists is checked.
+		info, err := os.Stat(dir)
+		if err != nil && dir != filepath.Dir(dir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if dir == target && info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		if dir != target && !info.IsDir() {
+			return fmt.Errorf("cannot write %s: %s is not a directory", path, dir)
+		}
+		return nil
+	}
+}
+
+// CreateFile writes content to a new file at path, creating any missing parent
+// directories. It returns the directories it created, outermost first.
+func (f *FileOps) CreateFile(path, content string) ([]string, error) {
 	if _, err := os.Stat(path); err == nil {
-		return fmt.Errorf("file already exists: %s", path)
+		return nil, fmt.Errorf("file already exists: %s", path)
 	}
 
+	var createdDirs []string
 	dir := filepath.Dir(path)
 	if dir != "." && dir != "/" {
+		for d := dir; ; d = filepath.Dir(d) {
+			if _, err := os.Stat(d); err == nil || d == filepath.Dir(d) {
+				break
+			}
+			createdDirs = append([]string{d}, createdDirs...)
+		}
+
 		err := os.MkdirAll(dir, 0o755)
 		if err != nil {
-			return fmt.Errorf("create directories %s: %w", dir, err)
+			return nil, fmt.Errorf("create directories %s: %w", dir, err)
 		}
 	}
 
-	return f.WriteFileContent(path, content, 0o644)
+	return createdDirs, f.WriteFileContent(path, content, 0o644)
 }
@@ -224,15 +224,37 @@ func TestFileOps_WriteFileContent(t *testing.T) {
 	}
 }
 
+func TestFileOps_CheckWrite(t *testing.T) {
+	oldPolicy := Symlinks
+	defer func() { Symlinks = oldPolicy }()
+
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file.txt")
+	link := filepath.Join(tmpDir, "link.txt")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+	require.NoError(t, os.Symlink(file, link))
+
+	f := &FileOps{}
+	assert.NoError(t, f.CheckWrite(file))
+	assert.NoError(t, f.CheckWrite(filepath.Join(tmpDir, "new", "dir", "file.txt")))
+	assert.NoError(t, f.CheckWrite(link))
+	assert.ErrorContains(t, f.CheckWrite(tmpDir), "is a directory")
+	assert.ErrorContains(t, f.CheckWrite(filepath.Join(file, "sub", "new.txt")), "is not a directory")
+
+	Symlinks = SymlinkRefuse
+	assert.ErrorContains(t, f.CheckWrite(link), "refusing to write through symlink")
+}
+
 func TestFileOps_CreateFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	f := &FileOps{}
 
 	tests := []struct {
-		name    string
-		setup   func() string
-		content string
-		wantErr string
+		name        string
+		setup       func() string
+		content     string
+		wantErr     string
+		wantCreated []string
 	}{
 		{
 			name: "create new file",
@@ -246,7 +268,16 @@ func TestFileOps_CreateFile(t *testing.T) {
 			setup: func() string {
 				return filepath.Join(tmpDir, "subdir", "deep", "new.txt")
 			},
-			content: "deep content",
+			content:     "deep content",
+			wantCreated: []string{filepath.Join(tmpDir, "subdir"), filepath.Join(tmpDir, "subdir", "deep")},
+		},
+		{
+			name: "create file in partially existing directories",
+			setup: func() string {
+				return filepath.Join(tmpDir, "subdir", "other", "new.txt")
+			},
+			content:     "other content",
+			wantCreated: []string{filepath.Join(tmpDir, "subdir", "other")},
 		},
 		{
 			name: "file already exists",
@@ -264,13 +295,14 @@ func TestFileOps_CreateFile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup()
-			err := f.CreateFile(path, tt.content)
+			createdDirs, err := f.CreateFile(path, tt.content)
 
 			if tt.wantErr != "" {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.wantErr)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.wantCreated, createdDirs)
 
 				content, err := os.ReadFile(path)
 				require.NoError(t, err)
@@ -0,0 +1,306 @@
+package fileops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings detected by DetectFormat.
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+	EncodingLatin1  = "ISO-8859-1"
+)
+
+// Line ending styles detected by DetectFormat. Files with mixed line endings
+// are edited with their line endings as they are.
+const (
+	LineEndingLF    = "LF"
+	LineEndingCRLF  = "CRLF"
+	LineEndingMixed = "mixed"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// Format is how the text of a file is stored: its encoding, whether it starts
+// with a byte order mark and its line endings. The zero Format is UTF-8
+// without a BOM, with line endings left as they are.
+type Format struct {
+	Encoding   string `json:"encoding"`
+	BOM        bool   `json:"bom"`
+	LineEnding string `json:"line_ending"`
+}
+
+func (f Format) String() string {
+	parts := []string{f.Encoding}
+	if f.Encoding == "" {
+		parts[0] = EncodingUTF8
+	}
+	if f.BOM {
+		parts = append(parts, "BOM")
+	}
+	if f.LineEnding != "" {
+		parts = append(parts, f.LineEnding)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatDetector works out the Format of the bytes written to it, so the
+// format of a file can be detected while it is streamed elsewhere. The line
+// endings of UTF-16 text are only known once it is decoded, so they are left
+// empty for it.
+type FormatDetector struct {
+	n        int
+	head     []byte
+	carry    []byte
+	invalid  bool
+	prevCR   bool
+	lf, crlf int
+	zeroEven int
+	zeroOdd  int
+}
+
+func (d *FormatDetector) Write(p []byte) (int, error) {
+	if len(d.head) < 3 {
+		d.head = append(d.head, p[:min(len(p), 3-len(d.head))]...)
+	}
+
+	d.lf += bytes.Count(p, []byte("\n"))
+	d.crlf += bytes.Count(p, []byte("\r\n"))
+	if d.prevCR && len(p) > 0 && p[0] == '\n' {
+		d.crlf++
+	}
+	if len(p) > 0 {
+		d.prevCR = p[len(p)-1] == '\r'
+	}
+
+	if bytes.IndexByte(p, 0) >= 0 {
+		for i, b := range p {
+			if b != 0 {
+				continue
+			}
+			if (d.n+i)%2 == 0 {
+				d.zeroEven++
+			} else {
+				d.zeroOdd++
+			}
+		}
+	}
+
+	if !d.invalid {
+		data := append(d.carry, p...)
+		d.carry = nil
+		// Keep a rune cut off by the end of p for the next write.
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					d.carry = append([]byte(nil), data[i:]...)
+					data = data[:i]
+				}
+				break
+			}
+		}
+		d.invalid = !utf8.Valid(data)
+	}
+
+	d.n += len(p)
+	return len(p), nil
+}
+
+// Format returns the format of the bytes written so far. Text that is not
+// valid UTF-8 or UTF-16 is taken to be ISO-8859-1, in which any bytes are
+// valid.
+func (d *FormatDetector) Format() Format {
+	switch {
+	case bytes.HasPrefix(d.head, bomUTF8):
+		return Format{Encoding: EncodingUTF8, BOM: true, LineEnding: d.lineEnding()}
+	case bytes.HasPrefix(d.head, bomUTF16LE):
+		return Format{Encoding: EncodingUTF16LE, BOM: true}
+	case bytes.HasPrefix(d.head, bomUTF16BE):
+		return Format{Encoding: EncodingUTF16BE, BOM: true}
+	}
+
+	// Without a BOM, UTF-16 is recognised by the zero bytes of the ASCII
+	// characters it is mostly made of.
+	if d.n >= 2 && d.n%2 == 0 {
+		if d.zeroEven == 0 && d.zeroOdd*4 >= d.n {
+			return Format{Encoding: EncodingUTF16LE}
+		}
+		if d.zeroOdd == 0 && d.zeroEven*4 >= d.n {
+			return Format{Encoding: EncodingUTF16BE}
+		}
+	}
+
+	if d.invalid || len(d.carry) > 0 {
+		return Format{Encoding: EncodingLatin1, LineEnding: d.lineEnding()}
+	}
+	return Format{Encoding: EncodingUTF8, LineEnding: d.lineEnding()}
+}
+
+func (d *FormatDetector) lineEnding() string {
+	return lineEnding(d.lf, d.crlf)
+}
+
+func lineEnding(lf, crlf int) string {
+	switch {
+	case crlf == 0:
+		return LineEndingLF
+	case crlf == lf:
+		return LineEndingCRLF
+	default:
+		return LineEndingMixed
+	}
+}
+
+// DetectFormat returns the Format of data.
+func DetectFormat(data []byte) Format {
+	var d FormatDetector
+	_, _ = d.Write(data)
+	format := d.Format()
+	if format.LineEnding == "" {
+		if text, err := format.Decode(string(data)); err == nil {
+			format.LineEnding = lineEnding(strings.Count(text, "\n"), strings.Count(text, "\r\n"))
+		}
+	}
+	return format
+}
+
+// DetectFormatPrefix returns the Format of prefix, the start of a longer
+// file, which may end in the middle of a character. Line endings are left
+// empty for UTF-16 text.
+func DetectFormatPrefix(prefix []byte) Format {
+	var d FormatDetector
+	_, _ = d.Write(prefix)
+	d.carry = nil
+	if len(prefix)%2 != 0 {
+		// The last byte is half of a UTF-16 code unit cut off by the end.
+		d.n--
+	}
+	return d.Format()
+}
+
+// DecodeText returns raw decoded to UTF-8 without a BOM, with CRLF line
+// endings turned into "\n", and the Format to encode edited text back into
+// with EncodeText. It fails if raw could not be encoded back exactly.
+func DecodeText(raw string) (string, Format, error) {
+	format := DetectFormat([]byte(raw))
+	decoded, err := format.Decode(raw)
+	if err != nil {
+		return "", Format{}, err
+	}
+
+	text := decoded
+	if format.LineEnding == LineEndingCRLF {
+		text = strings.ReplaceAll(decoded, "\r\n", "\n")
+	}
+	if encoded, err := format.EncodeText(text); err == nil && encoded == raw {
+		return text, format, nil
+	}
+
+	// Line endings such as "\r\r\n" do not survive normalization, so keep
+	// them as they are.
+	format.LineEnding = LineEndingMixed
+	if encoded, err := format.EncodeText(decoded); err == nil && encoded == raw {
+		return decoded, format, nil
+	}
+	return "", Format{}, fmt.Errorf("content is not valid %s", format.Encoding)
+}
+
+// EncodeText returns text as it is stored in format, the inverse of
+// DecodeText. Line breaks in text become the format's line endings, whether
+// they are "\n" or "\r\n".
+func (f Format) EncodeText(text string) (string, error) {
+	if f.LineEnding == LineEndingCRLF {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+
+	var b strings.Builder
+	switch f.Encoding {
+	case EncodingUTF8, "":
+		if f.BOM {
+			b.Write(bomUTF8)
+		}
+		b.WriteString(text)
+	case EncodingUTF16LE, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		bom := bomUTF16LE
+		if f.Encoding == EncodingUTF16BE {
+			order, bom = binary.BigEndian, bomUTF16BE
+		}
+		if f.BOM {
+			b.Write(bom)
+		}
+		unit := make([]byte, 2)
+		for _, u := range utf16.Encode([]rune(text)) {
+			order.PutUint16(unit, u)
+			b.Write(unit)
+		}
+	case EncodingLatin1:
+		for _, r := range text {
+			if r > 0xff {
+				return "", fmt.Errorf("%q cannot be encoded in %s", r, f.Encoding)
+			}
+			b.WriteByte(byte(r))
+		}
+	default:
+		return "", fmt.Errorf("unknown encoding %s", f.Encoding)
+	}
+	return b.String(), nil
+}
+
+// NormalizeText turns CRLF line endings in s into "\n" if text decoded in f
+// has them normalized, so strings given for an edit match the text.
+func (f Format) NormalizeText(s string) string {
+	if f.LineEnding == LineEndingCRLF {
+		return strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	return s
+}
+
+// Decode returns raw, text or a part of it stored in f, as UTF-8 without its
+// BOM, keeping line endings. Unlike DecodeText, it does not check that raw is
+// valid.
+func (f Format) Decode(raw string) (string, error) {
+	switch f.Encoding {
+	case EncodingUTF8, "":
+		if f.BOM {
+			raw = strings.TrimPrefix(raw, string(bomUTF8))
+		}
+		return raw, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if f.BOM {
+			raw = raw[2:]
+		}
+		if len(raw)%2 != 0 {
+			return "", fmt.Errorf("%s content has an odd number of bytes", f.Encoding)
+		}
+		var order binary.ByteOrder = binary.LittleEndian
+		if f.Encoding == EncodingUTF16BE {
+			order = binary.BigEndian
+		}
+		data := []byte(raw)
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case EncodingLatin1:
+		runes := make([]rune, len(raw))
+		for i := 0; i < len(raw); i++ {
+			runes[i] = rune(raw[i])
+		}
+		return string(runes), nil
+	default:
+		return "", fmt.Errorf("unknown encoding %s", f.Encoding)
+	}
+}
@@ -0,0 +1,80 @@
+package fileops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantText   string
+		wantFormat Format
+	}{
+		{"empty", "", "", Format{Encoding: EncodingUTF8, LineEnding: LineEndingLF}},
+		{"utf-8 lf", "a\nb\n", "a\nb\n", Format{Encoding: EncodingUTF8, LineEnding: LineEndingLF}},
+		{"utf-8 crlf", "a\r\nb\r\n", "a\nb\n", Format{Encoding: EncodingUTF8, LineEnding: LineEndingCRLF}},
+		{"utf-8 mixed", "a\r\nb\n", "a\r\nb\n", Format{Encoding: EncodingUTF8, LineEnding: LineEndingMixed}},
+		{"utf-8 bom", "\xef\xbb\xbfh\xc3\xa9\r\n", "hé\n", Format{Encoding: EncodingUTF8, BOM: true, LineEnding: LineEndingCRLF}},
+		{"lone cr kept", "a\rb\r\n", "a\rb\n", Format{Encoding: EncodingUTF8, LineEnding: LineEndingCRLF}},
+		{"irreversible crlf", "a\r\r\n", "a\r\r\n", Format{Encoding: EncodingUTF8, LineEnding: LineEndingMixed}},
+		{"latin-1", "caf\xe9\n", "café\n", Format{Encoding: EncodingLatin1, LineEnding: LineEndingLF}},
+		{"utf-16le bom", "\xff\xfeh\x00\xe9\x00\r\x00\n\x00", "hé\n", Format{Encoding: EncodingUTF16LE, BOM: true, LineEnding: LineEndingCRLF}},
+		{"utf-16be bom", "\xfe\xff\x00h\x00\n", "h\n", Format{Encoding: EncodingUTF16BE, BOM: true, LineEnding: LineEndingLF}},
+		{"utf-16le without bom", "h\x00i\x00\n\x00", "hi\n", Format{Encoding: EncodingUTF16LE, LineEnding: LineEndingLF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, format, err := DecodeText(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantFormat, format)
+
+			encoded, err := format.EncodeText(text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.raw, encoded)
+		})
+	}
+}
+
+func TestDecodeText_Errors(t *testing.T) {
+	_, _, err := DecodeText("\xff\xfeh\x00i")
+	assert.ErrorContains(t, err, "odd number of bytes")
+
+	// An unpaired surrogate does not survive decoding.
+	_, _, err = DecodeText("\xff\xfe\x00\xd8a\x00")
+	assert.ErrorContains(t, err, "not valid UTF-16LE")
+}
+
+func TestFormat_EncodeText(t *testing.T) {
+	crlf := Format{Encoding: EncodingUTF8, LineEnding: LineEndingCRLF}
+	got, err := crlf.EncodeText("a\nb\r\nc")
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\nc", got)
+
+	got, err = Format{}.EncodeText("a\r\nb\n")
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb\n", got)
+
+	_, err = Format{Encoding: EncodingLatin1}.EncodeText("€")
+	assert.ErrorContains(t, err, "cannot be encoded in ISO-8859-1")
+}
+
+func TestFormatDetector_Chunks(t *testing.T) {
+	raw := []byte("h\xc3\xa9\r\nx\r\n")
+	for split := 0; split <= len(raw); split++ {
+		var d FormatDetector
+		_, _ = d.Write(raw[:split])
+		_, _ = d.Write(raw[split:])
+		assert.Equal(t, Format{Encoding: EncodingUTF8, LineEnding: LineEndingCRLF}, d.Format(), "split at %d", split)
+	}
+}
+
+func TestFormat_String(t *testing.T) {
+	assert.Equal(t, "UTF-16LE, BOM, CRLF", Format{Encoding: EncodingUTF16LE, BOM: true, LineEnding: LineEndingCRLF}.String())
+	assert.Equal(t, "UTF-8", Format{}.String())
+}
@@ -0,0 +1,119 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBinary is returned when a file that looks binary is edited without
+// AllowBinary.
+var ErrBinary = errors.New("binary file")
+
+// ErrTooLarge is returned when a file larger than MaxFileSize would be read
+// whole.
+var ErrTooLarge = errors.New("file too large")
+
+// MaxFileSize is the size in bytes of the largest file FileOps reads whole
+// for an edit, and that view hashes. 0 means no limit. It is set once from
+// the --max-file-size flag.
+var MaxFileSize int64 = 64 << 20
+
+// AllowBinary lets files that look binary be edited and viewed. It is set
+// once from the --allow-binary flag.
+var AllowBinary = false
+
+// SniffLen is the number of bytes at the start of a file that LooksBinary is
+// given, as in git.
+const SniffLen = 8000
+
+// LooksBinary reports whether sample, the start of a file, looks like binary
+// data rather than text: it has NUL bytes that are not part of UTF-16 text,
+// or more than 30% of it is control characters or invalid UTF-8. Text in
+// ISO-8859-1 stays well below that.
+func LooksBinary(sample []byte) bool {
+	if len(sample) == 0 {
+		return false
+	}
+
+	format := DetectFormatPrefix(sample)
+	if format.Encoding == EncodingUTF16LE || format.Encoding == EncodingUTF16BE {
+		return false
+	}
+
+	suspicious := 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		switch {
+		case r == 0:
+			return true
+		case r == utf8.RuneError && size == 1:
+			// A character cut off by the end of the sample is not suspicious.
+			if !utf8.FullRune(sample[i:]) {
+				i = len(sample)
+				continue
+			}
+			suspicious++
+		case r < 0x20 && !strings.ContainsRune("\t\n\v\f\r\b\x1b", r):
+			suspicious++
+		}
+		i += size
+	}
+	return suspicious*10 > len(sample)*3
+}
+
+// ParseSize parses a size in bytes, such as 1048576, with an optional K, M or
+// G suffix for powers of 1024. The suffix may be followed by B or iB.
+func ParseSize(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	upper := strings.ToUpper(num)
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "B"), "I")
+
+	shift := 0
+	switch {
+	case strings.HasSuffix(upper, "K"):
+		shift = 10
+	case strings.HasSuffix(upper, "M"):
+		shift = 20
+	case strings.HasSuffix(upper, "G"):
+		shift = 30
+	}
+	if shift > 0 {
+		upper = upper[:len(upper)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes with an optional K, M or G suffix", s)
+	}
+	if n > (1<<63-1)>>shift {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return n << shift, nil
+}
+
+// FormatSize formats a size in bytes for messages, such as "1.5 MiB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
+
+// CheckSize returns an error wrapping ErrTooLarge if a file of size bytes is
+// over MaxFileSize.
+func CheckSize(path string, size int64) error {
+	if MaxFileSize > 0 && size > MaxFileSize {
+		return fmt.Errorf("%w: %s is %s, over the %s limit (see --max-file-size)",
+			ErrTooLarge, path, FormatSize(size), FormatSize(MaxFileSize))
+	}
+	return nil
+}
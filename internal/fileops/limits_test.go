@@ -0,0 +1,104 @@
+package fileops
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+		want   bool
+	}{
+		{"empty", nil, false},
+		{"text", []byte("package main\n\nfunc main() {}\n"), false},
+		{"latin-1", []byte("caf\xe9 cr\xe8me br\xfbl\xe9e\n"), false},
+		{"utf-16le", []byte("\xff\xfeh\x00i\x00\n\x00"), false},
+		{"utf-16le without bom", []byte("h\x00i\x00\n\x00"), false},
+		{"cut off rune", []byte("h\xc3"), false},
+		{"ansi colors", []byte("\x1b[31mred\x1b[0m\n"), false},
+		{"nul byte", []byte("abc\x00def"), true},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true},
+		{"random bytes", bytes.Repeat([]byte{0x8f, 0x01, 0xfe, 0x02}, 100), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LooksBinary(tt.sample))
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1048576", 1 << 20, false},
+		{"512K", 512 << 10, false},
+		{"64M", 64 << 20, false},
+		{"64mb", 64 << 20, false},
+		{"2GiB", 2 << 30, false},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"1T", 0, true},
+		{"99999999999G", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", FormatSize(512))
+	assert.Equal(t, "1.5 KiB", FormatSize(1536))
+	assert.Equal(t, "64.0 MiB", FormatSize(64<<20))
+	assert.Equal(t, "2048.0 GiB", FormatSize(2<<40))
+}
+
+func TestFileOps_ReadFileContentForOperation_Limits(t *testing.T) {
+	oldMax, oldAllow := MaxFileSize, AllowBinary
+	defer func() { MaxFileSize, AllowBinary = oldMax, oldAllow }()
+
+	tmpDir := t.TempDir()
+	f := &FileOps{}
+
+	binary := filepath.Join(tmpDir, "image.png")
+	require.NoError(t, os.WriteFile(binary, []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0o644))
+	_, _, err := f.ReadFileContentForOperation(binary, "replace strings in")
+	require.ErrorIs(t, err, ErrBinary)
+	assert.Contains(t, err.Error(), "refusing to replace strings in "+binary)
+
+	AllowBinary = true
+	content, _, err := f.ReadFileContentForOperation(binary, "replace strings in")
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG\r\n\x1a\n\x00\x00", content)
+
+	large := filepath.Join(tmpDir, "large.log")
+	require.NoError(t, os.WriteFile(large, bytes.Repeat([]byte("log line\n"), 1000), 0o644))
+	MaxFileSize = 1 << 10
+	_, _, err = f.ReadFileContentForOperation(large, "insert line in")
+	require.ErrorIs(t, err, ErrTooLarge)
+	assert.Contains(t, err.Error(), "is 8.8 KiB, over the 1.0 KiB limit")
+
+	MaxFileSize = 0
+	_, _, err = f.ReadFileContentForOperation(large, "insert line in")
+	require.NoError(t, err)
+}
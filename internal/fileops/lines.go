@@ -0,0 +1,82 @@
+package fileops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SplitLines splits content into lines. A trailing newline terminates the
+// last line rather than starting an empty one, and is reported separately so
+// JoinLines can restore it.
+func SplitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+
+	lines := strings.Split(content, "\n")
+	hasTrailingNewline := strings.HasSuffix(content, "\n")
+
+	if hasTrailingNewline && len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines, hasTrailingNewline
+}
+
+// JoinLines is the inverse of SplitLines.
+func JoinLines(lines []string, hasTrailingNewline bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	joined := strings.Join(lines, "\n")
+	if hasTrailingNewline {
+		joined += "\n"
+	}
+	return joined
+}
+
+// ParseLineRange parses a 1-based inclusive line range written as "N" or
+// "start,end". An end of -1 means the last line of the file.
+func ParseLineRange(lineRange string) (int, int, error) {
+	parts := strings.Split(lineRange, ",")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("invalid range %q, expected N or start,end", lineRange)
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start in range %q: %w", lineRange, err)
+	}
+	if start < 1 {
+		return 0, 0, fmt.Errorf("start line must be >= 1, got %d", start)
+	}
+
+	if len(parts) == 1 {
+		return start, start, nil
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end in range %q: %w", lineRange, err)
+	}
+	if end != -1 && end < start {
+		return 0, 0, fmt.Errorf("start cannot be greater than end in range %q", lineRange)
+	}
+
+	return start, end, nil
+}
+
+// LineHunk describes a block of lines replaced by an edit. Start is the
+// 1-based line in the original content where the block begins.
+type LineHunk struct {
+	Start    int
+	OldCount int
+	NewCount int
+}
+
+// Indentation returns the leading spaces and tabs of line.
+func Indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,66 @@
+package fileops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitLines_JoinLines(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantLines    []string
+		wantTrailing bool
+	}{
+		{"empty", "", nil, false},
+		{"single line no newline", "a", []string{"a"}, false},
+		{"single line with newline", "a\n", []string{"a"}, true},
+		{"multiple lines", "a\nb\nc\n", []string{"a", "b", "c"}, true},
+		{"blank last line", "a\n\n", []string{"a", ""}, true},
+		{"only newline", "\n", []string{""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, trailing := SplitLines(tt.content)
+			assert.Equal(t, tt.wantLines, lines)
+			assert.Equal(t, tt.wantTrailing, trailing)
+			assert.Equal(t, tt.content, JoinLines(lines, trailing))
+		})
+	}
+}
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		lineRange string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{"single line", "5", 5, 5, false},
+		{"range", "40,55", 40, 55, false},
+		{"to end", "10,-1", 10, -1, false},
+		{"with spaces", " 3 , 4 ", 3, 4, false},
+		{"zero start", "0,4", 0, 0, true},
+		{"start after end", "5,4", 0, 0, true},
+		{"too many parts", "1,2,3", 0, 0, true},
+		{"not a number", "a,b", 0, 0, true},
+		{"empty", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseLineRange(tt.lineRange)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package fileops
+
+import "os"
+
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	return nil
+}
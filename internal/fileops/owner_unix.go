@@ -0,0 +1,25 @@
+//go:build unix
+
+package fileops
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives f the owner and group of info. Only root can give a
+// file away, so a permission error is ignored and the file keeps the owner
+// of the process.
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	err := f.Chown(int(stat.Uid), int(stat.Gid))
+	if errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	return err
+}
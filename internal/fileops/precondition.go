@@ -0,0 +1,69 @@
+package fileops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned when a file no longer matches the precondition of
+// an edit, usually because someone else changed it after it was read.
+var ErrConflict = errors.New("conflict")
+
+// Precondition is an optimistic concurrency check made before a file is
+// edited. SHA256 is the expected hex digest of the file content and ModTime
+// its expected modification time in RFC 3339 format. Empty fields are not
+// checked.
+type Precondition struct {
+	SHA256  string `json:"sha256,omitempty"`
+	ModTime string `json:"mtime,omitempty"`
+}
+
+// Check returns an error wrapping ErrConflict if the file at path, with the
+// given content and info, does not match p.
+func (p Precondition) Check(path, content string, info os.FileInfo) error {
+	if p.SHA256 != "" {
+		actual := HashContent(content)
+		if !strings.EqualFold(actual, p.SHA256) {
+			return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrConflict, path, actual, p.SHA256)
+		}
+	}
+
+	if p.ModTime != "" {
+		expected, err := time.Parse(time.RFC3339Nano, p.ModTime)
+		if err != nil {
+			return fmt.Errorf("invalid expected mtime %q: %w", p.ModTime, err)
+		}
+		if !info.ModTime().Equal(expected) {
+			return fmt.Errorf("%w: %s was modified at %s, expected %s",
+				ErrConflict, path, FormatModTime(info.ModTime()), p.ModTime)
+		}
+	}
+
+	return nil
+}
+
+// HashContent returns the hex SHA-256 digest of content.
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashReader returns the hex SHA-256 digest of everything read from r.
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FormatModTime formats a modification time the way preconditions expect it.
+func FormatModTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,61 @@
+package fileops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecondition_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0o644))
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	hash := HashContent("hello\n")
+
+	tests := []struct {
+		name     string
+		pre      Precondition
+		wantErr  string
+		conflict bool
+	}{
+		{name: "no precondition", pre: Precondition{}},
+		{name: "matching hash", pre: Precondition{SHA256: hash}},
+		{name: "hash is case insensitive", pre: Precondition{SHA256: strings.ToUpper(hash)}},
+		{name: "matching mtime", pre: Precondition{ModTime: FormatModTime(modTime)}},
+		{name: "matching mtime in another zone", pre: Precondition{ModTime: modTime.In(time.FixedZone("EST", -5*3600)).Format(time.RFC3339Nano)}},
+		{name: "both match", pre: Precondition{SHA256: hash, ModTime: FormatModTime(modTime)}},
+		{name: "stale hash", pre: Precondition{SHA256: HashContent("old\n")}, wantErr: "has sha256 " + hash, conflict: true},
+		{name: "stale mtime", pre: Precondition{ModTime: FormatModTime(modTime.Add(-time.Second))}, wantErr: "was modified at", conflict: true},
+		{name: "invalid mtime", pre: Precondition{ModTime: "yesterday"}, wantErr: "invalid expected mtime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pre.Check(path, "hello\n", info)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, tt.conflict, errors.Is(err, ErrConflict))
+		})
+	}
+}
+
+func TestHashReader(t *testing.T) {
+	hash, err := HashReader(strings.NewReader("hello\n"))
+	require.NoError(t, err)
+	assert.Equal(t, HashContent("hello\n"), hash)
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", hash)
+}
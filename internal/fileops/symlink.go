@@ -0,0 +1,55 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkPolicy decides what happens when a file being written is a symlink.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow writes to the file the link points to.
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkRefuse fails instead of writing.
+	SymlinkRefuse SymlinkPolicy = "refuse"
+	// SymlinkReplace replaces the link itself with a regular file and leaves
+	// the file it pointed to untouched.
+	SymlinkReplace SymlinkPolicy = "replace"
+)
+
+// Symlinks is the policy applied by every write through FileOps. It is set
+// once from the --symlinks flag.
+var Symlinks = SymlinkFollow
+
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(s); policy {
+	case SymlinkFollow, SymlinkRefuse, SymlinkReplace:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid symlink policy %q: must be follow, refuse or replace", s)
+	}
+}
+
+// resolveWritePath returns the path a write to path should replace under the
+// current Symlinks policy.
+func resolveWritePath(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return path, nil
+	}
+
+	switch Symlinks {
+	case SymlinkRefuse:
+		return "", fmt.Errorf("refusing to write through symlink %s (see --symlinks)", path)
+	case SymlinkReplace:
+		return path, nil
+	default:
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return "", fmt.Errorf("resolve symlink %s: %w", path, err)
+		}
+		return target, nil
+	}
+}
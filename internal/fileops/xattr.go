@@ -0,0 +1,56 @@
+//go:build linux || darwin
+
+package fileops
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyXattrs copies the extended attributes of src to dst. Filesystems
+// without extended attribute support are skipped, as are attributes the
+// process is not allowed to set.
+func copyXattrs(src, dst string) error {
+	size, err := unix.Listxattr(src, nil)
+	if errors.Is(err, unix.ENOTSUP) || size == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	names := make([]byte, size)
+	size, err = unix.Listxattr(src, names)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+
+		valueSize, err := unix.Getxattr(src, attr, nil)
+		if err != nil {
+			return err
+		}
+		value := make([]byte, valueSize)
+		valueSize, err = unix.Getxattr(src, attr, value)
+		if err != nil {
+			return err
+		}
+
+		err = unix.Setxattr(dst, attr, value[:valueSize], 0)
+		if errors.Is(err, unix.EPERM) || errors.Is(err, unix.ENOTSUP) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package fileops
+
+func copyXattrs(src, dst string) error {
+	return nil
+}
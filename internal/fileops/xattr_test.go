@@ -0,0 +1,29 @@
+//go:build linux
+
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestWriteFileAtomic_Xattrs(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+	if err := unix.Setxattr(path, "user.eddie", []byte("kept"), 0); err != nil {
+		t.Skipf("filesystem does not support user extended attributes: %v", err)
+	}
+
+	require.NoError(t, WriteFileAtomic(path, []byte("new"), 0o644))
+
+	value := make([]byte, 16)
+	size, err := unix.Getxattr(path, "user.eddie", value)
+	require.NoError(t, err)
+	assert.Equal(t, "kept", string(value[:size]))
+}
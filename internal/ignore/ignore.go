@@ -0,0 +1,381 @@
+package ignore
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Files are the ignore files read in every directory of a walk, from lowest
+// to highest precedence.
+var Files = []string{".gitignore", ".ignore", ".eddieignore"}
+
+// DefaultExcludes are ignored in every walk unless ignore rules are turned
+// off, even in projects without ignore files.
+var DefaultExcludes = []string{".git/", ".hg/", ".svn/", "node_modules/"}
+
+// Options controls what a walk skips. NoIgnore turns off ignore files and
+// global excludes, and Hidden includes files and directories whose name
+// starts with a dot.
+type Options struct {
+	NoIgnore bool
+	Hidden   bool
+}
+
+// Matcher decides which paths under a root a walk skips. Ignore files are
+// read from the root, from each directory the walk asks about and from the
+// parents of the root up to the top of its git repository.
+type Matcher struct {
+	opts Options
+	root string
+	abs  string
+	top  string
+
+	// global are the rules that apply everywhere under top.
+	global []*rules
+	// dirs are the rules read from the ignore files of each directory, by
+	// absolute path.
+	dirs map[string][]*rules
+}
+
+// New returns a Matcher for the walk of root.
+func New(root string, opts Options) *Matcher {
+	m := &Matcher{opts: opts, root: root, dirs: map[string][]*rules{}}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = filepath.Clean(root)
+	}
+	m.abs = abs
+	m.top = repoTop(abs)
+
+	if !opts.NoIgnore {
+		m.global = append(m.global, parseRules(m.top, DefaultExcludes))
+		if lines, err := readLines(globalExcludesFile()); err == nil {
+			m.global = append(m.global, parseRules(m.top, lines))
+		}
+		if lines, err := readLines(filepath.Join(m.top, ".git", "info", "exclude")); err == nil {
+			m.global = append(m.global, parseRules(m.top, lines))
+		}
+	}
+	return m
+}
+
+// Ignored reports whether a walk of the root of m skips path, a file or
+// directory under it. The root itself is never ignored.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	return m.ignored(path, isDir, false)
+}
+
+// ignored is Ignored, except that path is kept even if it is hidden when
+// named is set.
+func (m *Matcher) ignored(path string, isDir, named bool) bool {
+	abs := m.absPath(path)
+	if abs == m.abs {
+		return false
+	}
+
+	if !m.opts.Hidden && !named && strings.HasPrefix(filepath.Base(abs), ".") {
+		return true
+	}
+	if m.opts.NoIgnore {
+		return false
+	}
+
+	ignored := false
+	check := func(sets []*rules) {
+		for _, set := range sets {
+			if match, negated := set.match(abs, isDir); match {
+				ignored = !negated
+			}
+		}
+	}
+	check(m.global)
+	for _, dir := range m.parents(abs) {
+		check(m.rules(dir))
+	}
+	return ignored
+}
+
+// Skipped reports whether path, or any directory between the root of m and
+// path, is ignored. It is for paths found without a walk, such as the
+// matches of a glob. named reports, for each part of path after the root,
+// whether it was written out rather than matched by a wildcard; those parts
+// are not skipped for being hidden, since they were asked for by name.
+func (m *Matcher) Skipped(path string, isDir bool, named []bool) bool {
+	abs := m.absPath(path)
+	rel, err := filepath.Rel(m.abs, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	dir := m.abs
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		dir = filepath.Join(dir, part)
+		last := i == len(parts)-1
+		if m.ignored(dir, !last || isDir, i < len(named) && named[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk walks the tree at root like filepath.WalkDir, skipping the files and
+// directories ignored with opts.
+func Walk(root string, opts Options, fn fs.WalkDirFunc) error {
+	m := New(root, opts)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && m.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(path, d, err)
+	})
+}
+
+func (m *Matcher) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(m.abs, rel)
+}
+
+// parents returns the directories whose ignore files apply to abs, from top
+// down to the directory that contains abs.
+func (m *Matcher) parents(abs string) []string {
+	var dirs []string
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == m.top || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	for i, j := 0, len(dirs)-1; i < j; i, j = i+1, j-1 {
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	}
+	return dirs
+}
+
+// rules returns the rules of the ignore files in dir, reading them the
+// first time dir is asked about.
+func (m *Matcher) rules(dir string) []*rules {
+	if sets, ok := m.dirs[dir]; ok {
+		return sets
+	}
+	var sets []*rules
+	for _, name := range Files {
+		lines, err := readLines(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		sets = append(sets, parseRules(dir, lines))
+	}
+	m.dirs[dir] = sets
+	return sets
+}
+
+// repoTop returns the top of the git repository that contains dir, or dir
+// itself if it is not in one.
+func repoTop(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if d == filepath.Dir(d) {
+			return dir
+		}
+	}
+}
+
+// globalExcludesFile returns git's default global ignore file.
+func globalExcludesFile() string {
+	if config := os.Getenv("XDG_CONFIG_HOME"); config != "" {
+		return filepath.Join(config, "git", "ignore")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
+
+func readLines(path string) ([]string, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Globs are patterns in gitignore syntax given on the command line, such as
+// the globs of search --include and --exclude, matched against paths under a
+// root.
+type Globs struct {
+	rules *rules
+}
+
+// NewGlobs parses globs, relative to root.
+func NewGlobs(root string, globs []string) Globs {
+	return Globs{rules: parseRules(filepath.Clean(root), globs)}
+}
+
+// Empty reports whether there are no globs.
+func (g Globs) Empty() bool {
+	return g.rules == nil || len(g.rules.patterns) == 0
+}
+
+// Match reports whether path, a file or directory under the root, matches
+// the last glob that applies to it, unless that glob is negated with "!".
+func (g Globs) Match(path string, isDir bool) bool {
+	if g.rules == nil {
+		return false
+	}
+	match, negated := g.rules.match(filepath.Clean(path), isDir)
+	return match && !negated
+}
+
+// rules are the patterns of one ignore file, matched against paths relative
+// to the directory the file is in.
+type rules struct {
+	base     string
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negated bool
+	dirOnly bool
+}
+
+// parseRules parses lines in gitignore syntax. Patterns that do not compile
+// are dropped, like git does.
+func parseRules(base string, lines []string) *rules {
+	set := &rules{base: base}
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		line = trimTrailingSpaces(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p pattern
+		if strings.HasPrefix(line, "!") {
+			p.negated = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern with a slash other than at its end is relative to the
+		// directory of the ignore file, otherwise it matches at any depth.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		p.re = re
+		set.patterns = append(set.patterns, p)
+	}
+	return set
+}
+
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// match reports whether the last pattern of set that matches abs exists and
+// whether it is negated.
+func (set *rules) match(abs string, isDir bool) (match, negated bool) {
+	rel, err := filepath.Rel(set.base, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for i := len(set.patterns) - 1; i >= 0; i-- {
+		p := set.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			return true, p.negated
+		}
+	}
+	return false, false
+}
+
+// globToRegexp translates a gitignore glob to a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package ignore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func walked(t *testing.T, root string, opts Options) []string {
+	t.Helper()
+	var paths []string
+	err := Walk(root, opts, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			rel, err := filepath.Rel(root, path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/HEAD":                  "ref: refs/heads/main\n",
+		".gitignore":                 "# build output\n/build/\n*.log\n!keep.log\n",
+		".env":                       "SECRET=1\n",
+		"main.go":                    "package main\n",
+		"debug.log":                  "log\n",
+		"keep.log":                   "log\n",
+		"build/out.bin":              "bin\n",
+		"node_modules/dep/index.js":  "js\n",
+		"src/build/gen.go":           "package build\n",
+		"src/.ignore":                "*.tmp\n",
+		"src/a.tmp":                  "tmp\n",
+		"src/lib.go":                 "package src\n",
+		"src/vendor/.eddieignore":    "*\n!keep.go\n",
+		"src/vendor/keep.go":         "package vendor\n",
+		"src/vendor/drop.go":         "package vendor\n",
+		"docs/deep/generated/y.md":   "md\n",
+		"docs/.gitignore":            "**/generated/\n",
+		"docs/readme.md":             "md\n",
+		"src/nested/.gitignore":      "!*.tmp\n",
+		"src/nested/kept.tmp":        "tmp\n",
+		"src/nested/sub/dropped.log": "log\n",
+	})
+
+	t.Run("default", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{
+			"docs/readme.md",
+			"keep.log",
+			"main.go",
+			"src/build/gen.go",
+			"src/lib.go",
+			"src/nested/kept.tmp",
+			"src/vendor/keep.go",
+		}, walked(t, root, Options{}))
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		paths := walked(t, root, Options{Hidden: true})
+		assert.Contains(t, paths, ".env")
+		assert.Contains(t, paths, ".gitignore")
+		assert.NotContains(t, paths, ".git/HEAD")
+		assert.NotContains(t, paths, "debug.log")
+	})
+
+	t.Run("no ignore", func(t *testing.T) {
+		paths := walked(t, root, Options{NoIgnore: true})
+		assert.Contains(t, paths, "debug.log")
+		assert.Contains(t, paths, "build/out.bin")
+		assert.Contains(t, paths, "node_modules/dep/index.js")
+		assert.NotContains(t, paths, ".env")
+	})
+
+	t.Run("subdirectory uses parent ignore files", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"gen.go"}, walked(t, filepath.Join(root, "src", "build"), Options{}))
+		assert.ElementsMatch(t, []string{"kept.tmp"}, walked(t, filepath.Join(root, "src", "nested"), Options{}))
+	})
+}
+
+func TestWalk_GlobalExcludes(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	writeTree(t, config, map[string]string{"git/ignore": "*.swp\n"})
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":     "package main\n",
+		"main.go.swp": "swap\n",
+	})
+
+	assert.Equal(t, []string{"main.go"}, walked(t, root, Options{}))
+	assert.ElementsMatch(t, []string{"main.go", "main.go.swp"}, walked(t, root, Options{NoIgnore: true}))
+}
+
+func TestMatcher_Skipped(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":        "dist/\n",
+		"dist/app.js":       "js\n",
+		"src/app.js":        "js\n",
+		".config/settings":  "x\n",
+		"node_modules/a.js": "js\n",
+	})
+
+	m := New(root, Options{})
+	assert.True(t, m.Skipped(filepath.Join(root, "dist", "app.js"), false, nil))
+	assert.True(t, m.Skipped(filepath.Join(root, ".config", "settings"), false, nil))
+	assert.True(t, m.Skipped(filepath.Join(root, "node_modules", "a.js"), false, nil))
+	assert.False(t, m.Skipped(filepath.Join(root, "src", "app.js"), false, nil))
+	assert.False(t, m.Skipped(root, true, nil))
+
+	// Hidden parts written out by name are kept, but ignore rules still apply.
+	assert.False(t, m.Skipped(filepath.Join(root, ".config", "settings"), false, []bool{true, false}))
+	assert.True(t, m.Skipped(filepath.Join(root, ".config", ".secret"), false, []bool{true, false}))
+	assert.True(t, m.Skipped(filepath.Join(root, "dist", "app.js"), false, []bool{true, true}))
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "dir/main.go", true},
+		{"/main.go", "dir/main.go", false},
+		{"a/*.go", "a/b.go", true},
+		{"a/*.go", "a/b/c.go", false},
+		{"a/**/c.go", "a/c.go", true},
+		{"a/**/c.go", "a/b/d/c.go", true},
+		{"a/**", "a/b/c", true},
+		{"**/foo", "x/y/foo", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "file5.txt", true},
+		{"file[!0-9].txt", "file5.txt", false},
+		{`\#notes`, "#notes", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			set := parseRules("/base", []string{tt.pattern})
+			match, _ := set.match(filepath.Join("/base", tt.path), false)
+			assert.Equal(t, tt.want, match)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats selected with the --output flag.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Format is the output format commands print their results in. It is set
+// once from the --output flag.
+var Format = FormatText
+
+// ParseFormat validates the value of the --output flag.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case FormatText, FormatJSON:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: use text or json", s)
+	}
+}
+
+// Result is the typed result of a command. String renders it as the text
+// the command prints by default.
+type Result interface {
+	String() string
+}
+
+// Print writes res to w in the selected Format. Nothing is written if w is
+// nil, so commands made without a writer only return their results.
+func Print(w io.Writer, res Result) error {
+	if w == nil {
+		return nil
+	}
+	if Format == FormatJSON {
+		return PrintJSON(w, res)
+	}
+	_, err := io.WriteString(w, res.String())
+	return err
+}
+
+// PrintJSON writes res to w as indented JSON, whatever the selected Format.
+// Like Print, it writes nothing if w is nil.
+func PrintJSON(w io.Writer, res Result) error {
+	if w == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal result: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// Render returns res as Print would write it.
+func Render(res Result) string {
+	var b strings.Builder
+	err := Print(&b, res)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return b.String()
+}
+
+// ErrorDetails returns what an error in the chain of err has to show beyond
+// its message, such as the diff of a file changed outside of eddie, or "" if
+// there is nothing more.
+func ErrorDetails(err error) string {
+	var detailed interface{ Details() string }
+	if errors.As(err, &detailed) {
+		return detailed.Details()
+	}
+	return ""
+}
+
+// Edit is the result of a command that changed one file. Count is what the
+// command counts: occurrences, matches, lines, hunks or bytes written. Diff is
+// the unified diff of the change and Content the file after it; either is
+// nil unless it was asked for. Warnings are problems that did not stop the
+// command.
+type Edit struct {
+	Command string  `json:"command"`
+	Path    string  `json:"path"`
+	Count   int     `json:"count"`
+	Created bool    `json:"created,omitempty"`
+	Deleted bool    `json:"deleted,omitempty"`
+	Diff    *string `json:"diff,omitempty"`
+	Content *string `json:"content,omitempty"`
+	Summary string  `json:"summary,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (e *Edit) String() string {
+	var b strings.Builder
+	if e.Diff != nil {
+		if e.Created {
+			b.WriteString(NewFileSection(e.Path, *e.Diff))
+		} else {
+			b.WriteString(DiffSection(e.Path, *e.Diff))
+		}
+	}
+	if e.Content != nil {
+		b.WriteString(ResultSection(e.Path, *e.Content))
+	}
+	for _, warning := range e.Warnings {
+		b.WriteString("Warning: " + warning + "\n")
+	}
+	if e.Summary != "" {
+		b.WriteString(e.Summary + "\n")
+	}
+	return b.String()
+}
+
+// Edits is the result of a command that changed several files, such as an
+// undo that also moved the files edited together with path.
+type Edits struct {
+	Edits   []*Edit `json:"edits"`
+	Summary string  `json:"summary,omitempty"`
+}
+
+func (e *Edits) String() string {
+	var b strings.Builder
+	for _, edit := range e.Edits {
+		b.WriteString(edit.String())
+	}
+	if e.Summary != "" {
+		b.WriteString(e.Summary + "\n")
+	}
+	return b.String()
+}
+
+// DiffSection renders unified, the diff of the changes to path, the way
+// edits show it.
+func DiffSection(path, unified string) string {
+	return fmt.Sprintf("\nChanges in %s:\n", path) + diffBody(unified)
+}
+
+// NewFileSection renders unified, the diff that creates path.
+func NewFileSection(path, unified string) string {
+	return fmt.Sprintf("\nContent of %s:\n", path) + diffBody(unified)
+}
+
+func diffBody(unified string) string {
+	if unified == "" {
+		return "No changes\n\n"
+	}
+	return unified + "\n"
+}
+
+// ResultSection renders content, the content of path after an edit.
+func ResultSection(path, content string) string {
+	return fmt.Sprintf("\nResult of %s:\n%s\n", path, content)
+}
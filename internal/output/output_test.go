@@ -0,0 +1,108 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "text", want: FormatText},
+		{input: "json", want: FormatJSON},
+		{input: "yaml", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFormat(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEdit_String(t *testing.T) {
+	diff := "--- a.txt\n+++ a.txt\n@@ -1 +1 @@\n-foo\n+bar\n"
+	empty := ""
+	content := "bar\n"
+
+	tests := []struct {
+		name     string
+		edit     *Edit
+		expected string
+	}{
+		{
+			name:     "summary only",
+			edit:     &Edit{Path: "a.txt", Summary: "Replaced 1 occurrence(s)"},
+			expected: "Replaced 1 occurrence(s)\n",
+		},
+		{
+			name:     "diff and result",
+			edit:     &Edit{Path: "a.txt", Diff: &diff, Content: &content, Summary: "done"},
+			expected: "\nChanges in a.txt:\n" + diff + "\n" + "\nResult of a.txt:\nbar\n\n" + "done\n",
+		},
+		{
+			name:     "empty diff",
+			edit:     &Edit{Path: "a.txt", Diff: &empty},
+			expected: "\nChanges in a.txt:\nNo changes\n\n",
+		},
+		{
+			name:     "created file",
+			edit:     &Edit{Path: "a.txt", Created: true, Diff: &diff},
+			expected: "\nContent of a.txt:\n" + diff + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.edit.String())
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	defer func(format string) { Format = format }(Format)
+
+	edit := &Edit{Command: "create", Path: "a.txt", Count: 3, Created: true, Summary: "Created a.txt"}
+
+	t.Run("text", func(t *testing.T) {
+		Format = FormatText
+		var buf bytes.Buffer
+		require.NoError(t, Print(&buf, edit))
+		assert.Equal(t, "Created a.txt\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		Format = FormatJSON
+		var buf bytes.Buffer
+		require.NoError(t, Print(&buf, edit))
+
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, "create", got["command"])
+		assert.Equal(t, "a.txt", got["path"])
+		assert.Equal(t, float64(3), got["count"])
+		assert.Equal(t, true, got["created"])
+		assert.NotContains(t, got, "deleted")
+		assert.NotContains(t, got, "diff")
+	})
+
+	t.Run("nil writer", func(t *testing.T) {
+		Format = FormatJSON
+		assert.NoError(t, Print(nil, edit))
+		assert.NoError(t, PrintJSON(nil, edit))
+	})
+}